@@ -0,0 +1,77 @@
+// Copyright 2021 Tamás Gulácsi.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package dbcsv
+
+import (
+	"io"
+	"sync"
+)
+
+// AsyncWriter writes to the underlying writer in a background goroutine,
+// so a slow writer does not block the producer until the queue is full.
+type AsyncWriter struct {
+	w    io.Writer
+	ch   chan []byte
+	done chan struct{}
+	mu   sync.Mutex
+	err  error
+}
+
+// NewAsyncWriter returns an AsyncWriter writing to w, queueing at most depth writes.
+func NewAsyncWriter(w io.Writer, depth int) *AsyncWriter {
+	if depth < 1 {
+		depth = 1
+	}
+	aw := &AsyncWriter{w: w, ch: make(chan []byte, depth), done: make(chan struct{})}
+	go aw.run()
+	return aw
+}
+
+func (aw *AsyncWriter) run() {
+	defer close(aw.done)
+	for p := range aw.ch {
+		if aw.Err() != nil {
+			continue
+		}
+		if _, err := aw.w.Write(p); err != nil {
+			aw.mu.Lock()
+			aw.err = err
+			aw.mu.Unlock()
+		}
+	}
+}
+
+// Err returns the first error of the underlying writer.
+func (aw *AsyncWriter) Err() error {
+	aw.mu.Lock()
+	defer aw.mu.Unlock()
+	return aw.err
+}
+
+// Write queues a copy of p, blocking only when the queue is full.
+// The error is the first error of the underlying writer (of a previous Write).
+func (aw *AsyncWriter) Write(p []byte) (int, error) {
+	if err := aw.Err(); err != nil {
+		return 0, err
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	aw.ch <- append(make([]byte, 0, len(p)), p...)
+	return len(p), nil
+}
+
+// Close waits for the queued writes, then closes the underlying writer if it is an io.Closer.
+func (aw *AsyncWriter) Close() error {
+	close(aw.ch)
+	<-aw.done
+	err := aw.Err()
+	if c, ok := aw.w.(io.Closer); ok {
+		if closeErr := c.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
@@ -0,0 +1,217 @@
+// Copyright 2021 Tamás Gulácsi.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package dbcsv
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"time"
+	"unicode/utf16"
+)
+
+// BCPMode is the mode of the SQL Server bcp data file.
+type BCPMode string
+
+const (
+	// BCPChar is the character mode (bcp -c): tab separated fields, CRLF terminated rows.
+	// The data must not contain tabs or CRLFs.
+	BCPChar = BCPMode("char")
+	// BCPNative is the native mode (bcp -n): length prefixed binary fields.
+	BCPNative = BCPMode("native")
+)
+
+const bcpDateFormat = "2006-01-02 15:04:05.9999999"
+
+var bcpEpoch = time.Date(1900, 1, 1, 0, 0, 0, 0, time.UTC)
+
+type bcpColumn struct {
+	// SQLType is the xsi:type of the COLUMN element.
+	SQLType string
+	// PrefixLength is the length prefix of the field in native mode.
+	PrefixLength int
+}
+
+// bcpColumnOf returns the BCP type of the value of the Stringer (created with Converter("")).
+func bcpColumnOf(s Stringer) bcpColumn {
+	switch s.(type) {
+	case *ValInt, *LocalizedValInt:
+		return bcpColumn{SQLType: "SQLBIGINT", PrefixLength: 1}
+	case *ValFloat, *LocalizedValFloat:
+		return bcpColumn{SQLType: "SQLFLT8", PrefixLength: 1}
+	case *ValTime, *LocalizedValTime:
+		return bcpColumn{SQLType: "SQLDATETIME", PrefixLength: 1}
+	}
+	return bcpColumn{SQLType: "SQLNVARCHAR", PrefixLength: 8}
+}
+
+// DumpBCP writes the rows as a SQL Server bcp data file to w,
+// and the XML format file describing it to formatFile,
+// so it can be loaded with "bcp <table> in <file> -f <format-file>".
+//
+// In character mode the data is written as is (use -C 65001 for UTF-8),
+// NULLs are empty fields and empty strings are a single NUL byte, as bcp does.
+// In native mode the numbers are BIGINT and FLOAT, the dates DATETIME,
+// and everything else is NVARCHAR(MAX).
+func DumpBCP(ctx context.Context, w, formatFile io.Writer, rows Rows, columns []Column, mode BCPMode, opts DumperOptions, Log func(...interface{}) error) (int, error) {
+	if mode != BCPChar && mode != BCPNative {
+		return 0, fmt.Errorf("unknown bcp mode %q", mode)
+	}
+	dest := make([]interface{}, len(columns))
+	values := make([]Stringer, len(columns))
+	types := make([]bcpColumn, len(columns))
+	for i, col := range columns {
+		c := col.Converter(opts)
+		values[i] = c
+		dest[i] = c.Pointer()
+		types[i] = bcpColumnOf(c)
+	}
+	if err := writeBCPFormat(formatFile, columns, types, mode); err != nil {
+		return 0, err
+	}
+
+	bw := bufio.NewWriterSize(w, 65536)
+	defer bw.Flush()
+	start := time.Now()
+	n := 0
+	var scratch [8]byte
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return n, fmt.Errorf("scan into %#v: %w", dest, err)
+		}
+		for i, v := range values {
+			if mode == BCPChar {
+				if i != 0 {
+					bw.WriteByte('\t')
+				}
+				switch x := TypedValue(v).(type) {
+				case nil:
+				case time.Time:
+					bw.WriteString(x.Format(bcpDateFormat))
+				case string:
+					if x == "" {
+						bw.WriteByte(0)
+					} else {
+						bw.WriteString(x)
+					}
+				default:
+					bw.WriteString(StringRaw(v))
+				}
+				continue
+			}
+
+			x := TypedValue(v)
+			if x == nil {
+				// all bits set means NULL, for every prefix length
+				for j := 0; j < types[i].PrefixLength; j++ {
+					bw.WriteByte(0xff)
+				}
+				continue
+			}
+			switch x := x.(type) {
+			case int64:
+				bw.WriteByte(8)
+				binary.LittleEndian.PutUint64(scratch[:], uint64(x))
+				bw.Write(scratch[:])
+			case float64:
+				bw.WriteByte(8)
+				binary.LittleEndian.PutUint64(scratch[:], math.Float64bits(x))
+				bw.Write(scratch[:])
+			case time.Time:
+				// DATETIME is the days since 1900-01-01, and the 1/300 seconds since midnight.
+				midnight := time.Date(x.Year(), x.Month(), x.Day(), 0, 0, 0, 0, x.Location())
+				days := time.Date(x.Year(), x.Month(), x.Day(), 0, 0, 0, 0, time.UTC).Sub(bcpEpoch) / (24 * time.Hour)
+				ticks := (x.Sub(midnight)*300 + time.Second/2) / time.Second
+				bw.WriteByte(8)
+				binary.LittleEndian.PutUint32(scratch[:4], uint32(int32(days)))
+				binary.LittleEndian.PutUint32(scratch[4:], uint32(ticks))
+				bw.Write(scratch[:])
+			case string:
+				u := utf16.Encode([]rune(x))
+				binary.LittleEndian.PutUint64(scratch[:], uint64(2*len(u)))
+				bw.Write(scratch[:])
+				for _, r := range u {
+					binary.LittleEndian.PutUint16(scratch[:2], r)
+					bw.Write(scratch[:2])
+				}
+			}
+		}
+		if mode == BCPChar {
+			bw.WriteString("\r\n")
+		}
+		n++
+		if err := ctx.Err(); err != nil {
+			return n, err
+		}
+	}
+	err := rows.Err()
+	dur := time.Since(start)
+	if Log != nil {
+		_ = Log("msg", "dump finished", "rows", n, "dur", dur, "speed", float64(n)/float64(dur)*float64(time.Second), "error", err)
+	}
+	if err != nil {
+		return n, err
+	}
+	return n, bw.Flush()
+}
+
+// writeBCPFormat writes the XML format file of the columns.
+func writeBCPFormat(w io.Writer, columns []Column, types []bcpColumn, mode BCPMode) error {
+	type field struct {
+		ID           int    `xml:"ID,attr"`
+		Type         string `xml:"xsi:type,attr"`
+		Terminator   string `xml:"TERMINATOR,attr,omitempty"`
+		PrefixLength int    `xml:"PREFIX_LENGTH,attr,omitempty"`
+	}
+	type column struct {
+		Source   int    `xml:"SOURCE,attr"`
+		Name     string `xml:"NAME,attr"`
+		Type     string `xml:"xsi:type,attr"`
+		Nullable string `xml:"NULLABLE,attr"`
+	}
+	type format struct {
+		XMLName xml.Name `xml:"BCPFORMAT"`
+		NS      string   `xml:"xmlns,attr"`
+		XSI     string   `xml:"xmlns:xsi,attr"`
+		Fields  []field  `xml:"RECORD>FIELD"`
+		Columns []column `xml:"ROW>COLUMN"`
+	}
+	f := format{
+		NS:      "http://schemas.microsoft.com/sqlserver/2004/bulkload/format",
+		XSI:     "http://www.w3.org/2001/XMLSchema-instance",
+		Fields:  make([]field, len(columns)),
+		Columns: make([]column, len(columns)),
+	}
+	for i, col := range columns {
+		id := i + 1
+		f.Columns[i] = column{Source: id, Name: col.Name, Type: types[i].SQLType, Nullable: "YES"}
+		if mode == BCPNative {
+			f.Fields[i] = field{ID: id, Type: "NativePrefix", PrefixLength: types[i].PrefixLength}
+			continue
+		}
+		f.Fields[i] = field{ID: id, Type: "CharTerm", Terminator: `\t`}
+		if id == len(columns) {
+			f.Fields[i].Terminator = `\r\n`
+		}
+		if types[i].SQLType == "SQLDATETIME" {
+			// the text has the precision of DATETIME2
+			f.Columns[i].Type = "SQLDATETIME2"
+		}
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", " ")
+	if err := enc.Encode(f); err != nil {
+		return fmt.Errorf("encode bcp format: %w", err)
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,75 @@
+// Copyright 2021 Tamás Gulácsi.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package dbcsv
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// isBlob reports whether the column needs ValBlob.
+func (col Column) isBlob() bool {
+	switch strings.ToUpper(col.DatabaseTypeName) {
+	case "BLOB", "TINYBLOB", "MEDIUMBLOB", "LONGBLOB", "BINARY", "VARBINARY", "RAW", "LONG RAW", "BYTEA":
+		return true
+	}
+	return false
+}
+
+// ValBlob is a binary value, written encoded by Encoding: base64 (if empty), hex or skip (writes an empty cell).
+// It is scanned from []byte, string or an io.Reader (such as *godror.Lob), at most Max bytes (if positive).
+type ValBlob struct {
+	Value    []byte
+	Valid    bool
+	Sep      string
+	Encoding string
+	Max      int64
+}
+
+func (v ValBlob) String() string { return csvQuoteString(v.Sep, v.StringRaw()) }
+func (v ValBlob) StringRaw() string {
+	if !v.Valid {
+		return ""
+	}
+	switch v.Encoding {
+	case "hex":
+		return hex.EncodeToString(v.Value)
+	case "skip":
+		return ""
+	}
+	return base64.StdEncoding.EncodeToString(v.Value)
+}
+func (v *ValBlob) Pointer() interface{} { return v }
+func (v *ValBlob) Scan(x interface{}) error {
+	v.Value, v.Valid = v.Value[:0], x != nil
+	switch x := x.(type) {
+	case nil:
+	case []byte:
+		// the driver may reuse x
+		v.Value = append(v.Value, x...)
+	case string:
+		v.Value = append(v.Value, x...)
+	case io.Reader:
+		if v.Max > 0 {
+			x = io.LimitReader(x, v.Max)
+		}
+		buf := bytes.NewBuffer(v.Value)
+		if _, err := io.Copy(buf, x); err != nil {
+			return fmt.Errorf("read BLOB: %w", err)
+		}
+		v.Value = buf.Bytes()
+		return nil
+	default:
+		return fmt.Errorf("unknown BLOB type %T", x)
+	}
+	if v.Max > 0 && int64(len(v.Value)) > v.Max {
+		v.Value = v.Value[:v.Max]
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+// Copyright 2021 Tamás Gulácsi.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package dbcsv
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/bits-and-blooms/bloom/v3"
+)
+
+// bloomDedupeRows skips the rows already seen, by a Bloom filter of their values.
+type bloomDedupeRows struct {
+	Rows
+	filter *bloom.BloomFilter
+	dest   []interface{}
+	values []interface{}
+	key    []byte
+	err    error
+}
+
+// NewBloomDedupeRows returns the rows without the duplicates, remembering the rows seen
+// in a Bloom filter sized for size rows with the fp false positive rate.
+//
+// This needs constant memory (about 1.8MB for 1M rows with 0.001), but is approximate:
+// a row never seen before is skipped with the fp probability (more, if there are more than size distinct rows),
+// while the duplicates are always skipped. So use it only where losing some unique rows is acceptable.
+func NewBloomDedupeRows(rows Rows, columns []Column, size uint, fp float64) (Rows, error) {
+	if size == 0 {
+		return nil, fmt.Errorf("bloom filter size must be positive")
+	}
+	if !(fp > 0 && fp < 1) {
+		return nil, fmt.Errorf("bloom filter false positive rate must be between 0 and 1, got %g", fp)
+	}
+	br := bloomDedupeRows{
+		Rows:   rows,
+		filter: bloom.NewWithEstimates(size, fp),
+		dest:   make([]interface{}, len(columns)),
+		values: make([]interface{}, len(columns)),
+	}
+	for i, col := range columns {
+		br.dest[i] = col.scanConverter().Pointer()
+	}
+	return &br, nil
+}
+
+func (br *bloomDedupeRows) Next() bool {
+	for br.Rows.Next() {
+		if br.err = br.Rows.Scan(br.dest...); br.err != nil {
+			return false
+		}
+		for i, d := range br.dest {
+			br.values[i] = replayValue(d)
+		}
+		br.key = appendRowKey(br.key[:0], br.values)
+		if !br.filter.TestAndAdd(br.key) {
+			return true
+		}
+	}
+	return false
+}
+
+func (br *bloomDedupeRows) Err() error {
+	if br.err != nil {
+		return br.err
+	}
+	return br.Rows.Err()
+}
+
+func (br *bloomDedupeRows) Scan(dest ...interface{}) error {
+	for j, d := range dest {
+		scanner, ok := d.(sql.Scanner)
+		if !ok {
+			return fmt.Errorf("%d. column: cannot scan into %T", j, d)
+		}
+		if err := scanner.Scan(br.values[j]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,364 @@
+// Copyright 2021 Tamás Gulácsi.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package dbcsv
+
+import (
+	"container/ring"
+	"database/sql"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cespare/xxhash/v2"
+)
+
+// ComputedColumn is a column appended to the rows, computed from the values of the row.
+type ComputedColumn struct {
+	Column
+	// Compute returns the value of the column (nil, int64, float64, time.Time or string)
+	// from the ScannedValue of the original columns.
+	Compute func(values []interface{}) (interface{}, error)
+}
+
+// AppendColumns returns the rows (and columns) with the computed columns appended.
+func AppendColumns(rows Rows, columns []Column, computed ...ComputedColumn) (Rows, []Column) {
+	if len(computed) == 0 {
+		return rows, columns
+	}
+	cols := make([]Column, len(columns), len(columns)+len(computed))
+	copy(cols, columns)
+	for _, c := range computed {
+		cols = append(cols, c.Column)
+	}
+	return &computedRows{Rows: rows, n: len(columns), computed: computed, values: make([]interface{}, len(columns))}, cols
+}
+
+type computedRows struct {
+	Rows
+	computed []ComputedColumn
+	values   []interface{}
+	n        int
+}
+
+func (cr *computedRows) Scan(dest ...interface{}) error {
+	if err := cr.Rows.Scan(dest[:cr.n]...); err != nil {
+		return err
+	}
+	for i, d := range dest[:cr.n] {
+		cr.values[i] = ScannedValue(d)
+	}
+	for i, c := range cr.computed {
+		v, err := c.Compute(cr.values)
+		if err != nil {
+			return fmt.Errorf("%s: %w", c.Name, err)
+		}
+		d := dest[cr.n+i]
+		scanner, ok := d.(sql.Scanner)
+		if !ok {
+			return fmt.Errorf("%s: cannot scan into %T", c.Name, d)
+		}
+		if err = scanner.Scan(v); err != nil {
+			return fmt.Errorf("%s: %w", c.Name, err)
+		}
+	}
+	return nil
+}
+
+// NewWindowAvg returns the COL_AVG_N column, the simple moving average of the last n
+// non-NULL values of the index-th column. NULLs are skipped, but the row still gets the current average.
+// The numeric strings of the numeric columns (such as the NUMBER columns') are averaged, too.
+func NewWindowAvg(col Column, index, n int) (ComputedColumn, error) {
+	if n < 1 {
+		return ComputedColumn{}, fmt.Errorf("%s: window size must be positive, got %d", col.Name, n)
+	}
+	numeric := col.isNumericString()
+	r := ring.New(n)
+	var count int
+	avg := func() interface{} {
+		if count == 0 {
+			return nil
+		}
+		var sum float64
+		r.Do(func(v interface{}) {
+			if f, ok := v.(float64); ok {
+				sum += f
+			}
+		})
+		return sum / float64(count)
+	}
+	return ComputedColumn{
+		Column: Column{Name: fmt.Sprintf("%s_AVG_%d", col.Name, n), Type: typeOfFloat64},
+		Compute: func(values []interface{}) (interface{}, error) {
+			switch x := values[index].(type) {
+			case nil:
+				return avg(), nil
+			case int64:
+				r.Value = float64(x)
+			case float64:
+				r.Value = x
+			case string:
+				f, err := strconv.ParseFloat(x, 64)
+				if !numeric || err != nil {
+					return nil, fmt.Errorf("%q is not a number", x)
+				}
+				r.Value = f
+			default:
+				return nil, fmt.Errorf("%v (%T) is not a number", x, x)
+			}
+			r = r.Next()
+			if count < n {
+				count++
+			}
+			return avg(), nil
+		},
+	}, nil
+}
+
+// RunningMinMax tracks the minimum and maximum of the non-NULL values seen so far.
+type RunningMinMax struct {
+	Min, Max interface{}
+	// Numeric compares the values as numbers, even the numeric strings
+	// (such as the NUMBER columns' or the big ValUint values).
+	Numeric bool
+}
+
+// isNumericString reports whether the column's values are numbers that may be scanned as strings.
+func (col Column) isNumericString() bool {
+	switch strings.ToUpper(col.DatabaseTypeName) {
+	case "NUMBER", "DECIMAL", "NUMERIC":
+		return true
+	}
+	_, ok := col.scanConverter().(*ValUint)
+	return ok
+}
+
+func (r *RunningMinMax) compare(a, b interface{}) (int, error) {
+	if r.Numeric {
+		x, okA := ratValue(a)
+		y, okB := ratValue(b)
+		if !okA || !okB {
+			return 0, fmt.Errorf("cannot compare %v (%T) with %v (%T) as numbers", a, a, b, b)
+		}
+		return x.Cmp(y), nil
+	}
+	return compareValues(a, b)
+}
+
+// Add the value to the running extremes. NULLs are ignored.
+func (r *RunningMinMax) Add(v interface{}) error {
+	if v == nil {
+		return nil
+	}
+	if r.Min == nil {
+		r.Min, r.Max = v, v
+		return nil
+	}
+	c, err := r.compare(v, r.Min)
+	if err != nil {
+		return err
+	}
+	if c < 0 {
+		r.Min = v
+	}
+	if c, err = r.compare(v, r.Max); err != nil {
+		return err
+	}
+	if c > 0 {
+		r.Max = v
+	}
+	return nil
+}
+
+// NewRunningMin returns the COL_RMIN column, the minimum of the index-th column's values so far.
+func NewRunningMin(col Column, index int) ComputedColumn {
+	r := RunningMinMax{Numeric: col.isNumericString()}
+	return ComputedColumn{
+		Column: Column{Name: col.Name + "_RMIN", Type: col.Type, DatabaseTypeName: col.DatabaseTypeName, Scale: col.Scale},
+		Compute: func(values []interface{}) (interface{}, error) {
+			if err := r.Add(values[index]); err != nil {
+				return nil, fmt.Errorf("%s: %w", col.Name, err)
+			}
+			return r.Min, nil
+		},
+	}
+}
+
+// NewRunningMax returns the COL_RMAX column, the maximum of the index-th column's values so far.
+func NewRunningMax(col Column, index int) ComputedColumn {
+	r := RunningMinMax{Numeric: col.isNumericString()}
+	return ComputedColumn{
+		Column: Column{Name: col.Name + "_RMAX", Type: col.Type, DatabaseTypeName: col.DatabaseTypeName, Scale: col.Scale},
+		Compute: func(values []interface{}) (interface{}, error) {
+			if err := r.Add(values[index]); err != nil {
+				return nil, fmt.Errorf("%s: %w", col.Name, err)
+			}
+			return r.Max, nil
+		},
+	}
+}
+
+// NewJoinColumn returns the name column, the raw string values of the columns of the indexes
+// joined with sep. NULLs are joined as empty strings.
+func NewJoinColumn(name string, indexes []int, sep string) ComputedColumn {
+	parts := make([]string, len(indexes))
+	return ComputedColumn{
+		Column: Column{Name: name, Type: typeOfString},
+		Compute: func(values []interface{}) (interface{}, error) {
+			for k, i := range indexes {
+				parts[k], _ = formatValue(values[i])
+			}
+			return strings.Join(parts, sep), nil
+		},
+	}
+}
+
+// NewCoalesceColumn returns the name column, the first non-NULL, non-empty value of the columns
+// of the indexes (in order), like SQL's COALESCE. Its type is the common type of the columns,
+// or string if they differ.
+func NewCoalesceColumn(name string, columns []Column, indexes []int) ComputedColumn {
+	typ := typeOfString
+	for k, i := range indexes {
+		if k == 0 {
+			typ = columns[i].Type
+		} else if columns[i].Type != typ {
+			typ = typeOfString
+			break
+		}
+	}
+	asString := typ == typeOfString
+	return ComputedColumn{
+		Column: Column{Name: name, Type: typ},
+		Compute: func(values []interface{}) (interface{}, error) {
+			for _, i := range indexes {
+				s, ok := formatValue(values[i])
+				if !ok || s == "" {
+					continue
+				}
+				if asString {
+					return s, nil
+				}
+				return values[i], nil
+			}
+			return nil, nil
+		},
+	}
+}
+
+// NewDateAge returns the COL_age column, the age of the date of the index-th column at ref,
+// in whole days, months or years (unit). Months and years are calendar differences.
+// NULL dates have NULL age, future dates negative age.
+func NewDateAge(col Column, index int, unit string, ref time.Time) (ComputedColumn, error) {
+	switch unit {
+	case "days", "months", "years":
+	default:
+		return ComputedColumn{}, fmt.Errorf("%s: unknown age unit %q (only days, months or years)", col.Name, unit)
+	}
+	return ComputedColumn{
+		Column: Column{Name: col.Name + "_age", Type: typeOfInt64},
+		Compute: func(values []interface{}) (interface{}, error) {
+			var t time.Time
+			switch x := values[index].(type) {
+			case nil:
+				return nil, nil
+			case time.Time:
+				t = x
+			default:
+				return nil, fmt.Errorf("%v (%T) is not a date", x, x)
+			}
+			if unit == "days" {
+				return int64(ref.Sub(t) / (24 * time.Hour)), nil
+			}
+			m := calendarMonths(t.In(ref.Location()), ref)
+			if unit == "years" {
+				m /= 12
+			}
+			return m, nil
+		},
+	}, nil
+}
+
+// calendarMonths returns the number of whole months from a to b (negative if b is before a).
+func calendarMonths(a, b time.Time) int64 {
+	sign := int64(1)
+	if b.Before(a) {
+		a, b, sign = b, a, -1
+	}
+	m := int64(b.Year()-a.Year())*12 + int64(b.Month()-a.Month())
+	// the last month is not complete yet
+	if b.Day() < a.Day() || b.Day() == a.Day() && timeOfDay(b) < timeOfDay(a) {
+		m--
+	}
+	return sign * m
+}
+
+func timeOfDay(t time.Time) time.Duration {
+	h, m, s := t.Clock()
+	return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute + time.Duration(s)*time.Second + time.Duration(t.Nanosecond())
+}
+
+// NewHashPrefix returns the COL_HASH_PREFIX column, the first width (1-16) hex characters
+// of the FNV-1a hash of the index-th column's raw string value (all zeros for NULL).
+func NewHashPrefix(col Column, index, width int) (ComputedColumn, error) {
+	if width < 1 || width > 16 {
+		return ComputedColumn{}, fmt.Errorf("%s: hash prefix width must be between 1 and 16, got %d", col.Name, width)
+	}
+	return ComputedColumn{
+		Column: Column{Name: col.Name + "_HASH_PREFIX", Type: typeOfString},
+		Compute: func(values []interface{}) (interface{}, error) {
+			s, ok := formatValue(values[index])
+			if !ok {
+				return strings.Repeat("0", width), nil
+			}
+			return hashPrefix(s, width), nil
+		},
+	}, nil
+}
+
+// hashPrefix returns the first width hex characters of the 64-bit FNV-1a hash of s,
+// in little-endian byte order, as the low bits of FNV are mixed better for short inputs.
+func hashPrefix(s string, width int) string {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(s))
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], h.Sum64())
+	return hex.EncodeToString(b[:])[:width]
+}
+
+// NewFingerprintColumn returns the name column, the hex-encoded 64-bit xxHash of the raw string values
+// of the columns of the indexes, concatenated with the \x1f (unit separator) between them.
+// NULLs are hashed as \x00, to differ from the empty string.
+func NewFingerprintColumn(name string, indexes []int) ComputedColumn {
+	var buf []byte
+	selected := make([]interface{}, len(indexes))
+	return ComputedColumn{
+		Column: Column{Name: name, Type: typeOfString},
+		Compute: func(values []interface{}) (interface{}, error) {
+			for k, i := range indexes {
+				selected[k] = values[i]
+			}
+			buf = appendRowKey(buf[:0], selected)
+			return fmt.Sprintf("%016x", xxhash.Sum64(buf)), nil
+		},
+	}
+}
+
+// appendRowKey appends the raw string values (\x00 for NULL) separated by \x1f to buf.
+func appendRowKey(buf []byte, values []interface{}) []byte {
+	for i, v := range values {
+		if i != 0 {
+			buf = append(buf, '\x1f')
+		}
+		if s, ok := formatValue(v); ok {
+			buf = append(buf, s...)
+		} else {
+			buf = append(buf, 0)
+		}
+	}
+	return buf
+}
@@ -0,0 +1,219 @@
+// Copyright 2021 Tamás Gulácsi.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package dbcsv_test
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/UNO-SOFT/dbcsv"
+)
+
+func TestRunningMinMaxNumber(t *testing.T) {
+	// godror returns the NUMBER columns as strings, with as many decimals as needed
+	columns := []dbcsv.Column{{Name: "N", Type: typeOfString, DatabaseTypeName: "NUMBER", Scale: 2}}
+	rows := &sliceRows{values: [][]interface{}{{"9.5"}, {"10"}, {nil}, {"-0.25"}, {"100.01"}}}
+	filled, cols := dbcsv.AppendColumns(rows, columns,
+		dbcsv.NewRunningMin(columns[0], 0), dbcsv.NewRunningMax(columns[0], 0))
+	var buf bytes.Buffer
+	if err := dbcsv.DumpCSV(context.Background(), &buf, filled, cols, true, ";", false, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), "N;N_RMIN;N_RMAX\n9.5;9.5;9.5\n10;9.5;10\n;9.5;10\n-0.25;-0.25;10\n100.01;-0.25;100.01\n"; got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+}
+
+func TestWindowAvgNumber(t *testing.T) {
+	col := dbcsv.Column{Name: "N", Type: typeOfString, DatabaseTypeName: "NUMBER"}
+	c, err := dbcsv.NewWindowAvg(col, 0, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, tc := range []struct {
+		In, Want interface{}
+	}{
+		{"1", 1.0},
+		{"2.5", 1.75},
+		{nil, 1.75},
+		{"-0.5", 1.0},
+	} {
+		got, err := c.Compute([]interface{}{tc.In})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tc.Want {
+			t.Errorf("%d. %v: got %v, wanted %v", i, tc.In, got, tc.Want)
+		}
+	}
+	if _, err = c.Compute([]interface{}{"a"}); err == nil {
+		t.Error("wanted error for non-numeric string")
+	}
+}
+
+func TestRunningMinMaxMismatch(t *testing.T) {
+	var r dbcsv.RunningMinMax
+	if err := r.Add(int64(1)); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Add("a"); err == nil {
+		t.Error("wanted error comparing a string with an int64")
+	}
+}
+
+func TestHashPrefix(t *testing.T) {
+	col := dbcsv.Column{Name: "K", Type: typeOfString}
+	for _, tc := range []struct {
+		In    interface{}
+		Width int
+		Want  string
+	}{
+		{"", 16, "25232284e49cf2cb"},
+		{"a", 16, "8cec01864cdc63af"},
+		{"a", 4, "8cec"},
+		{int64(42), 3, "239"},
+		{"foobar", 1, "e"},
+		{nil, 4, "0000"},
+	} {
+		c, err := dbcsv.NewHashPrefix(col, 0, tc.Width)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := c.Compute([]interface{}{tc.In})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tc.Want {
+			t.Errorf("%v (width=%d): got %q, wanted %q", tc.In, tc.Width, got, tc.Want)
+		}
+	}
+	for _, width := range []int{0, 17} {
+		if _, err := dbcsv.NewHashPrefix(col, 0, width); err == nil {
+			t.Errorf("width=%d: wanted error", width)
+		}
+	}
+}
+
+func TestDateAge(t *testing.T) {
+	col := dbcsv.Column{Name: "BORN", Type: reflect.TypeOf(time.Time{})}
+	ref := time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC)
+	date := func(y int, m time.Month, d, h int) time.Time { return time.Date(y, m, d, h, 0, 0, 0, time.UTC) }
+	for _, tc := range []struct {
+		In   interface{}
+		Unit string
+		Want interface{}
+	}{
+		{nil, "days", nil},
+		{date(2024, 3, 14, 12), "days", int64(1)},
+		{date(2024, 3, 14, 13), "days", int64(0)},
+		{date(2024, 2, 15, 12), "months", int64(1)},
+		{date(2024, 2, 15, 13), "months", int64(0)},
+		{date(2024, 2, 16, 0), "months", int64(0)},
+		{date(2023, 3, 31, 0), "months", int64(11)},
+		{date(2024, 4, 15, 12), "months", int64(-1)},
+		{date(2024, 4, 16, 0), "months", int64(-1)},
+		{date(2023, 3, 15, 12), "years", int64(1)},
+		{date(2023, 3, 16, 0), "years", int64(0)},
+		{date(2020, 2, 29, 0), "years", int64(4)},
+		{date(2025, 3, 15, 12), "years", int64(-1)},
+		// the same instant, in the time zone of ref
+		{time.Date(2024, 2, 15, 13, 0, 0, 0, time.FixedZone("CET", 3600)), "months", int64(1)},
+	} {
+		c, err := dbcsv.NewDateAge(col, 0, tc.Unit, ref)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := c.Compute([]interface{}{tc.In})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tc.Want {
+			t.Errorf("%v (%s): got %v, wanted %v", tc.In, tc.Unit, got, tc.Want)
+		}
+	}
+	if _, err := dbcsv.NewDateAge(col, 0, "weeks", ref); err == nil {
+		t.Error("wanted error for weeks")
+	}
+}
+
+func TestWindowAvg(t *testing.T) {
+	col := dbcsv.Column{Name: "X", Type: typeOfInt64}
+	c, err := dbcsv.NewWindowAvg(col, 0, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, tc := range []struct {
+		In, Want interface{}
+	}{
+		{nil, nil},
+		{int64(1), 1.0},
+		{2.0, 1.5},
+		{nil, 1.5},
+		{int64(3), 2.0},
+		{int64(4), 3.0},
+		{int64(-9), -2.0 / 3},
+	} {
+		got, err := c.Compute([]interface{}{tc.In})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tc.Want {
+			t.Errorf("%d. %v: got %v, wanted %v", i, tc.In, got, tc.Want)
+		}
+	}
+	if _, err = c.Compute([]interface{}{"a"}); err == nil {
+		t.Error("wanted error for string")
+	}
+	if _, err = dbcsv.NewWindowAvg(col, 0, 0); err == nil {
+		t.Error("wanted error for zero window")
+	}
+}
+
+func TestStandardizeNumber(t *testing.T) {
+	columns := []dbcsv.Column{{Name: "N", Type: typeOfString, DatabaseTypeName: "NUMBER"}}
+	rows, ss, err := dbcsv.ComputeStandardizations(&sliceRows{values: [][]interface{}{{"1"}, {nil}, {"3.0"}}}, columns, "N")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (dbcsv.Standardization{Mean: 2, StdDev: 1}); ss["N"] != want {
+		t.Errorf("got %v, wanted %v", ss["N"], want)
+	}
+	std, cols, err := dbcsv.Standardize(rows, columns, ss)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err = dbcsv.DumpCSV(context.Background(), &buf, std, cols, false, ";", false, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), "-1\n\n1\n"; got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+}
+
+func TestMinMaxScaleNumber(t *testing.T) {
+	columns := []dbcsv.Column{{Name: "N", Type: typeOfString, DatabaseTypeName: "NUMBER"}}
+	rows, mm, err := dbcsv.ComputeMinMaxes(&sliceRows{values: [][]interface{}{{"2.5"}, {nil}, {"-7.5"}, {"0"}}}, columns, "N")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (dbcsv.MinMax{Min: -7.5, Max: 2.5}); mm["N"] != want {
+		t.Errorf("got %v, wanted %v", mm["N"], want)
+	}
+	scaled, cols, err := dbcsv.MinMaxScale(rows, columns, mm, dbcsv.MinMaxes{"N": {Min: 0, Max: 100}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err = dbcsv.DumpCSV(context.Background(), &buf, scaled, cols, false, ";", false, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), "100\n\n0\n75\n"; got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright 2021 Tamás Gulácsi.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package dbcsv
+
+import (
+	"bufio"
+	"embed"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+//go:embed iso3166.csv
+var countryFS embed.FS
+
+// Country is an ISO 3166-1 country.
+type Country struct {
+	Alpha2, Alpha3, Numeric string
+}
+
+// Code returns the code of the country in the given format: alpha-2, alpha-3 or numeric.
+func (c Country) Code(format string) (string, error) {
+	switch format {
+	case "alpha-2", "alpha2", "":
+		return c.Alpha2, nil
+	case "alpha-3", "alpha3":
+		return c.Alpha3, nil
+	case "numeric":
+		return c.Numeric, nil
+	}
+	return "", fmt.Errorf("unknown country code format %q", format)
+}
+
+var (
+	countriesOnce sync.Once
+	countries     map[string]Country
+)
+
+// LookupCountry returns the country by its name, common variants of its name,
+// or its alpha-2, alpha-3 or numeric code - case insensitively.
+func LookupCountry(s string) (Country, bool) {
+	countriesOnce.Do(func() {
+		fh, err := countryFS.Open("iso3166.csv")
+		if err != nil {
+			panic(err)
+		}
+		defer fh.Close()
+		countries = make(map[string]Country, 1024)
+		scanner := bufio.NewScanner(fh)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if strings.HasPrefix(line, "#") {
+				continue
+			}
+			parts := strings.SplitN(line, ";", 4)
+			if len(parts) != 4 {
+				continue
+			}
+			c := Country{Alpha2: parts[0], Alpha3: parts[1], Numeric: parts[2]}
+			for _, k := range append(parts[:3], strings.Split(parts[3], "|")...) {
+				countries[countryKey(k)] = c
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			panic(err)
+		}
+	})
+	c, ok := countries[countryKey(s)]
+	return c, ok
+}
+
+func countryKey(s string) string { return strings.ToLower(strings.Join(strings.Fields(s), " ")) }
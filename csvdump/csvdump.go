@@ -7,29 +7,46 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"database/sql"
 	"database/sql/driver"
+	"encoding/csv"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"log"
+	"math"
+	"net"
 	"os"
 	"path/filepath"
+	"regexp"
+	"runtime"
 	"strconv"
 	"strings"
+	"sync/atomic"
+	"text/tabwriter"
+	"text/template"
+	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"golang.org/x/sync/errgroup"
 	"golang.org/x/text/encoding"
+	"google.golang.org/protobuf/reflect/protoreflect"
 
 	"github.com/UNO-SOFT/dbcsv"
 	"github.com/UNO-SOFT/spreadsheet"
 	"github.com/UNO-SOFT/spreadsheet/ods"
 	"github.com/UNO-SOFT/spreadsheet/xlsx"
+	"github.com/go-sql-driver/mysql"
 	"github.com/godror/godror"
 	"github.com/klauspost/compress/gzip"
 	"github.com/klauspost/compress/zstd"
+	_ "github.com/lib/pq" // registers the postgres driver
 )
 
 func main() {
@@ -40,18 +57,219 @@ func main() {
 
 func Main() error {
 	flagConnect := flag.String("connect", os.Getenv("DB_ID"), "user/passw@sid to connect to")
-	flagDateFormat := flag.String("date", dbcsv.DateFormat, "date format, in Go notation")
+	flagDriver := flag.String("driver", "godror", "database/sql driver of -connect: godror (Oracle), postgres (PostgreSQL connection string or URL) or mysql (user:pass@tcp(host:port)/db)")
+	flagDateFormat := flag.String("date", dbcsv.DefaultDateFormat, "date format, in Go notation; {MON} and {WEEKDAY} are replaced by the month and weekday names of -date-locale; COL1:FORMAT1,COL2:FORMAT2,... sets the format of the columns, the rest uses the format without column name (if any)")
+	flagNumberLocale := flag.String("number-locale", "", "format the numbers by the conventions (grouping, decimal separator, digits) of this locale, such as de_DE or ar")
+	flagDateLocale := flag.String("date-locale", "", "locale of the {MON} and {WEEKDAY} names of -date, such as de_DE or fr_FR (default English)")
 	flagSep := flag.String("sep", ";", "separator")
+	flagTimezone := flag.String("timezone", "", "convert the dates into this time zone (such as America/New_York or Local) for csv, tsv and spreadsheet output")
+	flagMaxColWidth := flag.Int("max-col-width", 0, "truncate the string and LOB values longer than this many characters (0 means no limit), for csv, tsv and spreadsheet output")
+	flagMaxColWidthSuffix := flag.String("max-col-width-suffix", "…", "appended to the values truncated by -max-col-width")
+	flagNull := flag.String("null", "", `string of the NULL values in the CSV (such as \N for MySQL's LOAD DATA INFILE); empty by default`)
 	flagHeader := flag.Bool("header", true, "print header")
-	flagEnc := flag.String("encoding", dbcsv.DefaultEncoding.Name, "encoding to use for output")
+	flagEnc := flag.String("encoding", dbcsv.DefaultEncoding.Name, "encoding to use for output and -query-file")
 	flagOut := flag.String("o", "-", "output (defaults to stdout)")
 	flagRaw := flag.Bool("raw", false, "not real csv, just dump the raw data")
 	flagSort := flag.Bool("sort", false, "sort data")
 	flagSheets := dbcsv.FlagStrings()
 	flag.Var(flagSheets, "sheet", "each -sheet=name:SELECT will become a separate sheet on the output ods")
 	flagVerbose := flag.Bool("v", false, "verbose logging")
+	flagProgress := flag.Duration("progress", 0, "report the progress (rows=N elapsed=T speed=R) to stderr at this interval, such as 30s")
+	flagRowCount := flag.Bool("row-count", false, "print the number of rows dumped (N rows exported) to stderr at the end, regardless of -v")
 	flagCompress := flag.String("compress", "", "compress output with gz/gzip or zst/zstd/zstandard")
+	flagCompressLevel := flag.Int("compress-level", -1, "compression level (gzip: 0-9, zstd: 1-22), -1 is the default")
+	flagPageSize := flag.Int("page-size", 0, "return only this many rows (of a table, not of a raw SELECT)")
+	flagPageOffset := flag.Int("page-offset", 0, "skip this many rows (of a table, not of a raw SELECT)")
+	flagPage := flag.Int("page", 0, "return the page-th (1-based) page of -page-size rows")
+	flagPageRowNum := flag.Bool("page-rownum", false, "use ROWNUM for paging, for Oracle older than 12c")
+	flagLimit := flag.Int("limit", 0, "return only this many rows (of a table or a raw SELECT)")
+	flagOffset := flag.Int("offset", 0, "skip this many rows (of a table or a raw SELECT)")
+	flagStats := flag.Bool("stats", false, "print statistics (such as the total row count when paging) to stderr")
+	flagQueryCacheDir := flag.String("query-cache-dir", "", "cache the query results in Arrow IPC stream files in this directory, and read them from there on the next runs")
+	flagQueryCacheTTL := flag.Duration("query-cache-ttl", time.Hour, "the -query-cache-dir files older than this are ignored and overwritten (0 means never)")
+	flagParam := dbcsv.FlagStrings()
+	flag.Var(flagParam, "param", "NAME=VALUE bind parameter of the query, in order: named (:NAME) with Oracle, positional (? or $1) with the other drivers")
+	flagQueryFile := flag.String("query-file", "", "read the query from this file (in -encoding) instead of the arguments or stdin")
 	flagCall := flag.Bool("call", false, "the first argument is not the WHERE, but the PL/SQL block to be called, the followings are not the columns but the arguments")
+	flagInputXLSX := flag.String("input-xlsx", "", "read the rows from this XLSX file (first row is the header) instead of the database")
+	flagInputODS := flag.String("input-ods", "", "read the rows from this ODS file (first row is the header) instead of the database")
+	flagInputSheet := flag.String("input-sheet", "", "name of the sheet to read with -input-xlsx or -input-ods (defaults to the first)")
+	flagFormat := flag.String("format", "csv", "output format: csv, tsv, fixed, markdown, bcp, dot, json, key-value-json, nquads, pandas-pickle, parquet, proto-json, sqlite, superset, syslog, teradata-fastload or xlsx-template")
+	flagFixedWidths := flag.String("fixed-widths", "", "W1,W2,... widths of the columns of -format=fixed (defaults to the longest values, reading all the rows into memory)")
+	flagFixedSep := flag.String("fixed-sep", " ", "separator between the columns of -format=fixed")
+	flagSQLiteTable := flag.String("sqlite-table", "", "name of the table created by -format=sqlite (defaults to the table argument, or \"exported\")")
+	flagSQLiteBatchSize := flag.Int("sqlite-batch-size", 1000, "number of rows inserted in one transaction by -format=sqlite")
+	flagKVKeyCol := flag.String("kv-key-col", "", "column of the keys for -format=key-value-json")
+	flagKVMerge := flag.Bool("kv-merge", false, "write one merged JSON object instead of one object per line for -format=key-value-json")
+	flagRDFSubjectCol := flag.String("rdf-subject-col", "", "column of the subject IRI for -format=nquads")
+	flagRDFPredicatePrefix := flag.String("rdf-predicate-prefix", "", "IRI prefix of the predicates (the column names) for -format=nquads")
+	flagRDFObjectCol := flag.String("rdf-object-col", "", "column of the object for -format=nquads (defaults to all the other columns)")
+	flagRDFGraphCol := flag.String("rdf-graph-col", "", "column of the graph IRI for -format=nquads")
+	flagBCPFormatFile := flag.String("bcp-format-file", "", "write the XML format file of -format=bcp to this file")
+	flagDetectTypes := flag.Bool("detect-types", false, "detect the types (number, date) of the string columns from their values")
+	flagInferRows := flag.Int("infer-rows", 1, "number of rows to scan for -detect-types")
+	flagWindowAvg := dbcsv.FlagStrings()
+	flag.Var(flagWindowAvg, "window-avg", "COL:N appends the COL_AVG_N column, the moving average of the last N non-NULL values of the column")
+	flagWindowSize := flag.Int("window-size", 0, "number of rows of the sliding window of -window-expr")
+	flagWindowExpr := flag.String("window-expr", "", "expression (github.com/expr-lang/expr) evaluated over the sliding window of the last -window-size rows, appended as the -window-name column: the column names are the values of the current row, window is the list of the rows (oldest first), such as sum(map(window, .AMOUNT)) / len(window)")
+	flagWindowName := flag.String("window-name", "WINDOW", "name of the -window-expr column")
+	flagWindowPartial := flag.Bool("window-partial", false, "write also the first -window-size - 1 rows of -window-expr, with partial windows")
+	flagRunningMin := dbcsv.FlagStrings()
+	flag.Var(flagRunningMin, "running-min", "COL appends the COL_RMIN column, the minimum of the column's values so far")
+	flagRunningMax := dbcsv.FlagStrings()
+	flag.Var(flagRunningMax, "running-max", "COL appends the COL_RMAX column, the maximum of the column's values so far")
+	flagColTmpl := dbcsv.FlagStrings()
+	flag.Var(flagColTmpl, "col-tmpl", "COL:TEMPLATE formats the column with the text/template, with .Value (the value as string) and .Row (map of all the column values)")
+	flagBaseConvert := dbcsv.FlagStrings()
+	flag.Var(flagBaseConvert, "base-convert", "COL:FROM_BASE:TO_BASE converts the integers of the column to the 2, 8, 10 or 16 base")
+	flagBaseConvertPrefix := flag.Bool("base-convert-prefix", false, "prefix the -base-convert hexadecimal values with 0x and binary with 0b")
+	flagSplit := dbcsv.FlagStrings()
+	flag.Var(flagSplit, "split", "COL:DELIMITER:N1,N2,... splits the column on DELIMITER into the N1, N2... columns")
+	flagSplitKeepSource := flag.Bool("split-keep-source", false, "keep the source columns of -split")
+	flagJoin := dbcsv.FlagStrings()
+	flag.Var(flagJoin, "join", "COL1,COL2,...:DELIMITER:NEW_NAME appends the NEW_NAME column, the values of the columns joined with DELIMITER")
+	flagJoinDropSources := flag.Bool("join-drop-sources", false, "drop the source columns of -join")
+	flagExcludeColumns := flag.String("exclude-columns", "", "COL1,COL2,... columns to drop from the output")
+	flagColumnMap := flag.String("column-map", "", "CSV file of original,renamed column name pairs to rename the columns")
+	flagHeaderCase := flag.String("header-case", "", "transform the column names: upper, lower or snake (MyColumn to my_column)")
+	flagColType := dbcsv.FlagStrings()
+	flag.Var(flagColType, "col-type", "COL1:TYPE1,COL2:TYPE2,... overrides the type reported by the driver: int, uint, float, bool, date, string or binary")
+	flagBloomDedupe := flag.Bool("bloom-dedupe", false, "skip the duplicate rows, remembered in a Bloom filter: needs constant memory, but skips also some (-bloom-fp ratio) unique rows")
+	flagBloomSize := flag.Uint("bloom-size", 10_000_000, "expected number of distinct rows of -bloom-dedupe")
+	flagBloomFP := flag.Float64("bloom-fp", 0.001, "false positive rate of -bloom-dedupe: the probability of skipping a unique row")
+	flagFingerprint := dbcsv.FlagStrings()
+	flag.Var(flagFingerprint, "fingerprint", "COL1,COL2,...:NEW_NAME appends the NEW_NAME column, the hex xxHash fingerprint of the values of the columns")
+	flagFingerprintAll := flag.Bool("fingerprint-all", false, "append the _ROW_HASH column, the hex xxHash fingerprint of all the columns")
+	flagNumberWords := dbcsv.FlagStrings()
+	flag.Var(flagNumberWords, "number-words", "COL writes the numbers of the column in words")
+	flagNumberWordsLocale := flag.String("number-words-locale", "en", "language of -number-words: en or hu")
+	flagOrdinal := dbcsv.FlagStrings()
+	flag.Var(flagOrdinal, "ordinal", "COL writes the integers of the column as English ordinals (1st, 2nd, 3rd...)")
+	flagQR := dbcsv.FlagStrings()
+	flag.Var(flagQR, "qr", "COL writes the values of the column as (multi-line) QR codes; only practical for terminal output")
+	flagSIFormat := dbcsv.FlagStrings()
+	flag.Var(flagSIFormat, "si-format", "COL[:binary] writes the numbers of the column with SI magnitude prefixes (1.50k, 2.30M), or with binary, IEC prefixes (1.50Ki, 2.30Mi)")
+	flagSIPrecision := flag.Int("si-precision", 2, "number of decimals of -si-format")
+	flagBoolLabels := dbcsv.FlagStrings()
+	flag.Var(flagBoolLabels, "bool-labels", "COL:TRUE_LABEL:FALSE_LABEL writes TRUE_LABEL for the 1, Y, T and TRUE (case-insensitive) values of the column, FALSE_LABEL for the others")
+	flagScientific := dbcsv.FlagStrings()
+	flag.Var(flagScientific, "scientific", "COL[:PREC] writes the numbers of the column in scientific notation (1.23e+06) with PREC decimals (default: as few as needed)")
+	flagScientificUpper := dbcsv.FlagStrings()
+	flag.Var(flagScientificUpper, "scientific-upper", "COL[:PREC] is -scientific with upper case E (1.23E+06)")
+	flagScientificThreshold := flag.Float64("scientific-threshold", 1e15, "-scientific writes only the values above THRESHOLD or below 1/THRESHOLD (in absolute value) in scientific notation; 0 writes all")
+	flagPct := dbcsv.FlagStrings()
+	flag.Var(flagPct, "pct", "COL[:PLACES] writes the numbers of the column multiplied by 100, with PLACES (default 2) decimals, followed by % (12.34% for 0.1234)")
+	flagPctScaled := dbcsv.FlagStrings()
+	flag.Var(flagPctScaled, "pct-already-scaled", "COL[:PLACES] is -pct for the columns already storing percents (12.34% for 12.34)")
+	flagFileSize := dbcsv.FlagStrings()
+	flag.Var(flagFileSize, "filesize", "COL[:binary] writes the integers of the column as file sizes (1.23 GB), or with binary, with 1024-based units (1.15 GiB)")
+	flagDuration := dbcsv.FlagStrings()
+	flag.Var(flagDuration, "duration", "COL:UNIT writes the numbers of the column as durations (such as 1h23m45s) of UNIT: ns, us, ms, s, min or h")
+	flagDurationPrecision := flag.Int("duration-precision", 0, "number of components of -duration (2 writes 1h23m for 1h23m45s); 0 writes all")
+	flagBlob := flag.String("blob", "base64", "encoding of the binary (BLOB, RAW, BYTEA) values: base64, hex or skip (writes an empty cell)")
+	flagLobMax := flag.Int64("lob-max", 0, "maximum number of bytes written from a CLOB, NCLOB or BLOB cell; 0 means unlimited")
+	flagSchemaExport := flag.Bool("schema-export", false, "write the CREATE TABLE statement of the result columns instead of the rows")
+	flagSchemaExportDialect := flag.String("schema-export-dialect", "oracle", "SQL dialect of -schema-export: oracle, postgresql, mysql, sqlite or bigquery")
+	flagSchemaExportTable := flag.String("schema-export-table", "", "table name for -schema-export (defaults to the table argument)")
+	flagPKCol := dbcsv.FlagStrings()
+	flag.Var(flagPKCol, "pk-col", "primary key column for -schema-export")
+	flagWhereBuilder := flag.Bool("where-builder", false, "read filters (COL op VALUE, COL LIKE 'PATTERN', COL IN (V1, V2), COL IS NULL) from stdin, and print the WHERE clause built from them, instead of dumping")
+	flagAutoDateDetect := flag.Bool("auto-date-detect", false, "with -detect-types, promote the string columns whose values all match the same common date layout to dates")
+	flagTimestampFormat := flag.String("timestamp-format", "", "output format of the -auto-date-detect columns with time part, in Go notation (defaults to -date)")
+	flagFilterSQL := flag.String("filter-sql", "", "SQLite WHERE expression to filter the rows with, after loading them into an in-memory SQLite table")
+	flagImpute := dbcsv.FlagStrings()
+	flag.Var(flagImpute, "impute", "COL:STRATEGY replaces the NULLs of the column: STRATEGY is mean, median, mode, zero, empty or a literal value")
+	flagImputeTwoPass := flag.Bool("impute-two-pass", false, "read all the rows into memory first, to compute the mean, median and mode of -impute")
+	flagStandardize := dbcsv.FlagStrings()
+	flag.Var(flagStandardize, "standardize", "COL replaces the numbers of the column with their Z-score, (value - mean) / stddev; reads all the rows into memory")
+	flagStandardizeFile := flag.String("standardize-file", "", "JSON file of the mean and stddev of the -standardize columns: used if exists (for each -sheet), written otherwise (with the columns of all the -sheets)")
+	flagMinMax := dbcsv.FlagStrings()
+	flag.Var(flagMinMax, "minmax", "COL[:MIN_OUT:MAX_OUT] scales the numbers of the column from their min-max range to MIN_OUT-MAX_OUT (default 0-1); reads all the rows into memory")
+	flagMinMaxParamsFile := flag.String("minmax-params-file", "", "JSON file of the min and max of the -minmax columns: used if exists (for each -sheet), written otherwise (with the columns of all the -sheets)")
+	flagFFill := dbcsv.FlagStrings()
+	flag.Var(flagFFill, "ffill", "COL writes the last non-NULL value of the column instead of NULLs")
+	flagBFill := dbcsv.FlagStrings()
+	flag.Var(flagBFill, "bfill", "COL replaces the NULLs of the column with the next non-NULL value; reads all the rows into memory")
+	flagSupersetDatasource := flag.String("superset-datasource", "", "dataset (table) name for -format=superset (defaults to the table argument)")
+	flagSupersetSchema := flag.String("superset-schema", "", "schema of the dataset for -format=superset")
+	flagSupersetDatabaseUUID := flag.String("superset-database-uuid", "", "uuid of the Superset database of the dataset for -format=superset")
+	flagAsyncWrite := flag.Bool("async-write", false, "write the output in a background goroutine, so slow storage does not block the query")
+	flagAsyncQueueDepth := flag.Int("async-queue-depth", 64, "number of buffers queued for -async-write before blocking")
+	flagCoalesce := dbcsv.FlagStrings()
+	flag.Var(flagCoalesce, "coalesce", "ALIAS:COL1,COL2,... appends the ALIAS column, the first non-NULL, non-empty value of the columns")
+	flagCaseWhen := dbcsv.FlagStrings()
+	flag.Var(flagCaseWhen, "case-when", "COL:VALUE1→OUT1,VALUE2→OUT2,...:DEFAULT replaces the values of the column (-> can be used instead of →); NULLs and other values become DEFAULT")
+	flagEmojiMap := dbcsv.FlagStrings()
+	flag.Var(flagEmojiMap, "emoji-map", "COL:VALUE1→EMOJI1,VALUE2→EMOJI2,...[:DEFAULT] replaces the values of the column with emojis (-> can be used instead of →); NULLs and other values become DEFAULT, or stay as is without DEFAULT")
+	flagCaseWhenIgnoreCase := flag.Bool("case-when-ignore-case", false, "match the values of -case-when case-insensitively")
+	flagDOTSourceCol := flag.String("dot-source-col", "", "column of the edge sources for -format=dot")
+	flagDOTTargetCol := flag.String("dot-target-col", "", "column of the edge targets for -format=dot")
+	flagDOTLabelCol := flag.String("dot-label-col", "", "column of the edge labels for -format=dot")
+	flagDOTWeightCol := flag.String("dot-weight-col", "", "column of the edge weights for -format=dot")
+	flagDOTDirected := flag.Bool("dot-directed", false, "write a directed graph (digraph) for -format=dot (the default)")
+	flagDOTUndirected := flag.Bool("dot-undirected", false, "write an undirected graph for -format=dot")
+	flagDateAge := dbcsv.FlagStrings()
+	flag.Var(flagDateAge, "date-age", "COL:UNIT[:REF_DATE] appends the COL_age column, the age of the date at REF_DATE (default: now) in days, months or years")
+	flagHashPrefix := dbcsv.FlagStrings()
+	flag.Var(flagHashPrefix, "hash-prefix", "COL[:WIDTH] appends the COL_HASH_PREFIX column, the first WIDTH (default 2) hex characters of the FNV-1a hash of the value")
+	flagSplitBy := flag.String("split-by", "", "write the rows into separate <base>-<value>.csv files by the values of this column (with % and the characters unsafe in file names percent-encoded, such as a%2Fb for a/b), the NULLs into <base>_NULL.csv (base is -o without extension)")
+	flagSplitRows := flag.Int("split-rows", 0, "write the rows into <base>_001.csv, <base>_002.csv... files of this many rows (base is -o without extension)")
+	flagSplitByMaxFiles := flag.Int("split-by-max-files", 1000, "warn when -split-by has more distinct values than this")
+	flagProtoGenDescriptor := flag.String("proto-gen-descriptor", "", "write the .proto file of a message with a field for each result column to this file")
+	flagProtoMessage := flag.String("proto-message", "Row", "name of the Protobuf message of -proto-gen-descriptor and -format=proto-json")
+	flagProtoSchema := flag.String("proto-schema", "", ".proto file of the -proto-message message for -format=proto-json (defaults to a message generated from the columns)")
+	flagColEncoding := dbcsv.FlagStrings()
+	flag.Var(flagColEncoding, "col-encoding", "COL:ENCODING decodes the values of the column from ENCODING (such as cp1252) to UTF-8")
+	flagIconv := dbcsv.FlagStrings()
+	flag.Var(flagIconv, "iconv", "COL:FROM_CHARSET:TO_CHARSET converts the string values of the column between the (IANA named) character sets; keep -encoding=utf-8 for non-UTF-8 targets")
+	flagBoolTrue := flag.String("bool-true", "true", "string of the true boolean values")
+	flagBoolFalse := flag.String("bool-false", "false", "string of the false boolean values")
+	flagPipe := flag.String("pipe", "", "shell command to pipe the output rows through (such as awk, sed or jq), line by line; the header is not piped")
+	flagLookup := dbcsv.FlagStrings()
+	flag.Var(flagLookup, "lookup", "COL:LOOKUP_CSV:KEY_COL:VALUE_COL:OUTPUT_COL appends the OUTPUT_COL column, the VALUE_COL of the row of LOOKUP_CSV whose KEY_COL is the value of COL (empty if not found)")
+	flagLookupSep := flag.String("lookup-sep", ",", "separator of the -lookup CSV files")
+	flagLookupCaseSensitive := flag.Bool("lookup-case-sensitive", false, "match the -lookup keys case-sensitively")
+	flagDBLookup := flag.String("db-lookup", "", "COL:QUERY appends the COL_LOOKUP column, the result of QUERY (such as SELECT value FROM ref_table WHERE key = :1) for the value of COL")
+	flagDBLookupCacheSize := flag.Int("db-lookup-cache-size", 10000, "number of -db-lookup results to cache (LRU)")
+	flagDBLookupOutputCol := flag.String("db-lookup-output-col", "", "name of the -db-lookup column (defaults to COL_LOOKUP)")
+	flagDryRun := flag.Bool("dry-run", false, "print the columns (name, database type, Go type, nullability) of the query without fetching any row, instead of dumping")
+	flagDryRunExplain := flag.Bool("dry-run-explain", false, "print the execution plan and the estimated row count of the query, instead of dumping")
+	flagSparse := flag.Bool("sparse", false, "write only the non-NULL, non-default columns, as name:value pairs")
+	flagSparseDefault := flag.String("sparse-default", "", "the default value omitted by -sparse")
+	flagRowFormatLua := flag.String("row-format-lua", "", "Lua script with a format_row(cols) function returning the output line of each row")
+	flagRowFormatStarlark := flag.String("row-format-starlark", "", "Starlark script with a format_row(cols) function returning the output line of each row")
+	flagTeradataRecordMode := flag.String("teradata-record-mode", "variable", "record mode of -format=teradata-fastload: variable or fixed")
+	flagTeradataCtlFile := flag.String("teradata-ctl-file", "", "write the FastLoad control script of -format=teradata-fastload to this file (defaults to the output with .ctl extension)")
+	flagBCPMode := flag.String("bcp-mode", "char", "mode of -format=bcp: char or native")
+	flagXLSXTemplate := flag.String("xlsx-template", "", "XLSX template file to fill the data into, for -format=xlsx-template")
+	flagXLSXTemplateSheet := flag.String("xlsx-template-sheet", "", "name of the sheet of -xlsx-template to fill (defaults to the first)")
+	flagXLSXDataStartRow := flag.Int("xlsx-data-start-row", 0, "first row (1-based) of the data in -xlsx-template (defaults to after the last used row)")
+	flagSyslogFacility := flag.Int("syslog-facility", 1, "syslog facility (0-23) for -format=syslog")
+	flagSyslogSeverity := flag.Int("syslog-severity", 6, "syslog severity (0-7) for -format=syslog")
+	flagSyslogAppName := flag.String("syslog-app-name", "csvdump", "syslog APP-NAME for -format=syslog")
+	flagSyslogAddr := flag.String("syslog-addr", "", "send -format=syslog messages to this udp://host:port or tcp://host:port address, instead of the local syslog socket")
+	flagIPNormalize := dbcsv.FlagStrings()
+	flag.Var(flagIPNormalize, "ip-normalize", "COL normalizes the IP addresses in the column")
+	flagIPNormalizeVersion := flag.Int("ip-normalize-version", 0, "force IPv4 (4) or IPv6 (6) representation for -ip-normalize")
+	flagCountryNormalize := dbcsv.FlagStrings()
+	flag.Var(flagCountryNormalize, "country-normalize", "COL converts the country names in the column to ISO 3166-1 codes")
+	flagCountryNormalizeFormat := flag.String("country-normalize-format", "alpha-2", "code format for -country-normalize: alpha-2, alpha-3 or numeric")
+	flagRangeValidate := dbcsv.FlagStrings()
+	flag.Var(flagRangeValidate, "range-validate", "COL:MIN:MAX checks that the numeric/date column's values are between MIN and MAX (empty means unbounded); the dates are in the -date format of the column")
+	flagRangeValidateLog := flag.String("range-validate-log", "", "write range violations to this file")
+	flagRangeValidateWarnOnly := flag.Bool("range-validate-warn-only", false, "do not abort on range violations")
+	flagUniqueValidate := dbcsv.FlagStrings()
+	flag.Var(flagUniqueValidate, "unique-validate", "COL checks that the column has no duplicate values")
+	flagUniqueValidateReportAll := flag.Bool("unique-validate-report-all", false, "do not abort on the first duplicate, but report all of them at the end")
+	flagUniqueValidateMaxValues := flag.Int("unique-validate-max-values", 0, "remember at most this many distinct values per column (0 is unlimited)")
+	flagRegexValidate := dbcsv.FlagStrings()
+	flag.Var(flagRegexValidate, "regex-validate", "COL:PATTERN checks that the column's values match the (anchored) regular expression")
+	flagRegexValidateWarnOnly := flag.Bool("regex-validate-warn-only", false, "do not abort on regex violations")
+	flagRowValidate := dbcsv.FlagStrings()
+	flag.Var(flagRowValidate, "row-validate", "boolean expression (github.com/expr-lang/expr syntax) on the columns, which must hold for each row, such as 'START_DATE <= END_DATE'; multiple expressions are AND-ed")
+	flagRowValidateWarnOnly := flag.Bool("row-validate-warn-only", false, "do not abort on row validation failures")
+	flagValidateErrorOutput := flag.String("validate-error-output", "", "write row validation failures to this file")
+	flagMaxErrors := flag.Int("max-errors", 0, "abort after this many validation failures of the -*-warn-only validations (0 means unlimited)")
 
 	flag.Usage = func() {
 		fmt.Fprintln(flag.CommandLine.Output(), strings.Replace(`Usage of {{.prog}}:
@@ -63,6 +281,10 @@ will execute a "SELECT * FROM T_able WHERE F_ield=1" and dump all the columns;
 
 will execute "BEGIN :1 := DB_lista.csv(p_a=>:2, p_b=>3); END" with p_a=1, p_b=c
 and dump all the columns of the cursor returned by the function.
+With -driver=postgres, it will execute "CALL DB_lista.csv(p_a=>$1, p_b=>$2)",
+and dump the (OUT and INOUT) parameters returned by the procedure;
+with -driver=mysql "CALL DB_lista.csv(?, ?)" (the parameters in order),
+and dump the first result set of the procedure.
 
 `, "{{.prog}}", os.Args[0], -1))
 		flag.PrintDefaults()
@@ -71,6 +293,15 @@ and dump all the columns of the cursor returned by the function.
 		*flagConnect = os.Getenv("BRUNO_ID")
 	}
 	flag.Parse()
+	if format, ok := map[string]string{
+		".json": "json", ".parquet": "parquet", ".tsv": "tsv", ".db": "sqlite", ".sqlite": "sqlite", ".md": "markdown",
+	}[strings.ToLower(filepath.Ext(*flagOut))]; ok {
+		formatSet := false
+		flag.Visit(func(f *flag.Flag) { formatSet = formatSet || f.Name == "format" })
+		if !formatSet {
+			*flagFormat = format
+		}
+	}
 
 	Log := func(...interface{}) error { return nil }
 	if *flagVerbose {
@@ -90,28 +321,108 @@ and dump all the columns of the cursor returned by the function.
 			return nil
 		}
 	}
+	// the sum of the row counts returned by the dumps (of the sheets, too)
+	var rowCount int64
+	if *flagRowCount {
+		defer func() { fmt.Fprintf(os.Stderr, "%d rows exported\n", atomic.LoadInt64(&rowCount)) }()
+	}
 
 	enc, err := dbcsv.EncFromName(*flagEnc)
 	if err != nil {
 		return err
 	}
-	dbcsv.DateFormat = *flagDateFormat
-	dbcsv.DateEnd = `"` + strings.NewReplacer(
+	dumpOpts := dbcsv.DumperOptions{
+		Sep: *flagSep, Header: *flagHeader, Raw: *flagRaw, NullValue: *flagNull,
+		BoolTrue: *flagBoolTrue, BoolFalse: *flagBoolFalse,
+		MaxColWidth: *flagMaxColWidth, MaxColWidthSuffix: *flagMaxColWidthSuffix,
+		LobMax: *flagLobMax, ProgressInterval: *flagProgress,
+	}
+	if dumpOpts.DateFormat, dumpOpts.DateFormats, err = parseDateFormats(*flagDateFormat); err != nil {
+		return fmt.Errorf("-date=%q: %w", *flagDateFormat, err)
+	} else if dumpOpts.DateFormat == "" {
+		dumpOpts.DateFormat = flag.Lookup("date").DefValue
+	}
+	dumpOpts.DateEnd = `"` + strings.NewReplacer(
 		"2006", "9999",
 		"01", "12",
 		"02", "31",
 		"15", "23",
 		"04", "59",
 		"05", "59",
-	).Replace(dbcsv.DateFormat) + `"`
+	).Replace(dumpOpts.DateFormat) + `"`
+	switch *flagBlob {
+	case "base64", "hex", "skip":
+		dumpOpts.BlobEncoding = *flagBlob
+	default:
+		return fmt.Errorf("blob=%q: wanted base64, hex or skip", *flagBlob)
+	}
+	if *flagNumberLocale != "" {
+		if dumpOpts.NumberPrinter, err = dbcsv.NewNumberPrinter(*flagNumberLocale); err != nil {
+			return err
+		}
+	}
+	if *flagDateLocale != "" {
+		if dumpOpts.DateLocale, err = dbcsv.LookupDateNames(*flagDateLocale); err != nil {
+			return err
+		}
+	}
+	if *flagTimezone != "" {
+		if dumpOpts.Location, err = time.LoadLocation(*flagTimezone); err != nil {
+			return fmt.Errorf("timezone=%q: %w", *flagTimezone, err)
+		}
+	}
+
+	queryCache := dbcsv.QueryCache{Dir: *flagQueryCacheDir, TTL: *flagQueryCacheTTL}
+
+	if *flagSplitRows != 0 && *flagSplitBy != "" {
+		return fmt.Errorf("-split-rows and -split-by are mutually exclusive")
+	} else if *flagSplitRows < 0 {
+		return fmt.Errorf("-split-rows=%d: must be positive", *flagSplitRows)
+	}
+	splitting := *flagSplitRows != 0 || *flagSplitBy != ""
+	if splitting {
+		if !(*flagFormat == "" || *flagFormat == "csv") || len(flagSheets.Strings) != 0 {
+			return fmt.Errorf("-split-by and -split-rows need -format=csv")
+		} else if *flagCompress != "" || *flagPipe != "" {
+			return fmt.Errorf("-compress and -pipe cannot be used with -split-by or -split-rows")
+		}
+	}
 
+	// the Oracle specific query options and PL/SQL are used only with godror
+	oracle := *flagDriver == "godror"
+	inputFile := *flagInputXLSX
+	if *flagInputODS != "" {
+		if inputFile != "" {
+			return fmt.Errorf("-input-xlsx and -input-ods are mutually exclusive")
+		}
+		inputFile = *flagInputODS
+	}
 	var queries []string
 	var params []interface{}
-	if len(flagSheets.Strings) != 0 {
+	var countQry string
+	// the queries are paged (and sorted before that) by doQuery
+	var page paging
+	if inputFile != "" {
+		if len(flagSheets.Strings) != 0 {
+			return fmt.Errorf("-input-xlsx/-input-ods cannot be used with -sheet")
+		}
+	} else if len(flagSheets.Strings) != 0 {
 		queries = flagSheets.Strings
+	} else if *flagCall && len(flagParam.Strings) != 0 {
+		return fmt.Errorf("-param cannot be used with -call: the arguments are the parameters")
+	} else if *flagQueryFile != "" && (*flagCall || flag.NArg() != 0) {
+		return fmt.Errorf("-query-file cannot be used with -call or the table, where and column arguments")
 	} else if *flagCall {
 		var buf strings.Builder
-		fmt.Fprintf(&buf, `BEGIN :1 := %s(`, flag.Arg(0))
+		// the first placeholder of Oracle is the returned cursor
+		placeholder, first := ":%d", 2
+		if oracle {
+			fmt.Fprintf(&buf, `BEGIN :1 := %s(`, flag.Arg(0))
+		} else {
+			fmt.Fprintf(&buf, `CALL %s(`, flag.Arg(0))
+			placeholder, first = "$%d", 1
+		}
+		isMySQL := *flagDriver == "mysql"
 		params = make([]interface{}, flag.NArg()-1)
 		for i, x := range flag.Args()[1:] {
 			arg := strings.SplitN(x, "=", 2)
@@ -122,9 +433,18 @@ and dump all the columns of the cursor returned by the function.
 			if i != 0 {
 				buf.WriteString(", ")
 			}
-			fmt.Fprintf(&buf, "%s=>:%d", arg[0], i+2)
+			if isMySQL {
+				// no named parameters
+				buf.WriteByte('?')
+				continue
+			}
+			fmt.Fprintf(&buf, "%s=>"+placeholder, arg[0], i+first)
+		}
+		if oracle {
+			buf.WriteString("); END;")
+		} else {
+			buf.WriteString(")")
 		}
-		buf.WriteString("); END;")
 		qry := buf.String()
 		if Log != nil {
 			_ = Log("call", qry, "params", params)
@@ -141,21 +461,73 @@ and dump all the columns of the cursor returned by the function.
 				columns = flag.Args()[2:]
 			}
 		}
-		qry := getQuery(flag.Arg(0), where, columns, dbcsv.DefaultEncoding)
+		page = paging{Offset: *flagPageOffset, Size: *flagPageSize, RowNum: *flagPageRowNum, Limit: !oracle}
+		if *flagPage > 0 {
+			if page.Size <= 0 {
+				return fmt.Errorf("-page needs -page-size")
+			}
+			page.Offset = (*flagPage - 1) * page.Size
+		}
+		limit := paging{Offset: *flagOffset, Size: *flagLimit, RowNum: *flagPageRowNum, Limit: !oracle}
+		if (limit.Offset > 0 || limit.Size > 0) && (page.Offset > 0 || page.Size > 0) {
+			return fmt.Errorf("-limit and -offset are mutually exclusive with -page, -page-size and -page-offset")
+		}
+		var qry string
+		if *flagQueryFile != "" {
+			b, err := os.ReadFile(*flagQueryFile)
+			if err != nil {
+				return err
+			}
+			if b, err = enc.NewDecoder().Bytes(b); err != nil {
+				return fmt.Errorf("%s: %w", *flagQueryFile, err)
+			}
+			qry = string(b)
+		} else {
+			qry = getQuery(flag.Arg(0), where, columns, dbcsv.DefaultEncoding)
+		}
+		if *flagQueryFile != "" || countQuery(flag.Arg(0), where) == "" {
+			// only the table queries are paged by -page
+			page = paging{}
+		}
+		if limit.Offset > 0 || limit.Size > 0 {
+			// raw queries are paged, too
+			qry = "SELECT * FROM (" + strings.TrimRight(strings.TrimSpace(qry), ";") + ") Q__"
+			page = limit
+		}
 		queries = append(queries, qry)
+		for _, x := range flagParam.Strings {
+			i := strings.IndexByte(x, '=')
+			if i < 0 {
+				return fmt.Errorf("param %q: wanted NAME=VALUE", x)
+			}
+			if name := x[:i]; oracle && name != "" {
+				params = append(params, sql.Named(name, x[i+1:]))
+			} else {
+				params = append(params, x[i+1:])
+			}
+		}
+		if *flagStats && (page.Offset > 0 || page.Size > 0) {
+			countQry = countQuery(flag.Arg(0), where)
+		}
 	}
-	db, err := sql.Open("godror", *flagConnect)
+	db, err := openDB(*flagDriver, *flagConnect)
 	if err != nil {
 		return fmt.Errorf("%s: %w", *flagConnect, err)
 	}
 	defer db.Close()
-	db.SetMaxOpenConns(1)
-	db.SetMaxIdleConns(1)
+	maxConns := 1
+	if *flagDBLookup != "" {
+		// the lookups need a second connection, as most drivers cannot query while the rows are open
+		maxConns = 2
+	}
+	db.SetMaxOpenConns(maxConns)
+	db.SetMaxIdleConns(maxConns)
 	ctx, cancel := dbcsv.Wrap(context.Background())
 	defer cancel()
 
 	fh := os.Stdout
-	if !(*flagOut == "" || *flagOut == "-") {
+	// when splitting, -o is only the base of the file names
+	if !(*flagOut == "" || *flagOut == "-" || splitting) {
 		_ = os.MkdirAll(filepath.Dir(*flagOut), 0775)
 		if fh, err = os.Create(*flagOut); err != nil {
 			return fmt.Errorf("%s: %w", *flagOut, err)
@@ -166,26 +538,1221 @@ and dump all the columns of the cursor returned by the function.
 	if *flagCompress != "" {
 		switch (strings.TrimSpace(strings.ToLower(*flagCompress)) + "  ")[:2] {
 		case "gz":
-			wfh = gzip.NewWriter(fh)
+			level := gzip.DefaultCompression
+			if *flagCompressLevel != -1 {
+				if *flagCompressLevel < gzip.NoCompression || *flagCompressLevel > gzip.BestCompression {
+					return fmt.Errorf("-compress-level=%d: gzip level must be between %d and %d", *flagCompressLevel, gzip.NoCompression, gzip.BestCompression)
+				}
+				level = *flagCompressLevel
+			}
+			if wfh, err = gzip.NewWriterLevel(fh, level); err != nil {
+				return err
+			}
 		case "zs":
+			var opts []zstd.EOption
+			if *flagCompressLevel != -1 {
+				if *flagCompressLevel < 1 || *flagCompressLevel > 22 {
+					return fmt.Errorf("-compress-level=%d: zstd level must be between 1 and 22", *flagCompressLevel)
+				}
+				opts = append(opts, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(*flagCompressLevel)))
+			}
+			if wfh, err = zstd.NewWriter(fh, opts...); err != nil {
+				return err
+			}
+		}
+	}
+	if *flagPipe != "" {
+		var inner io.Writer = wfh
+		if wfh == fh {
+			// fh is closed at the end
+			inner = struct{ io.Writer }{fh}
+		}
+		var skip int
+		if *flagHeader && !*flagRaw && (*flagFormat == "" || *flagFormat == "csv" || *flagFormat == "tsv") {
+			skip = 1
+		}
+		shell, arg := "sh", "-c"
+		if runtime.GOOS == "windows" {
+			shell, arg = "cmd", "/C"
+		}
+		// not ctx: that is canceled before the output is closed
+		if wfh, err = dbcsv.NewPipeWriter(context.Background(), inner, skip, shell, arg, *flagPipe); err != nil {
+			return fmt.Errorf("-pipe %q: %w", *flagPipe, err)
+		}
+	}
+	if *flagAsyncWrite {
+		var inner io.Writer = wfh
+		if wfh == fh {
+			// fh is closed at the end
+			inner = struct{ io.Writer }{fh}
+		}
+		wfh = dbcsv.NewAsyncWriter(inner, *flagAsyncQueueDepth)
+	}
+
+	var tx *sql.Tx
+	var lookupConn *sql.Conn
+	var wrappers []rowsWrapper
+	var reports []func()
+	if *flagMaxErrors < 0 {
+		return fmt.Errorf("-max-errors=%d: must not be negative", *flagMaxErrors)
+	}
+	budget := &dbcsv.ErrorBudget{Max: *flagMaxErrors}
+	if *flagDetectTypes {
+		opts := dbcsv.InferOptions{Rows: *flagInferRows, AutoDate: *flagAutoDateDetect, TimestampFormat: *flagTimestampFormat}
+		if opts.Rows < 1 {
+			opts.Rows = 1
+		}
+		if *flagVerbose {
+			opts.Log = Log
+		}
+		wrappers = append(wrappers, func(rows dbcsv.Rows, columns []dbcsv.Column) (dbcsv.Rows, []dbcsv.Column, error) {
+			return dbcsv.InferTypes(rows, columns, opts)
+		})
+	}
+	if len(flagColType.Strings) != 0 {
+		wrappers = append(wrappers, func(rows dbcsv.Rows, columns []dbcsv.Column) (dbcsv.Rows, []dbcsv.Column, error) {
+			for _, spec := range flagColType.Strings {
+				for _, pair := range strings.Split(spec, ",") {
+					i := strings.LastIndexByte(pair, ':')
+					if i < 0 {
+						return nil, nil, fmt.Errorf("col-type %q: wanted COL:TYPE", pair)
+					}
+					idx, err := columnIndex(columns, pair[:i])
+					if err != nil {
+						return nil, nil, err
+					}
+					if err = columns[idx].SetType(pair[i+1:]); err != nil {
+						return nil, nil, err
+					}
+				}
+			}
+			return rows, columns, nil
+		})
+	}
+	if *flagBloomDedupe {
+		wrappers = append(wrappers, func(rows dbcsv.Rows, columns []dbcsv.Column) (dbcsv.Rows, []dbcsv.Column, error) {
+			rows, err := dbcsv.NewBloomDedupeRows(rows, columns, *flagBloomSize, *flagBloomFP)
+			return rows, columns, err
+		})
+	}
+	if len(flagRangeValidate.Strings) != 0 {
+		var errLog io.Writer
+		if *flagRangeValidateLog != "" {
+			lfh, err := os.Create(*flagRangeValidateLog)
+			if err != nil {
+				return err
+			}
+			defer lfh.Close()
+			errLog = lfh
+		}
+		// the total of the queries (sheets)
+		var vrs []*dbcsv.ValidatingRows
+		reports = append(reports, func() {
+			var n int
+			for _, vr := range vrs {
+				n += vr.Violations
+			}
+			fmt.Fprintf(os.Stderr, "%d range violations\n", n)
+		})
+		wrappers = append(wrappers, func(rows dbcsv.Rows, columns []dbcsv.Column) (dbcsv.Rows, []dbcsv.Column, error) {
+			vr := &dbcsv.ValidatingRows{Rows: rows, ErrorLog: errLog, WarnOnly: *flagRangeValidateWarnOnly, Budget: budget}
+			for _, spec := range flagRangeValidate.Strings {
+				col, min, max, err := splitRangeSpec(spec)
+				if err != nil {
+					return nil, nil, err
+				}
+				i, err := columnIndex(columns, col)
+				if err != nil {
+					return nil, nil, err
+				}
+				rv, err := dbcsv.NewRangeValidator(columns[i], i, min, max, dumpOpts)
+				if err != nil {
+					return nil, nil, err
+				}
+				vr.Validators = append(vr.Validators, rv)
+			}
+			vrs = append(vrs, vr)
+			reports = append(reports, func() { _ = Log("msg", "range validation finished", "violations", vr.Violations) })
+			return vr, columns, nil
+		})
+	}
+	if len(flagUniqueValidate.Strings) != 0 {
+		wrappers = append(wrappers, func(rows dbcsv.Rows, columns []dbcsv.Column) (dbcsv.Rows, []dbcsv.Column, error) {
+			vr := &dbcsv.ValidatingRows{Rows: rows, WarnOnly: *flagUniqueValidateReportAll, Budget: budget}
+			var buf bytes.Buffer
+			if *flagUniqueValidateReportAll {
+				vr.ErrorLog = &buf
+			}
+			for _, name := range flagUniqueValidate.Strings {
+				i, err := columnIndex(columns, name)
+				if err != nil {
+					return nil, nil, err
+				}
+				vr.Validators = append(vr.Validators, &dbcsv.UniqueValidator{Name: columns[i].Name, Index: i, MaxValues: *flagUniqueValidateMaxValues})
+			}
+			reports = append(reports, func() {
+				if buf.Len() != 0 {
+					log.Printf("Duplicates:\n%s", buf.String())
+				}
+				_ = Log("msg", "unique validation finished", "violations", vr.Violations)
+			})
+			return vr, columns, nil
+		})
+	}
+	if len(flagRegexValidate.Strings) != 0 {
+		wrappers = append(wrappers, func(rows dbcsv.Rows, columns []dbcsv.Column) (dbcsv.Rows, []dbcsv.Column, error) {
+			vr := &dbcsv.ValidatingRows{Rows: rows, WarnOnly: *flagRegexValidateWarnOnly, Budget: budget}
+			for _, spec := range flagRegexValidate.Strings {
+				i := strings.IndexByte(spec, ':')
+				if i < 0 {
+					return nil, nil, fmt.Errorf("regex-validate %q: wanted COL:PATTERN", spec)
+				}
+				j, err := columnIndex(columns, spec[:i])
+				if err != nil {
+					return nil, nil, err
+				}
+				rv, err := dbcsv.NewRegexValidator(columns[j], j, spec[i+1:])
+				if err != nil {
+					return nil, nil, err
+				}
+				vr.Validators = append(vr.Validators, rv)
+			}
+			reports = append(reports, func() { _ = Log("msg", "regex validation finished", "violations", vr.Violations) })
+			return vr, columns, nil
+		})
+	}
+	if len(flagRowValidate.Strings) != 0 {
+		var errLog io.Writer
+		if *flagValidateErrorOutput != "" {
+			lfh, err := os.Create(*flagValidateErrorOutput)
+			if err != nil {
+				return err
+			}
+			defer lfh.Close()
+			errLog = lfh
+		}
+		wrappers = append(wrappers, func(rows dbcsv.Rows, columns []dbcsv.Column) (dbcsv.Rows, []dbcsv.Column, error) {
+			vr := &dbcsv.ValidatingRows{Rows: rows, ErrorLog: errLog, WarnOnly: *flagRowValidateWarnOnly, Budget: budget}
+			for _, src := range flagRowValidate.Strings {
+				ev, err := dbcsv.NewExprValidator(columns, src)
+				if err != nil {
+					return nil, nil, err
+				}
+				vr.Validators = append(vr.Validators, ev)
+			}
+			reports = append(reports, func() { _ = Log("msg", "row validation finished", "violations", vr.Violations) })
+			return vr, columns, nil
+		})
+	}
+
+	if len(flagIPNormalize.Strings) != 0 {
+		version := *flagIPNormalizeVersion
+		wrappers = append(wrappers, func(rows dbcsv.Rows, columns []dbcsv.Column) (dbcsv.Rows, []dbcsv.Column, error) {
+			for _, name := range flagIPNormalize.Strings {
+				name := name
+				i, err := columnIndex(columns, name)
+				if err != nil {
+					return nil, nil, err
+				}
+				columns[i].Wrappers = append(columns[i].Wrappers, dbcsv.NewMapWrapper(func(s string) string {
+					t, err := dbcsv.NormalizeIP(s, version)
+					if err != nil {
+						log.Printf("[WARN] %s: %+v", name, err)
+					}
+					return t
+				}))
+			}
+			return rows, columns, nil
+		})
+	}
+	if len(flagCountryNormalize.Strings) != 0 {
+		format := *flagCountryNormalizeFormat
+		if _, err := (dbcsv.Country{}).Code(format); err != nil {
+			return err
+		}
+		wrappers = append(wrappers, func(rows dbcsv.Rows, columns []dbcsv.Column) (dbcsv.Rows, []dbcsv.Column, error) {
+			for _, name := range flagCountryNormalize.Strings {
+				name := name
+				i, err := columnIndex(columns, name)
+				if err != nil {
+					return nil, nil, err
+				}
+				columns[i].Wrappers = append(columns[i].Wrappers, dbcsv.NewMapWrapper(func(s string) string {
+					c, ok := dbcsv.LookupCountry(s)
+					if !ok {
+						log.Printf("[WARN] %s: unknown country %q", name, s)
+						return ""
+					}
+					code, _ := c.Code(format)
+					return code
+				}))
+			}
+			return rows, columns, nil
+		})
+	}
+
+	if *flagFilterSQL != "" {
+		where := *flagFilterSQL
+		wrappers = append(wrappers, func(rows dbcsv.Rows, columns []dbcsv.Column) (dbcsv.Rows, []dbcsv.Column, error) {
+			return dbcsv.FilterSQL(ctx, rows, columns, where)
+		})
+	}
+	if len(flagColTmpl.Strings) != 0 {
+		templates := make(map[string]*template.Template, len(flagColTmpl.Strings))
+		for _, spec := range flagColTmpl.Strings {
+			i := strings.IndexByte(spec, ':')
+			if i < 0 {
+				return fmt.Errorf("col-tmpl %q: wanted COL:TEMPLATE", spec)
+			}
+			tmpl, err := template.New(spec[:i]).Parse(spec[i+1:])
+			if err != nil {
+				return fmt.Errorf("col-tmpl %q: %w", spec, err)
+			}
+			templates[spec[:i]] = tmpl
+		}
+		wrappers = append(wrappers, func(rows dbcsv.Rows, columns []dbcsv.Column) (dbcsv.Rows, []dbcsv.Column, error) {
+			rm := dbcsv.NewRowMapRows(rows, columns)
+			for name, tmpl := range templates {
+				i, err := columnIndex(columns, name)
+				if err != nil {
+					return nil, nil, err
+				}
+				columns[i].Wrappers = append(columns[i].Wrappers, dbcsv.NewTemplateWrapper(tmpl, rm.Row))
+			}
+			return rm, columns, nil
+		})
+	}
+	if len(flagBaseConvert.Strings) != 0 {
+		wrappers = append(wrappers, func(rows dbcsv.Rows, columns []dbcsv.Column) (dbcsv.Rows, []dbcsv.Column, error) {
+			for _, spec := range flagBaseConvert.Strings {
+				parts := strings.Split(spec, ":")
+				if len(parts) != 3 {
+					return nil, nil, fmt.Errorf("base-convert %q: wanted COL:FROM_BASE:TO_BASE", spec)
+				}
+				from, err := strconv.Atoi(parts[1])
+				if err != nil {
+					return nil, nil, fmt.Errorf("base-convert %q: %w", spec, err)
+				}
+				to, err := strconv.Atoi(parts[2])
+				if err != nil {
+					return nil, nil, fmt.Errorf("base-convert %q: %w", spec, err)
+				}
+				if _, err = dbcsv.ConvertBase("0", 10, to, false); err != nil {
+					return nil, nil, fmt.Errorf("base-convert %q: %w", spec, err)
+				}
+				i, err := columnIndex(columns, parts[0])
+				if err != nil {
+					return nil, nil, err
+				}
+				columns[i].Wrappers = append(columns[i].Wrappers, dbcsv.NewBaseConvertWrapper(from, to, *flagBaseConvertPrefix, columns[i].Name))
+			}
+			return rows, columns, nil
+		})
+	}
+	if len(flagSplit.Strings) != 0 {
+		wrappers = append(wrappers, func(rows dbcsv.Rows, columns []dbcsv.Column) (dbcsv.Rows, []dbcsv.Column, error) {
+			for _, spec := range flagSplit.Strings {
+				i, j := strings.IndexByte(spec, ':'), strings.LastIndexByte(spec, ':')
+				if i < 0 || i == j {
+					return nil, nil, fmt.Errorf("split %q: wanted COL:DELIMITER:N1,N2,...", spec)
+				}
+				idx, err := columnIndex(columns, spec[:i])
+				if err != nil {
+					return nil, nil, err
+				}
+				rows, columns = dbcsv.NewSplitRows(rows, columns, idx, spec[i+1:j], strings.Split(spec[j+1:], ","))
+				if !*flagSplitKeepSource {
+					rows, columns = dbcsv.DropColumns(rows, columns, idx)
+				}
+			}
+			return rows, columns, nil
+		})
+	}
+	if len(flagJoin.Strings) != 0 {
+		wrappers = append(wrappers, func(rows dbcsv.Rows, columns []dbcsv.Column) (dbcsv.Rows, []dbcsv.Column, error) {
+			for _, spec := range flagJoin.Strings {
+				i, j := strings.IndexByte(spec, ':'), strings.LastIndexByte(spec, ':')
+				if i < 0 || i == j {
+					return nil, nil, fmt.Errorf("join %q: wanted COL1,COL2,...:DELIMITER:NEW_NAME", spec)
+				}
+				var indexes []int
+				for _, name := range strings.Split(spec[:i], ",") {
+					idx, err := columnIndex(columns, name)
+					if err != nil {
+						return nil, nil, err
+					}
+					indexes = append(indexes, idx)
+				}
+				rows, columns = dbcsv.AppendColumns(rows, columns, dbcsv.NewJoinColumn(spec[j+1:], indexes, spec[i+1:j]))
+				if *flagJoinDropSources {
+					rows, columns = dbcsv.DropColumns(rows, columns, indexes...)
+				}
+			}
+			return rows, columns, nil
+		})
+	}
+	if len(flagFingerprint.Strings) != 0 || *flagFingerprintAll {
+		wrappers = append(wrappers, func(rows dbcsv.Rows, columns []dbcsv.Column) (dbcsv.Rows, []dbcsv.Column, error) {
+			var computed []dbcsv.ComputedColumn
+			for _, spec := range flagFingerprint.Strings {
+				i := strings.LastIndexByte(spec, ':')
+				if i <= 0 || i == len(spec)-1 {
+					return nil, nil, fmt.Errorf("fingerprint %q: wanted COL1,COL2,...:NEW_NAME", spec)
+				}
+				var indexes []int
+				for _, name := range strings.Split(spec[:i], ",") {
+					idx, err := columnIndex(columns, name)
+					if err != nil {
+						return nil, nil, err
+					}
+					indexes = append(indexes, idx)
+				}
+				computed = append(computed, dbcsv.NewFingerprintColumn(spec[i+1:], indexes))
+			}
+			if *flagFingerprintAll {
+				indexes := make([]int, len(columns))
+				for i := range indexes {
+					indexes[i] = i
+				}
+				computed = append(computed, dbcsv.NewFingerprintColumn("_ROW_HASH", indexes))
+			}
+			rows, columns = dbcsv.AppendColumns(rows, columns, computed...)
+			return rows, columns, nil
+		})
+	}
+	if len(flagNumberWords.Strings) != 0 {
+		locale := *flagNumberWordsLocale
+		if _, err := dbcsv.NumberWords(0, locale); err != nil {
+			return err
+		}
+		wrappers = append(wrappers, func(rows dbcsv.Rows, columns []dbcsv.Column) (dbcsv.Rows, []dbcsv.Column, error) {
+			for _, name := range flagNumberWords.Strings {
+				i, err := columnIndex(columns, name)
+				if err != nil {
+					return nil, nil, err
+				}
+				columns[i].Wrappers = append(columns[i].Wrappers, dbcsv.NewNumberWordsWrapper(locale, columns[i].Name))
+			}
+			return rows, columns, nil
+		})
+	}
+	if len(flagOrdinal.Strings) != 0 {
+		wrappers = append(wrappers, func(rows dbcsv.Rows, columns []dbcsv.Column) (dbcsv.Rows, []dbcsv.Column, error) {
+			for _, name := range flagOrdinal.Strings {
+				i, err := columnIndex(columns, name)
+				if err != nil {
+					return nil, nil, err
+				}
+				columns[i].Wrappers = append(columns[i].Wrappers, dbcsv.NewOrdinalWrapper(columns[i].Name))
+			}
+			return rows, columns, nil
+		})
+	}
+	if len(flagQR.Strings) != 0 {
+		wrappers = append(wrappers, func(rows dbcsv.Rows, columns []dbcsv.Column) (dbcsv.Rows, []dbcsv.Column, error) {
+			for _, name := range flagQR.Strings {
+				i, err := columnIndex(columns, name)
+				if err != nil {
+					return nil, nil, err
+				}
+				columns[i].Wrappers = append(columns[i].Wrappers, dbcsv.NewQRWrapper(columns[i].Name))
+			}
+			return rows, columns, nil
+		})
+	}
+	if len(flagSIFormat.Strings) != 0 {
+		wrappers = append(wrappers, func(rows dbcsv.Rows, columns []dbcsv.Column) (dbcsv.Rows, []dbcsv.Column, error) {
+			for _, spec := range flagSIFormat.Strings {
+				name, mode := spec, ""
+				if i := strings.LastIndexByte(spec, ':'); i >= 0 {
+					name, mode = spec[:i], spec[i+1:]
+				}
+				if mode != "" && mode != "binary" {
+					return nil, nil, fmt.Errorf("si-format %q: wanted COL[:binary]", spec)
+				}
+				i, err := columnIndex(columns, name)
+				if err != nil {
+					return nil, nil, err
+				}
+				columns[i].Wrappers = append(columns[i].Wrappers, dbcsv.NewSIFormatWrapper(mode == "binary", *flagSIPrecision))
+			}
+			return rows, columns, nil
+		})
+	}
+	if len(flagBoolLabels.Strings) != 0 {
+		wrappers = append(wrappers, func(rows dbcsv.Rows, columns []dbcsv.Column) (dbcsv.Rows, []dbcsv.Column, error) {
+			for _, spec := range flagBoolLabels.Strings {
+				parts := strings.Split(spec, ":")
+				if len(parts) != 3 {
+					return nil, nil, fmt.Errorf("bool-labels %q: wanted COL:TRUE_LABEL:FALSE_LABEL", spec)
+				}
+				i, err := columnIndex(columns, parts[0])
+				if err != nil {
+					return nil, nil, err
+				}
+				columns[i].Wrappers = append(columns[i].Wrappers, dbcsv.NewBoolLabelsWrapper(parts[1], parts[2]))
+			}
+			return rows, columns, nil
+		})
+	}
+	if len(flagScientific.Strings) != 0 || len(flagScientificUpper.Strings) != 0 {
+		threshold := *flagScientificThreshold
+		wrappers = append(wrappers, func(rows dbcsv.Rows, columns []dbcsv.Column) (dbcsv.Rows, []dbcsv.Column, error) {
+			for _, specs := range []struct {
+				Name    string
+				Strings []string
+				Upper   bool
+			}{{"scientific", flagScientific.Strings, false}, {"scientific-upper", flagScientificUpper.Strings, true}} {
+				for _, spec := range specs.Strings {
+					name, prec := spec, -1
+					if i := strings.LastIndexByte(spec, ':'); i >= 0 {
+						var err error
+						if prec, err = strconv.Atoi(spec[i+1:]); err != nil || prec < 0 {
+							return nil, nil, fmt.Errorf("%s %q: wanted COL[:PREC]", specs.Name, spec)
+						}
+						name = spec[:i]
+					}
+					i, err := columnIndex(columns, name)
+					if err != nil {
+						return nil, nil, err
+					}
+					columns[i].Wrappers = append(columns[i].Wrappers, dbcsv.NewScientificWrapper(prec, specs.Upper, threshold))
+				}
+			}
+			return rows, columns, nil
+		})
+	}
+	if len(flagPct.Strings) != 0 || len(flagPctScaled.Strings) != 0 {
+		wrappers = append(wrappers, func(rows dbcsv.Rows, columns []dbcsv.Column) (dbcsv.Rows, []dbcsv.Column, error) {
+			for _, specs := range []struct {
+				Name    string
+				Strings []string
+				Scaled  bool
+			}{{"pct", flagPct.Strings, false}, {"pct-already-scaled", flagPctScaled.Strings, true}} {
+				for _, spec := range specs.Strings {
+					name, places := spec, 2
+					if i := strings.LastIndexByte(spec, ':'); i >= 0 {
+						var err error
+						if places, err = strconv.Atoi(spec[i+1:]); err != nil || places < 0 {
+							return nil, nil, fmt.Errorf("%s %q: wanted COL[:PLACES]", specs.Name, spec)
+						}
+						name = spec[:i]
+					}
+					i, err := columnIndex(columns, name)
+					if err != nil {
+						return nil, nil, err
+					}
+					columns[i].Wrappers = append(columns[i].Wrappers, dbcsv.NewPercentWrapper(places, specs.Scaled))
+				}
+			}
+			return rows, columns, nil
+		})
+	}
+	if len(flagFileSize.Strings) != 0 {
+		wrappers = append(wrappers, func(rows dbcsv.Rows, columns []dbcsv.Column) (dbcsv.Rows, []dbcsv.Column, error) {
+			for _, spec := range flagFileSize.Strings {
+				name, mode := spec, ""
+				if i := strings.LastIndexByte(spec, ':'); i >= 0 {
+					name, mode = spec[:i], spec[i+1:]
+				}
+				if mode != "" && mode != "binary" {
+					return nil, nil, fmt.Errorf("filesize %q: wanted COL[:binary]", spec)
+				}
+				i, err := columnIndex(columns, name)
+				if err != nil {
+					return nil, nil, err
+				}
+				columns[i].Wrappers = append(columns[i].Wrappers, dbcsv.NewFileSizeWrapper(mode == "binary"))
+			}
+			return rows, columns, nil
+		})
+	}
+	if len(flagDuration.Strings) != 0 {
+		wrappers = append(wrappers, func(rows dbcsv.Rows, columns []dbcsv.Column) (dbcsv.Rows, []dbcsv.Column, error) {
+			for _, spec := range flagDuration.Strings {
+				i := strings.LastIndexByte(spec, ':')
+				if i < 0 {
+					return nil, nil, fmt.Errorf("duration %q: wanted COL:UNIT", spec)
+				}
+				unit, ok := dbcsv.DurationUnits[spec[i+1:]]
+				if !ok {
+					return nil, nil, fmt.Errorf("duration %q: unknown unit %q (ns, us, ms, s, min or h)", spec, spec[i+1:])
+				}
+				idx, err := columnIndex(columns, spec[:i])
+				if err != nil {
+					return nil, nil, err
+				}
+				columns[idx].Wrappers = append(columns[idx].Wrappers, dbcsv.NewDurationWrapper(unit, *flagDurationPrecision))
+			}
+			return rows, columns, nil
+		})
+	}
+	if len(flagImpute.Strings) != 0 {
+		for _, spec := range flagImpute.Strings {
+			i := strings.IndexByte(spec, ':')
+			if i < 0 {
+				return fmt.Errorf("impute %q: wanted COL:STRATEGY", spec)
+			}
+			if dbcsv.ImputeNeedsStats(spec[i+1:]) && !*flagImputeTwoPass {
+				return fmt.Errorf("impute %q: %s needs -impute-two-pass", spec, spec[i+1:])
+			}
+		}
+		wrappers = append(wrappers, func(rows dbcsv.Rows, columns []dbcsv.Column) (dbcsv.Rows, []dbcsv.Column, error) {
+			strategies := make(map[int]string, len(flagImpute.Strings))
+			values := make(map[int]string, len(flagImpute.Strings))
+			for _, spec := range flagImpute.Strings {
+				i := strings.IndexByte(spec, ':')
+				j, err := columnIndex(columns, spec[:i])
+				if err != nil {
+					return nil, nil, err
+				}
+				if strategy := spec[i+1:]; dbcsv.ImputeNeedsStats(strategy) {
+					strategies[j] = strategy
+				} else if values[j], err = dbcsv.ImputeValue(strategy); err != nil {
+					return nil, nil, fmt.Errorf("impute %q: %w", spec, err)
+				}
+			}
+			if len(strategies) != 0 {
+				var stats map[int]string
+				var err error
+				if rows, stats, err = dbcsv.ImputeStats(rows, columns, strategies); err != nil {
+					return nil, nil, err
+				}
+				for j, v := range stats {
+					values[j] = v
+				}
+			}
+			for j, v := range values {
+				columns[j].Wrappers = append(columns[j].Wrappers, dbcsv.NewImputeWrapper(v))
+			}
+			return rows, columns, nil
+		})
+	}
+	if len(flagStandardize.Strings) != 0 {
+		// the loaded standardizations are used for each -sheet, otherwise each is computed on its own,
+		// and the file gets all of them
+		var loaded, computed dbcsv.Standardizations
+		if *flagStandardizeFile != "" {
+			fh, err := os.Open(*flagStandardizeFile)
+			if err == nil {
+				loaded, err = dbcsv.ReadStandardizations(fh)
+				fh.Close()
+				if err != nil {
+					return fmt.Errorf("%s: %w", *flagStandardizeFile, err)
+				}
+			} else if !os.IsNotExist(err) {
+				return err
+			}
+		}
+		wrappers = append(wrappers, func(rows dbcsv.Rows, columns []dbcsv.Column) (dbcsv.Rows, []dbcsv.Column, error) {
+			names := make([]string, len(flagStandardize.Strings))
+			for k, name := range flagStandardize.Strings {
+				i, err := columnIndex(columns, name)
+				if err != nil {
+					return nil, nil, err
+				}
+				names[k] = columns[i].Name
+			}
+			ss := loaded
+			if ss == nil {
+				var err error
+				if rows, ss, err = dbcsv.ComputeStandardizations(rows, columns, names...); err != nil {
+					return nil, nil, err
+				}
+				if *flagStandardizeFile != "" {
+					if computed == nil {
+						computed = make(dbcsv.Standardizations, len(ss))
+					}
+					for name, s := range ss {
+						computed[name] = s
+					}
+					fh, err := os.Create(*flagStandardizeFile)
+					if err != nil {
+						return nil, nil, err
+					}
+					if _, err = computed.WriteTo(fh); err != nil {
+						fh.Close()
+						return nil, nil, fmt.Errorf("%s: %w", *flagStandardizeFile, err)
+					}
+					if err = fh.Close(); err != nil {
+						return nil, nil, err
+					}
+				}
+			}
+			sub := make(dbcsv.Standardizations, len(names))
+			for _, name := range names {
+				s, ok := ss[name]
+				if !ok {
+					return nil, nil, fmt.Errorf("%s: no standardization for %s", *flagStandardizeFile, name)
+				}
+				sub[name] = s
+			}
+			return dbcsv.Standardize(rows, columns, sub)
+		})
+	}
+	if len(flagMinMax.Strings) != 0 {
+		specs := make([]string, len(flagMinMax.Strings))
+		outs := make([]dbcsv.MinMax, len(flagMinMax.Strings))
+		for k, spec := range flagMinMax.Strings {
+			parts := strings.Split(spec, ":")
+			specs[k], outs[k] = parts[0], dbcsv.MinMax{Min: 0, Max: 1}
+			if len(parts) == 1 {
+				continue
+			}
+			if len(parts) != 3 {
+				return fmt.Errorf("minmax %q: wanted COL[:MIN_OUT:MAX_OUT]", spec)
+			}
 			var err error
-			if wfh, err = zstd.NewWriter(fh); err != nil {
+			if outs[k].Min, err = strconv.ParseFloat(parts[1], 64); err != nil {
+				return fmt.Errorf("minmax %q: %w", spec, err)
+			}
+			if outs[k].Max, err = strconv.ParseFloat(parts[2], 64); err != nil {
+				return fmt.Errorf("minmax %q: %w", spec, err)
+			}
+		}
+		// as with -standardize-file
+		var loaded, computed dbcsv.MinMaxes
+		if *flagMinMaxParamsFile != "" {
+			fh, err := os.Open(*flagMinMaxParamsFile)
+			if err == nil {
+				loaded, err = dbcsv.ReadMinMaxes(fh)
+				fh.Close()
+				if err != nil {
+					return fmt.Errorf("%s: %w", *flagMinMaxParamsFile, err)
+				}
+			} else if !os.IsNotExist(err) {
 				return err
 			}
 		}
+		wrappers = append(wrappers, func(rows dbcsv.Rows, columns []dbcsv.Column) (dbcsv.Rows, []dbcsv.Column, error) {
+			names := make([]string, len(specs))
+			out := make(dbcsv.MinMaxes, len(specs))
+			for k, name := range specs {
+				i, err := columnIndex(columns, name)
+				if err != nil {
+					return nil, nil, err
+				}
+				names[k] = columns[i].Name
+				out[names[k]] = outs[k]
+			}
+			mm := loaded
+			if mm == nil {
+				var err error
+				if rows, mm, err = dbcsv.ComputeMinMaxes(rows, columns, names...); err != nil {
+					return nil, nil, err
+				}
+				if *flagMinMaxParamsFile != "" {
+					if computed == nil {
+						computed = make(dbcsv.MinMaxes, len(mm))
+					}
+					for name, m := range mm {
+						computed[name] = m
+					}
+					fh, err := os.Create(*flagMinMaxParamsFile)
+					if err != nil {
+						return nil, nil, err
+					}
+					if _, err = computed.WriteTo(fh); err != nil {
+						fh.Close()
+						return nil, nil, fmt.Errorf("%s: %w", *flagMinMaxParamsFile, err)
+					}
+					if err = fh.Close(); err != nil {
+						return nil, nil, err
+					}
+				}
+			}
+			sub := make(dbcsv.MinMaxes, len(names))
+			for _, name := range names {
+				m, ok := mm[name]
+				if !ok {
+					return nil, nil, fmt.Errorf("%s: no min and max for %s", *flagMinMaxParamsFile, name)
+				}
+				sub[name] = m
+			}
+			return dbcsv.MinMaxScale(rows, columns, sub, out)
+		})
+	}
+	if len(flagFFill.Strings) != 0 {
+		wrappers = append(wrappers, func(rows dbcsv.Rows, columns []dbcsv.Column) (dbcsv.Rows, []dbcsv.Column, error) {
+			for _, name := range flagFFill.Strings {
+				i, err := columnIndex(columns, name)
+				if err != nil {
+					return nil, nil, err
+				}
+				columns[i].Wrappers = append(columns[i].Wrappers, dbcsv.NewForwardFillWrapper())
+			}
+			return rows, columns, nil
+		})
+	}
+	if len(flagBFill.Strings) != 0 {
+		wrappers = append(wrappers, func(rows dbcsv.Rows, columns []dbcsv.Column) (dbcsv.Rows, []dbcsv.Column, error) {
+			rows, err := dbcsv.BackFill(rows, columns, flagBFill.Strings...)
+			return rows, columns, err
+		})
+	}
+	if len(flagCoalesce.Strings) != 0 {
+		wrappers = append(wrappers, func(rows dbcsv.Rows, columns []dbcsv.Column) (dbcsv.Rows, []dbcsv.Column, error) {
+			var computed []dbcsv.ComputedColumn
+			for _, spec := range flagCoalesce.Strings {
+				i := strings.IndexByte(spec, ':')
+				if i <= 0 {
+					return nil, nil, fmt.Errorf("coalesce %q: wanted ALIAS:COL1,COL2,...", spec)
+				}
+				var indexes []int
+				for _, name := range strings.Split(spec[i+1:], ",") {
+					idx, err := columnIndex(columns, name)
+					if err != nil {
+						return nil, nil, err
+					}
+					indexes = append(indexes, idx)
+				}
+				computed = append(computed, dbcsv.NewCoalesceColumn(spec[:i], columns, indexes))
+			}
+			rows, columns = dbcsv.AppendColumns(rows, columns, computed...)
+			return rows, columns, nil
+		})
+	}
+	if len(flagCaseWhen.Strings) != 0 {
+		type caseWhen struct {
+			col, def string
+			cases    map[string]string
+		}
+		cws := make([]caseWhen, 0, len(flagCaseWhen.Strings))
+		for _, spec := range flagCaseWhen.Strings {
+			i, j := strings.IndexByte(spec, ':'), strings.LastIndexByte(spec, ':')
+			if i < 0 || i == j {
+				return fmt.Errorf("case-when %q: wanted COL:VALUE1→OUT1,VALUE2→OUT2,...:DEFAULT", spec)
+			}
+			cw := caseWhen{col: spec[:i], def: spec[j+1:], cases: make(map[string]string)}
+			for _, pair := range strings.Split(strings.ReplaceAll(spec[i+1:j], "->", "→"), ",") {
+				k := strings.Index(pair, "→")
+				if k < 0 {
+					return fmt.Errorf("case-when %q: wanted VALUE→OUT, got %q", spec, pair)
+				}
+				cw.cases[pair[:k]] = pair[k+len("→"):]
+			}
+			cws = append(cws, cw)
+		}
+		wrappers = append(wrappers, func(rows dbcsv.Rows, columns []dbcsv.Column) (dbcsv.Rows, []dbcsv.Column, error) {
+			for _, cw := range cws {
+				i, err := columnIndex(columns, cw.col)
+				if err != nil {
+					return nil, nil, err
+				}
+				columns[i].Wrappers = append(columns[i].Wrappers, dbcsv.NewCaseWhenWrapper(cw.cases, cw.def, *flagCaseWhenIgnoreCase))
+			}
+			return rows, columns, nil
+		})
+	}
+	if len(flagEmojiMap.Strings) != 0 {
+		type emojiMap struct {
+			col, def string
+			m        map[string]string
+		}
+		ems := make([]emojiMap, 0, len(flagEmojiMap.Strings))
+		for _, spec := range flagEmojiMap.Strings {
+			i, j := strings.IndexByte(spec, ':'), strings.LastIndexByte(spec, ':')
+			if i < 0 {
+				return fmt.Errorf("emoji-map %q: wanted COL:VALUE1→EMOJI1,VALUE2→EMOJI2,...[:DEFAULT]", spec)
+			}
+			em := emojiMap{col: spec[:i], m: make(map[string]string)}
+			if i == j {
+				j = len(spec)
+			} else {
+				em.def = spec[j+1:]
+			}
+			for _, pair := range strings.Split(strings.ReplaceAll(spec[i+1:j], "->", "→"), ",") {
+				k := strings.Index(pair, "→")
+				if k < 0 {
+					return fmt.Errorf("emoji-map %q: wanted VALUE→EMOJI, got %q", spec, pair)
+				}
+				em.m[pair[:k]] = pair[k+len("→"):]
+			}
+			ems = append(ems, em)
+		}
+		wrappers = append(wrappers, func(rows dbcsv.Rows, columns []dbcsv.Column) (dbcsv.Rows, []dbcsv.Column, error) {
+			for _, em := range ems {
+				i, err := columnIndex(columns, em.col)
+				if err != nil {
+					return nil, nil, err
+				}
+				columns[i].Wrappers = append(columns[i].Wrappers, dbcsv.NewEmojiMapWrapper(em.m, em.def))
+			}
+			return rows, columns, nil
+		})
+	}
+	if len(flagDateAge.Strings) != 0 {
+		now := time.Now()
+		var computed []func(columns []dbcsv.Column) (dbcsv.ComputedColumn, error)
+		for _, spec := range flagDateAge.Strings {
+			parts := strings.SplitN(spec, ":", 3)
+			if len(parts) < 2 {
+				return fmt.Errorf("date-age %q: wanted COL:UNIT[:REF_DATE]", spec)
+			}
+			ref := now
+			if len(parts) == 3 {
+				var err error
+				if ref, err = dbcsv.ParseInputDate(parts[2]); err != nil {
+					return fmt.Errorf("date-age %q: %w", spec, err)
+				}
+			}
+			computed = append(computed, func(columns []dbcsv.Column) (dbcsv.ComputedColumn, error) {
+				i, err := columnIndex(columns, parts[0])
+				if err != nil {
+					return dbcsv.ComputedColumn{}, err
+				}
+				return dbcsv.NewDateAge(columns[i], i, parts[1], ref)
+			})
+		}
+		wrappers = append(wrappers, func(rows dbcsv.Rows, columns []dbcsv.Column) (dbcsv.Rows, []dbcsv.Column, error) {
+			cols := make([]dbcsv.ComputedColumn, len(computed))
+			for k, f := range computed {
+				var err error
+				if cols[k], err = f(columns); err != nil {
+					return nil, nil, err
+				}
+			}
+			rows, columns = dbcsv.AppendColumns(rows, columns, cols...)
+			return rows, columns, nil
+		})
+	}
+	if len(flagHashPrefix.Strings) != 0 {
+		wrappers = append(wrappers, func(rows dbcsv.Rows, columns []dbcsv.Column) (dbcsv.Rows, []dbcsv.Column, error) {
+			var computed []dbcsv.ComputedColumn
+			for _, spec := range flagHashPrefix.Strings {
+				name, width := spec, 2
+				if i := strings.LastIndexByte(spec, ':'); i >= 0 {
+					var err error
+					if width, err = strconv.Atoi(spec[i+1:]); err != nil {
+						return nil, nil, fmt.Errorf("hash-prefix %q: %w", spec, err)
+					}
+					name = spec[:i]
+				}
+				j, err := columnIndex(columns, name)
+				if err != nil {
+					return nil, nil, err
+				}
+				c, err := dbcsv.NewHashPrefix(columns[j], j, width)
+				if err != nil {
+					return nil, nil, err
+				}
+				computed = append(computed, c)
+			}
+			rows, columns = dbcsv.AppendColumns(rows, columns, computed...)
+			return rows, columns, nil
+		})
+	}
+	if len(flagLookup.Strings) != 0 {
+		type lookup struct {
+			col, output string
+			m           map[string]string
+		}
+		comma, _ := utf8.DecodeRuneInString(*flagLookupSep)
+		ignoreCase := !*flagLookupCaseSensitive
+		lookups := make([]lookup, 0, len(flagLookup.Strings))
+		for _, spec := range flagLookup.Strings {
+			// the file name may contain :
+			parts := strings.Split(spec, ":")
+			if len(parts) < 5 {
+				return fmt.Errorf("lookup %q: wanted COL:LOOKUP_CSV:KEY_COL:VALUE_COL:OUTPUT_COL", spec)
+			}
+			n := len(parts)
+			fn := strings.Join(parts[1:n-3], ":")
+			fh, err := os.Open(fn)
+			if err != nil {
+				return fmt.Errorf("lookup %q: %w", spec, err)
+			}
+			m, err := dbcsv.ReadLookup(fh, comma, parts[n-3], parts[n-2], ignoreCase)
+			fh.Close()
+			if err != nil {
+				return fmt.Errorf("lookup %q: %s: %w", spec, fn, err)
+			}
+			lookups = append(lookups, lookup{col: parts[0], output: parts[n-1], m: m})
+		}
+		wrappers = append(wrappers, func(rows dbcsv.Rows, columns []dbcsv.Column) (dbcsv.Rows, []dbcsv.Column, error) {
+			computed := make([]dbcsv.ComputedColumn, 0, len(lookups))
+			for _, l := range lookups {
+				i, err := columnIndex(columns, l.col)
+				if err != nil {
+					return nil, nil, err
+				}
+				computed = append(computed, dbcsv.NewLookupColumn(l.output, i, l.m, ignoreCase))
+			}
+			rows, columns = dbcsv.AppendColumns(rows, columns, computed...)
+			return rows, columns, nil
+		})
+	}
+	if *flagDBLookup != "" {
+		i := strings.IndexByte(*flagDBLookup, ':')
+		if i <= 0 {
+			return fmt.Errorf("db-lookup %q: wanted COL:QUERY", *flagDBLookup)
+		}
+		col, qry := (*flagDBLookup)[:i], (*flagDBLookup)[i+1:]
+		wrappers = append(wrappers, func(rows dbcsv.Rows, columns []dbcsv.Column) (dbcsv.Rows, []dbcsv.Column, error) {
+			if lookupConn == nil {
+				return nil, nil, fmt.Errorf("-db-lookup needs a database connection")
+			}
+			j, err := columnIndex(columns, col)
+			if err != nil {
+				return nil, nil, err
+			}
+			name := *flagDBLookupOutputCol
+			if name == "" {
+				name = columns[j].Name + "_LOOKUP"
+			}
+			rows, columns = dbcsv.AppendColumns(rows, columns,
+				dbcsv.NewDBLookupColumn(ctx, lookupConn, name, j, qry, *flagDBLookupCacheSize))
+			return rows, columns, nil
+		})
+	}
+	if len(flagColEncoding.Strings) != 0 {
+		encs := make([]dbcsv.NamedEncoding, len(flagColEncoding.Strings))
+		for k, spec := range flagColEncoding.Strings {
+			i := strings.LastIndexByte(spec, ':')
+			if i < 0 {
+				return fmt.Errorf("col-encoding %q: wanted COL:ENCODING", spec)
+			}
+			var err error
+			if encs[k], err = dbcsv.EncFromName(spec[i+1:]); err != nil {
+				return fmt.Errorf("col-encoding %q: %w", spec, err)
+			}
+		}
+		wrappers = append(wrappers, func(rows dbcsv.Rows, columns []dbcsv.Column) (dbcsv.Rows, []dbcsv.Column, error) {
+			for k, spec := range flagColEncoding.Strings {
+				i, err := columnIndex(columns, spec[:strings.LastIndexByte(spec, ':')])
+				if err != nil {
+					return nil, nil, err
+				}
+				columns[i].Wrappers = append(columns[i].Wrappers, dbcsv.NewEncodingConvertWrapper(encs[k].Encoding, columns[i].Name))
+			}
+			return rows, columns, nil
+		})
+	}
+	if len(flagIconv.Strings) != 0 {
+		wrappers = append(wrappers, func(rows dbcsv.Rows, columns []dbcsv.Column) (dbcsv.Rows, []dbcsv.Column, error) {
+			ir := dbcsv.NewIconvRows(rows, columns)
+			for _, spec := range flagIconv.Strings {
+				parts := strings.Split(spec, ":")
+				if len(parts) != 3 {
+					return nil, nil, fmt.Errorf("iconv %q: wanted COL:FROM_CHARSET:TO_CHARSET", spec)
+				}
+				i, err := columnIndex(columns, parts[0])
+				if err != nil {
+					return nil, nil, err
+				}
+				if err = ir.Add(i, parts[1], parts[2]); err != nil {
+					return nil, nil, fmt.Errorf("iconv %q: %w", spec, err)
+				}
+			}
+			return ir, columns, nil
+		})
+	}
+	if len(flagWindowAvg.Strings) != 0 {
+		wrappers = append(wrappers, func(rows dbcsv.Rows, columns []dbcsv.Column) (dbcsv.Rows, []dbcsv.Column, error) {
+			var computed []dbcsv.ComputedColumn
+			for _, spec := range flagWindowAvg.Strings {
+				i := strings.LastIndexByte(spec, ':')
+				if i < 0 {
+					return nil, nil, fmt.Errorf("window-avg %q: wanted COL:N", spec)
+				}
+				n, err := strconv.Atoi(spec[i+1:])
+				if err != nil {
+					return nil, nil, fmt.Errorf("window-avg %q: %w", spec, err)
+				}
+				j, err := columnIndex(columns, spec[:i])
+				if err != nil {
+					return nil, nil, err
+				}
+				c, err := dbcsv.NewWindowAvg(columns[j], j, n)
+				if err != nil {
+					return nil, nil, err
+				}
+				computed = append(computed, c)
+			}
+			rows, columns = dbcsv.AppendColumns(rows, columns, computed...)
+			return rows, columns, nil
+		})
+	}
+	if *flagWindowExpr != "" {
+		if *flagWindowSize < 1 {
+			return fmt.Errorf("-window-expr needs a positive -window-size")
+		}
+		wrappers = append(wrappers, func(rows dbcsv.Rows, columns []dbcsv.Column) (dbcsv.Rows, []dbcsv.Column, error) {
+			return dbcsv.NewWindowRows(rows, columns, *flagWindowSize, *flagWindowName, *flagWindowExpr, *flagWindowPartial)
+		})
+	}
+	if len(flagRunningMin.Strings) != 0 || len(flagRunningMax.Strings) != 0 {
+		wrappers = append(wrappers, func(rows dbcsv.Rows, columns []dbcsv.Column) (dbcsv.Rows, []dbcsv.Column, error) {
+			var computed []dbcsv.ComputedColumn
+			for _, name := range flagRunningMin.Strings {
+				j, err := columnIndex(columns, name)
+				if err != nil {
+					return nil, nil, err
+				}
+				computed = append(computed, dbcsv.NewRunningMin(columns[j], j))
+			}
+			for _, name := range flagRunningMax.Strings {
+				j, err := columnIndex(columns, name)
+				if err != nil {
+					return nil, nil, err
+				}
+				computed = append(computed, dbcsv.NewRunningMax(columns[j], j))
+			}
+			rows, columns = dbcsv.AppendColumns(rows, columns, computed...)
+			return rows, columns, nil
+		})
+	}
+	// the last, as the column names are looked up by the other wrappers
+	if *flagExcludeColumns != "" {
+		wrappers = append(wrappers, func(rows dbcsv.Rows, columns []dbcsv.Column) (dbcsv.Rows, []dbcsv.Column, error) {
+			var indexes []int
+			for _, name := range strings.Split(*flagExcludeColumns, ",") {
+				idx, err := columnIndex(columns, strings.TrimSpace(name))
+				if err != nil {
+					return nil, nil, err
+				}
+				indexes = append(indexes, idx)
+			}
+			rows, columns = dbcsv.DropColumns(rows, columns, indexes...)
+			return rows, columns, nil
+		})
+	}
+	if *flagColumnMap != "" {
+		m, err := readColumnMap(*flagColumnMap)
+		if err != nil {
+			return err
+		}
+		wrappers = append(wrappers, func(rows dbcsv.Rows, columns []dbcsv.Column) (dbcsv.Rows, []dbcsv.Column, error) {
+			for i, c := range columns {
+				if nm, ok := m[strings.ToLower(c.Name)]; ok {
+					columns[i].Name = nm
+				}
+			}
+			return rows, columns, nil
+		})
+	}
+	if *flagHeaderCase != "" {
+		var transform func(string) string
+		switch strings.ToLower(*flagHeaderCase) {
+		case "upper":
+			transform = strings.ToUpper
+		case "lower":
+			transform = strings.ToLower
+		case "snake":
+			transform = snakeCase
+		default:
+			return fmt.Errorf("unknown -header-case %q (only upper, lower or snake)", *flagHeaderCase)
+		}
+		wrappers = append(wrappers, func(rows dbcsv.Rows, columns []dbcsv.Column) (dbcsv.Rows, []dbcsv.Column, error) {
+			for i := range columns {
+				columns[i].Name = transform(columns[i].Name)
+			}
+			return rows, columns, nil
+		})
+	}
+	// the per-column -date formats are looked up by the final column names
+	if len(dumpOpts.DateFormats) != 0 {
+		wrappers = append(wrappers, func(rows dbcsv.Rows, columns []dbcsv.Column) (dbcsv.Rows, []dbcsv.Column, error) {
+			if err := checkDateFormats(dumpOpts.DateFormats, columns); err != nil {
+				return nil, nil, fmt.Errorf("-date: %w", err)
+			}
+			return rows, columns, nil
+		})
+	}
+
+	var formatter dbcsv.RowFormatter
+	if *flagRowFormatLua != "" {
+		f, err := dbcsv.NewLuaFormatter(*flagRowFormatLua)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		formatter = f
+	} else if *flagRowFormatStarlark != "" {
+		if formatter, err = dbcsv.NewStarlarkFormatter(*flagRowFormatStarlark); err != nil {
+			return err
+		}
+	} else if *flagSparse && *flagFormat != "json" {
+		if !(*flagFormat == "" || *flagFormat == "csv") {
+			return fmt.Errorf("-sparse is only applicable to -format=csv or json")
+		}
+		formatter = dbcsv.SparseFormatter{Sep: *flagSep, Default: *flagSparseDefault}
 	}
 
 	if Log != nil {
 		_ = Log("msg", "writing", "file", fh.Name(), "encoding", enc)
 	}
-	tx, err := db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
-	if err != nil {
-		log.Printf("[WARN] Read-Only transaction: %v", err)
-		if tx, err = db.BeginTx(ctx, nil); err != nil {
-			return fmt.Errorf("%s: %w", "beginTx", err)
+	if inputFile == "" {
+		if tx, err = db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true}); err != nil {
+			log.Printf("[WARN] Read-Only transaction: %v", err)
+			if tx, err = db.BeginTx(ctx, nil); err != nil {
+				return fmt.Errorf("%s: %w", "beginTx", err)
+			}
+		}
+		defer tx.Rollback()
+		if *flagDBLookup != "" {
+			if lookupConn, err = db.Conn(ctx); err != nil {
+				return fmt.Errorf("db-lookup connection: %w", err)
+			}
+			defer lookupConn.Close()
 		}
+		if countQry != "" {
+			var n int64
+			if err = tx.QueryRowContext(ctx, countQry).Scan(&n); err != nil {
+				return fmt.Errorf("%s: %w", countQry, err)
+			}
+			fmt.Fprintf(os.Stderr, "total rows: %d\n", n)
+		}
+	}
+	if *flagWhereBuilder {
+		if tx == nil {
+			return fmt.Errorf("-where-builder needs a database query")
+		}
+		return buildWhere(ctx, tx, oracle, queries[0], os.Stdin, os.Stdout)
+	}
+	if *flagDryRunExplain {
+		if tx == nil || *flagCall {
+			return fmt.Errorf("-dry-run-explain needs a database query")
+		}
+		for _, qry := range queries {
+			if i := strings.IndexByte(qry, ':'); i >= 0 && len(flagSheets.Strings) != 0 {
+				qry = qry[i+1:]
+			}
+			if err = explainQuery(ctx, tx, oracle, qry, os.Stdout); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if *flagDryRun {
+		if tx == nil {
+			return fmt.Errorf("-dry-run needs a database query")
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 8, 1, ' ', 0)
+		for _, qry := range queries {
+			if i := strings.IndexByte(qry, ':'); i >= 0 && len(flagSheets.Strings) != 0 {
+				fmt.Fprintf(tw, "-- %s\n", qry[:i])
+				qry = qry[i+1:]
+			}
+			if !*flagCall {
+				qry = "SELECT * FROM (" + strings.TrimRight(strings.TrimSpace(qry), ";") + ") Q__ WHERE 1=0"
+			}
+			rows, columns, err := doQuery(ctx, tx, oracle, qry, params, *flagCall, false, paging{})
+			if err != nil {
+				return err
+			}
+			rows.Close()
+			fmt.Fprintln(tw, "NAME\tDATABASE TYPE\tGO TYPE\tNULLABLE")
+			for _, col := range columns {
+				var typ string
+				if col.Type != nil {
+					typ = col.Type.String()
+				}
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%t\n", col.Name, col.DatabaseTypeName, typ, !col.NotNull)
+			}
+		}
+		return tw.Flush()
 	}
-	defer tx.Rollback()
 
 	if len(flagSheets.Strings) == 0 {
 		w := encoding.ReplaceUnsupported(enc.NewEncoder()).Writer(wfh)
@@ -193,16 +1760,219 @@ and dump all the columns of the cursor returned by the function.
 			_ = Log("env_encoding", dbcsv.DefaultEncoding.Name)
 		}
 
-		rows, columns, qErr := doQuery(ctx, tx, queries[0], params, *flagCall, *flagSort)
+		var qRows dbcsv.Rows
+		var columns []dbcsv.Column
+		var qErr error
+		if inputFile != "" {
+			qRows, columns, qErr = loadInput(ctx, inputFile, *flagInputSheet)
+		} else {
+			qRows, columns, qErr = cachedQuery(ctx, queryCache, tx, oracle, queries[0], params, *flagCall, *flagSort, page, Log)
+		}
 		if qErr != nil {
 			err = qErr
 		} else {
-			defer rows.Close()
-			err = dbcsv.DumpCSV(ctx, w, rows, columns, *flagHeader, *flagSep, *flagRaw, Log)
+			defer qRows.Close()
+			var rows dbcsv.Rows
+			if rows, columns, err = wrapRows(qRows, columns, wrappers); err == nil && *flagProtoGenDescriptor != "" {
+				err = writeProtoDescriptor(*flagProtoGenDescriptor, *flagProtoMessage, columns)
+			}
+			if err == nil {
+				format := *flagFormat
+				if formatter != nil {
+					format = "row-format"
+				}
+				if *flagSchemaExport {
+					format = "schema-export"
+				}
+				var dumped int
+				switch format {
+				case "schema-export":
+					table := *flagSchemaExportTable
+					if table == "" {
+						table = argTable("exported")
+					}
+					var ddl string
+					if ddl, err = dbcsv.CreateTableDDL(table, columns, *flagSchemaExportDialect, flagPKCol.Strings); err == nil {
+						_, err = io.WriteString(w, ddl)
+					}
+				case "superset":
+					table := *flagSupersetDatasource
+					if table == "" {
+						table = argTable("dataset")
+					}
+					err = dbcsv.WriteSupersetDataset(w, columns, dbcsv.SupersetOptions{
+						Datasource: table, Schema: *flagSupersetSchema, DatabaseUUID: *flagSupersetDatabaseUUID,
+					})
+				case "row-format":
+					dumped, err = dbcsv.DumpFormatted(ctx, w, rows, columns, formatter, dumpOpts, Log)
+				case "syslog":
+					var conn net.Conn
+					if conn, err = dialSyslog(*flagSyslogAddr); err != nil {
+						return err
+					}
+					defer conn.Close()
+					dumped, err = dbcsv.DumpSyslog(ctx, conn, rows, columns, dbcsv.SyslogOptions{
+						AppName: *flagSyslogAppName, Facility: *flagSyslogFacility, Severity: *flagSyslogSeverity,
+						DumperOptions: dumpOpts,
+					}, Log)
+				case "bcp":
+					if *flagBCPFormatFile == "" {
+						return fmt.Errorf("-format=bcp needs -bcp-format-file")
+					}
+					var ff *os.File
+					if ff, err = os.Create(*flagBCPFormatFile); err != nil {
+						return err
+					}
+					defer ff.Close()
+					bw := w
+					if dbcsv.BCPMode(*flagBCPMode) == dbcsv.BCPNative {
+						bw = wfh
+					}
+					if dumped, err = dbcsv.DumpBCP(ctx, bw, ff, rows, columns, dbcsv.BCPMode(*flagBCPMode), dumpOpts, Log); err == nil {
+						err = ff.Close()
+					}
+				case "teradata-fastload":
+					opts := dbcsv.FastLoadOptions{Table: "target_table", DataFile: *flagOut, Fixed: *flagTeradataRecordMode == "fixed", DumperOptions: dumpOpts}
+					if !opts.Fixed && *flagTeradataRecordMode != "variable" {
+						return fmt.Errorf("unknown -teradata-record-mode %q", *flagTeradataRecordMode)
+					}
+					if tbl := strings.TrimSpace(flag.Arg(0)); tbl != "" && !strings.HasPrefix(strings.ToUpper(tbl), "SELECT ") {
+						opts.Table = tbl
+					}
+					ctlFile := *flagTeradataCtlFile
+					if ctlFile == "" {
+						if *flagOut == "" || *flagOut == "-" {
+							return fmt.Errorf("-format=teradata-fastload to stdout needs -teradata-ctl-file")
+						}
+						ctlFile = strings.TrimSuffix(*flagOut, filepath.Ext(*flagOut)) + ".ctl"
+					}
+					var ff *os.File
+					if ff, err = os.Create(ctlFile); err != nil {
+						return err
+					}
+					defer ff.Close()
+					if dumped, err = dbcsv.DumpFastLoad(ctx, wfh, ff, rows, columns, opts, Log); err == nil {
+						err = ff.Close()
+					}
+				case "nquads":
+					dumped, err = dbcsv.DumpNQuads(ctx, w, rows, columns, dbcsv.NQuadsOptions{
+						SubjectColumn: *flagRDFSubjectCol, PredicatePrefix: *flagRDFPredicatePrefix,
+						ObjectColumn: *flagRDFObjectCol, GraphColumn: *flagRDFGraphCol,
+						DumperOptions: dumpOpts,
+					}, Log)
+				case "dot":
+					if *flagDOTDirected && *flagDOTUndirected {
+						return fmt.Errorf("-dot-directed and -dot-undirected are mutually exclusive")
+					}
+					dumped, err = dbcsv.DumpDOT(ctx, w, rows, columns, dbcsv.DOTOptions{
+						SourceColumn: *flagDOTSourceCol, TargetColumn: *flagDOTTargetCol,
+						LabelColumn: *flagDOTLabelCol, WeightColumn: *flagDOTWeightCol,
+						Undirected: *flagDOTUndirected, DumperOptions: dumpOpts,
+					}, Log)
+				case "json":
+					dumped, err = dbcsv.DumpJSON(ctx, w, rows, columns, dbcsv.JSONOptions{Sparse: *flagSparse, SparseDefault: *flagSparseDefault, DumperOptions: dumpOpts}, Log)
+				case "key-value-json":
+					if *flagKVKeyCol == "" {
+						return fmt.Errorf("-format=key-value-json needs -kv-key-col")
+					}
+					dumped, err = dbcsv.DumpKeyValueJSON(ctx, w, rows, columns, dbcsv.KeyValueJSONOptions{KeyColumn: *flagKVKeyCol, Merge: *flagKVMerge, DumperOptions: dumpOpts}, Log)
+				case "parquet":
+					dumped, err = dbcsv.DumpParquet(ctx, wfh, rows, columns, dumpOpts, Log)
+				case "fixed":
+					opts := dbcsv.FixedOptions{DumperOptions: dumpOpts}
+					opts.Sep = *flagFixedSep
+					if *flagFixedWidths != "" {
+						for _, s := range strings.Split(*flagFixedWidths, ",") {
+							n, err := strconv.Atoi(strings.TrimSpace(s))
+							if err != nil || n < 0 {
+								return fmt.Errorf("fixed-widths %q: wanted non-negative integers", *flagFixedWidths)
+							}
+							opts.Widths = append(opts.Widths, n)
+						}
+					}
+					dumped, err = dbcsv.DumpFixed(ctx, w, rows, columns, opts, Log)
+				case "markdown":
+					dumped, err = dbcsv.DumpMarkdown(ctx, w, rows, columns, dumpOpts, Log)
+				case "sqlite":
+					if *flagOut == "" || *flagOut == "-" || wfh != fh {
+						return fmt.Errorf("-format=sqlite needs an uncompressed -o file")
+					}
+					table := *flagSQLiteTable
+					if table == "" {
+						table = argTable("exported")
+					}
+					dumped, err = dbcsv.DumpSQLite(ctx, *flagOut, rows, columns, dbcsv.SQLiteOptions{Table: table, BatchSize: *flagSQLiteBatchSize, DumperOptions: dumpOpts}, Log)
+				case "proto-json":
+					var md protoreflect.MessageDescriptor
+					if *flagProtoSchema != "" {
+						md, err = dbcsv.ParseProtoFile(*flagProtoSchema, *flagProtoMessage)
+					} else {
+						_, md, err = dbcsv.ProtoDescriptor(*flagProtoMessage, columns)
+					}
+					if err == nil {
+						dumped, err = dbcsv.DumpProtoJSON(ctx, w, rows, columns, md, dumpOpts, Log)
+					}
+				case "pandas-pickle":
+					dumped, err = dbcsv.DumpPandasPickle(ctx, w, rows, columns, dumpOpts, Log)
+				case "xlsx-template":
+					if *flagXLSXTemplate == "" {
+						return fmt.Errorf("-format=xlsx-template needs -xlsx-template")
+					}
+					dumped, err = dbcsv.DumpXLSXTemplate(ctx, wfh, *flagXLSXTemplate, *flagXLSXTemplateSheet, *flagXLSXDataStartRow, rows, columns, dumpOpts, Log)
+				case "tsv":
+					opts := dumpOpts
+					opts.TSV = true
+					dumped, err = dbcsv.Dumper{Log: Log, DumperOptions: opts}.DumpCSV(ctx, w, rows, columns)
+				case "", "csv":
+					if !splitting {
+						dumped, err = dbcsv.Dumper{Log: Log, DumperOptions: dumpOpts}.DumpCSV(ctx, w, rows, columns)
+						break
+					}
+					base, ext := "split", ".csv"
+					if !(*flagOut == "" || *flagOut == "-") {
+						if ext = filepath.Ext(*flagOut); ext == "" {
+							ext = ".csv"
+						}
+						base = strings.TrimSuffix(*flagOut, filepath.Ext(*flagOut))
+					}
+					create := func(fn string) (io.WriteCloser, error) {
+						f, err := os.Create(fn)
+						if err != nil {
+							return nil, err
+						}
+						return struct {
+							io.Writer
+							io.Closer
+						}{encoding.ReplaceUnsupported(enc.NewEncoder()).Writer(f), f}, nil
+					}
+					if *flagSplitRows != 0 {
+						dumped, err = dbcsv.DumpCSVSplitRows(ctx, rows, columns, dbcsv.SplitRowsOptions{
+							Rows: *flagSplitRows, DumperOptions: dumpOpts,
+							Create: func(part int) (io.WriteCloser, error) {
+								return create(dbcsv.SplitRowsFileName(base, ext, part))
+							},
+						}, Log)
+						break
+					}
+					dumped, err = dbcsv.DumpCSVSplitBy(ctx, rows, columns, dbcsv.SplitByOptions{
+						Column: *flagSplitBy, MaxFiles: *flagSplitByMaxFiles, DumperOptions: dumpOpts,
+						Create: func(value string) (io.WriteCloser, error) {
+							return create(dbcsv.SplitFileName(base, ext, value))
+						},
+						CreateNull: func() (io.WriteCloser, error) {
+							return create(dbcsv.SplitNullFileName(base, ext))
+						},
+					}, Log)
+				default:
+					err = fmt.Errorf("unknown format %q", *flagFormat)
+				}
+				atomic.AddInt64(&rowCount, int64(dumped))
+			}
 		}
 	} else {
 		var w spreadsheet.Writer
-		if strings.HasSuffix(fh.Name(), ".xlsx") {
+		// wfh is compressed with -compress
+		if isXLSX(fh.Name()) {
 			w = xlsx.NewWriter(wfh)
 		} else {
 			w, err = ods.NewWriter(wfh)
@@ -226,11 +1996,17 @@ and dump all the columns of the cursor returned by the function.
 			if name == "" {
 				name = strconv.Itoa(sheetNo + 1)
 			}
-			rows, columns, qErr := doQuery(ctx, tx, qry, nil, false, *flagSort)
+			qRows, columns, qErr := cachedQuery(ctx, queryCache, tx, oracle, qry, nil, false, *flagSort, paging{}, Log)
 			if qErr != nil {
 				err = qErr
 				break
 			}
+			rows, columns, wErr := wrapRows(qRows, columns, wrappers)
+			if wErr != nil {
+				qRows.Close()
+				err = wErr
+				break
+			}
 			header := make([]spreadsheet.Column, len(columns))
 			if *flagHeader {
 				for i, c := range columns {
@@ -245,8 +2021,9 @@ and dump all the columns of the cursor returned by the function.
 			}
 			grp.Go(func() error {
 				_ = Log(name, qry)
-				err := dbcsv.DumpSheet(ctx, sheet, rows, columns, Log)
+				n, err := dbcsv.Dumper{Log: Log, DumperOptions: dumpOpts}.DumpSheet(ctx, sheet, rows, columns)
 				rows.Close()
+				atomic.AddInt64(&rowCount, int64(n))
 				if closeErr := sheet.Close(); closeErr != nil && err == nil {
 					return closeErr
 				}
@@ -261,6 +2038,9 @@ and dump all the columns of the cursor returned by the function.
 			err = closeErr
 		}
 	}
+	for _, f := range reports {
+		f()
+	}
 	cancel()
 	if wfh != fh {
 		if closeErr := wfh.Close(); closeErr != nil && err == nil {
@@ -270,6 +2050,19 @@ and dump all the columns of the cursor returned by the function.
 	if closeErr := fh.Close(); closeErr != nil && err == nil {
 		err = closeErr
 	}
+	if errors.Is(err, dbcsv.ErrTooManyErrors) {
+		var logs []string
+		for _, fn := range []string{*flagRangeValidateLog, *flagValidateErrorOutput} {
+			if fn != "" {
+				logs = append(logs, fn)
+			}
+		}
+		if len(logs) != 0 {
+			err = fmt.Errorf("aborted after %d errors (see %s): %w", budget.Count(), strings.Join(logs, ", "), err)
+		} else {
+			err = fmt.Errorf("aborted after %d errors: %w", budget.Count(), err)
+		}
+	}
 	return err
 }
 
@@ -298,6 +2091,410 @@ func getQuery(table, where string, columns []string, enc encoding.Encoding) stri
 	return "SELECT " + cols + " FROM " + table + " WHERE " + where //nolint:gas
 }
 
+// countQuery returns the query counting the rows getQuery would return without paging,
+// or "" for raw queries.
+func countQuery(table, where string) string {
+	table = strings.TrimSpace(table)
+	if table == "" || strings.HasPrefix(strings.ToUpper(table), "SELECT ") {
+		return ""
+	}
+	if where == "" {
+		return "SELECT COUNT(*) FROM " + table //nolint:gas
+	}
+	return "SELECT COUNT(*) FROM " + table + " WHERE " + where //nolint:gas
+}
+
+// paging is the window of rows returned by doQuery - which sorts the unordered queries before paging them.
+type paging struct {
+	Offset, Size int
+	// RowNum uses ROWNUM instead of the OFFSET ... FETCH NEXT syntax (Oracle 12c+).
+	RowNum bool
+	// Limit uses the LIMIT ... OFFSET syntax (PostgreSQL, MySQL).
+	Limit bool
+}
+
+// apply returns the query of the page of qry's rows, ordered by orderBy (such as "1,2") - if not empty -
+// before the paging clause.
+func (p paging) apply(qry, orderBy string) string {
+	if p.Offset <= 0 && p.Size <= 0 {
+		if orderBy != "" {
+			return qry + " ORDER BY " + orderBy
+		}
+		return qry
+	}
+	if orderBy != "" {
+		qry += " ORDER BY " + orderBy
+	}
+	if p.Limit {
+		// MySQL has no OFFSET without LIMIT
+		size := int64(math.MaxInt64)
+		if p.Size > 0 {
+			size = int64(p.Size)
+		}
+		qry += fmt.Sprintf(" LIMIT %d", size)
+		if p.Offset > 0 {
+			qry += fmt.Sprintf(" OFFSET %d", p.Offset)
+		}
+		return qry
+	}
+	if p.RowNum {
+		if p.Offset <= 0 {
+			return fmt.Sprintf("SELECT * FROM (%s) WHERE ROWNUM <= %d", qry, p.Size)
+		}
+		if p.Size <= 0 {
+			return fmt.Sprintf("SELECT * FROM (SELECT A.*, ROWNUM AS RN__ FROM (%s) A) WHERE RN__ > %d", qry, p.Offset)
+		}
+		return fmt.Sprintf("SELECT * FROM (SELECT A.*, ROWNUM AS RN__ FROM (%s) A WHERE ROWNUM <= %d) WHERE RN__ > %d",
+			qry, p.Offset+p.Size, p.Offset)
+	}
+	if p.Offset > 0 {
+		qry += fmt.Sprintf(" OFFSET %d ROWS", p.Offset)
+	}
+	if p.Size > 0 {
+		qry += fmt.Sprintf(" FETCH NEXT %d ROWS ONLY", p.Size)
+	}
+	return qry
+}
+
+// writeProtoDescriptor writes the .proto file of the message of the columns.
+func writeProtoDescriptor(fileName, message string, columns []dbcsv.Column) error {
+	fdp, _, err := dbcsv.ProtoDescriptor(message, columns)
+	if err != nil {
+		return err
+	}
+	fh, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	if err = dbcsv.WriteProtoFile(fh, fdp); err != nil {
+		fh.Close()
+		return fmt.Errorf("%s: %w", fileName, err)
+	}
+	return fh.Close()
+}
+
+// argTable returns the table argument, or def if the argument is a query.
+func argTable(def string) string {
+	if tbl := strings.TrimSpace(flag.Arg(0)); tbl != "" && !strings.HasPrefix(strings.ToUpper(tbl), "SELECT ") {
+		return tbl
+	}
+	return def
+}
+
+// rDateFormatColumn matches the COL: prefixes of the per-column -date formats.
+var rDateFormatColumn = regexp.MustCompile(`(?:^|,)([A-Za-z_][\w$#]*):`)
+
+// parseDateFormats parses the -date flag: the FORMAT (default) and the COL:FORMAT (per-column) parts,
+// separated by commas. The formats may contain commas and colons, too: only those commas start a new part,
+// which are followed by a column name and a colon.
+// A column must not be given twice (case insensitively), nor with an empty format.
+func parseDateFormats(s string) (string, map[string]string, error) {
+	locs := rDateFormatColumn.FindAllStringSubmatchIndex(s, -1)
+	if len(locs) == 0 {
+		return s, nil, nil
+	}
+	def := s[:locs[0][0]]
+	m := make(map[string]string, len(locs))
+	for i, loc := range locs {
+		end := len(s)
+		if i+1 < len(locs) {
+			end = locs[i+1][0]
+		}
+		name, format := s[loc[2]:loc[3]], s[loc[1]:end]
+		if format == "" {
+			return "", nil, fmt.Errorf("%s: empty format", name)
+		}
+		for k := range m {
+			if strings.EqualFold(k, name) {
+				return "", nil, fmt.Errorf("%s: duplicate column", name)
+			}
+		}
+		m[name] = format
+	}
+	return def, m, nil
+}
+
+// splitRangeSpec splits the COL:MIN:MAX spec of -range-validate.
+// MIN and MAX may contain colons (such as 2024-01-01 10:00:00) if one of them is empty,
+// or both have the same number of them.
+func splitRangeSpec(spec string) (col, min, max string, err error) {
+	i := strings.IndexByte(spec, ':')
+	if i < 0 {
+		return "", "", "", fmt.Errorf("range-validate %q: wanted COL:MIN:MAX", spec)
+	}
+	col, bounds := spec[:i], spec[i+1:]
+	if strings.HasPrefix(bounds, ":") {
+		return col, "", bounds[1:], nil
+	} else if strings.HasSuffix(bounds, ":") {
+		return col, bounds[:len(bounds)-1], "", nil
+	}
+	n := strings.Count(bounds, ":")
+	if n%2 == 0 {
+		return "", "", "", fmt.Errorf("range-validate %q: wanted COL:MIN:MAX", spec)
+	}
+	// the middle colon
+	j := -1
+	for k := 0; k <= n/2; k++ {
+		j += 1 + strings.IndexByte(bounds[j+1:], ':')
+	}
+	return col, bounds[:j], bounds[j+1:], nil
+}
+
+// isXLSX reports whether the spreadsheet file is an XLSX (and not an ODS),
+// by its extension before the compression's, so out.xlsx.gz is an XLSX, too.
+func isXLSX(fileName string) bool {
+	for _, ext := range []string{".gz", ".gzip", ".zst", ".zstd"} {
+		fileName = strings.TrimSuffix(fileName, ext)
+	}
+	return strings.HasSuffix(fileName, ".xlsx")
+}
+
+// checkDateFormats returns an error if a column of the per-column -date formats is not among the columns.
+func checkDateFormats(formats map[string]string, columns []dbcsv.Column) error {
+	for name := range formats {
+		if _, err := columnIndex(columns, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadInput reads the (named or first) sheet of the file.
+func loadInput(ctx context.Context, fileName, sheet string) (dbcsv.Rows, []dbcsv.Column, error) {
+	var cfg dbcsv.Config
+	if err := cfg.Open(fileName); err != nil {
+		return nil, nil, err
+	}
+	defer cfg.Close()
+	if sheet != "" {
+		var err error
+		if cfg.Sheet, err = cfg.SheetIndex(ctx, sheet); err != nil {
+			return nil, nil, err
+		}
+	}
+	return cfg.LoadRows(ctx)
+}
+
+// rowsWrapper wraps the rows (and columns) of a query, to validate or transform them.
+type rowsWrapper func(dbcsv.Rows, []dbcsv.Column) (dbcsv.Rows, []dbcsv.Column, error)
+
+func wrapRows(rows dbcsv.Rows, columns []dbcsv.Column, wrappers []rowsWrapper) (dbcsv.Rows, []dbcsv.Column, error) {
+	for _, w := range wrappers {
+		var err error
+		if rows, columns, err = w(rows, columns); err != nil {
+			return nil, nil, err
+		}
+	}
+	return rows, columns, nil
+}
+
+// readColumnMap reads the original,renamed column name pairs from the CSV file,
+// keyed by the lower cased original name. The "original,renamed" header is optional.
+func readColumnMap(fileName string) (map[string]string, error) {
+	fh, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+	cr := csv.NewReader(fh)
+	cr.FieldsPerRecord = 2
+	cr.TrimLeadingSpace = true
+	m := make(map[string]string)
+	for {
+		rec, err := cr.Read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return m, nil
+			}
+			return nil, fmt.Errorf("%s: %w", fileName, err)
+		}
+		if len(m) == 0 && strings.EqualFold(rec[0], "original") && strings.EqualFold(rec[1], "renamed") {
+			continue
+		}
+		m[strings.ToLower(rec[0])] = rec[1]
+	}
+}
+
+// snakeCase returns the name in snake_case: MY_COLUMN and MyColumn both become my_column.
+func snakeCase(name string) string {
+	var buf strings.Builder
+	var prev rune
+	for _, r := range name {
+		switch {
+		case r == ' ' || r == '-':
+			buf.WriteByte('_')
+		case unicode.IsUpper(r):
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) {
+				buf.WriteByte('_')
+			}
+			buf.WriteRune(unicode.ToLower(r))
+		default:
+			buf.WriteRune(r)
+		}
+		prev = r
+	}
+	return buf.String()
+}
+
+// columnIndex returns the index of the column with the given name (case insensitive).
+func columnIndex(columns []dbcsv.Column, name string) (int, error) {
+	for i, c := range columns {
+		if strings.EqualFold(c.Name, name) {
+			return i, nil
+		}
+	}
+	return -1, fmt.Errorf("%s: unknown column", name)
+}
+
+// dialSyslog connects to the syslog server at addr (network://host:port, udp if no network is given),
+// or to the local syslog socket if addr is empty.
+func dialSyslog(addr string) (net.Conn, error) {
+	if addr != "" {
+		network := "udp"
+		if i := strings.Index(addr, "://"); i >= 0 {
+			network, addr = addr[:i], addr[i+3:]
+		}
+		return net.Dial(network, addr)
+	}
+	var firstErr error
+	for _, path := range []string{"/dev/log", "/var/run/syslog", "/var/run/log"} {
+		for _, network := range []string{"unixgram", "unix"} {
+			conn, err := net.Dial(network, path)
+			if err == nil {
+				return conn, nil
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return nil, fmt.Errorf("connect to local syslog: %w", firstErr)
+}
+
+// openDB opens the database of the driver.
+// For mysql, the DSN is parsed and parseTime is forced, to get dates as time.Time.
+func openDB(driverName, dsn string) (*sql.DB, error) {
+	if driverName != "mysql" {
+		return sql.Open(driverName, dsn)
+	}
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+	cfg.ParseTime = true
+	connector, err := mysql.NewConnector(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return sql.OpenDB(connector), nil
+}
+
+// buildWhere reads filter expressions (see dbcsv.FilterCondition) from r, one per line,
+// until an empty line, and writes the WHERE clause of them (joined with AND) to w.
+func buildWhere(ctx context.Context, db queryExecer, oracle bool, qry string, r io.Reader, w io.Writer) error {
+	rows, columns, err := doQuery(ctx, db, oracle, qry, nil, false, false, paging{})
+	if err != nil {
+		return err
+	}
+	rows.Close()
+	names := make([]string, len(columns))
+	for i, c := range columns {
+		names[i] = c.Name
+	}
+	fmt.Fprintf(os.Stderr, "Columns: %s\nEnter the filters one per line, finish with an empty line.\n", strings.Join(names, ", "))
+	var conds []string
+	scanner := bufio.NewScanner(r)
+	for {
+		fmt.Fprint(os.Stderr, "filter> ")
+		if !scanner.Scan() {
+			break
+		}
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			break
+		}
+		cond, err := dbcsv.FilterCondition(columns, line)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			continue
+		}
+		conds = append(conds, cond)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if len(conds) == 0 {
+		return nil
+	}
+	_, err = fmt.Fprintln(w, "WHERE "+strings.Join(conds, " AND "))
+	return err
+}
+
+var (
+	rxPGRows        = regexp.MustCompile(`\brows=([0-9]+)`)
+	rxOraclePlanRow = regexp.MustCompile(`^\|\*? *0 *\|`)
+)
+
+// explainQuery writes the execution plan of the query (EXPLAIN PLAN and DBMS_XPLAN on Oracle, EXPLAIN otherwise)
+// and the estimated number of rows (of the top operation) to w.
+func explainQuery(ctx context.Context, db queryExecer, oracle bool, qry string, w io.Writer) error {
+	explain, show := "EXPLAIN "+qry, "EXPLAIN "+qry
+	if oracle {
+		explain, show = "EXPLAIN PLAN FOR "+qry, "SELECT plan_table_output FROM TABLE(DBMS_XPLAN.DISPLAY())"
+		if _, err := db.ExecContext(ctx, explain); err != nil {
+			return fmt.Errorf("%s: %w", explain, err)
+		}
+	}
+	rows, err := db.QueryContext(ctx, show)
+	if err != nil {
+		return fmt.Errorf("%s: %w", show, err)
+	}
+	defer rows.Close()
+	var plan []string
+	for rows.Next() {
+		var line sql.NullString
+		if err = rows.Scan(&line); err != nil {
+			return fmt.Errorf("%s: %w", show, err)
+		}
+		plan = append(plan, line.String)
+	}
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("%s: %w", show, err)
+	}
+	estimate := "unknown"
+	if n, ok := estimatedRows(plan); ok {
+		estimate = n
+	}
+	_, err = fmt.Fprintf(w, "%s\n\nestimated rows: %s\n", strings.Join(plan, "\n"), estimate)
+	return err
+}
+
+// estimatedRows returns the estimated row count of the top operation of the plan:
+// the Rows column of the Id 0 line of DBMS_XPLAN (such as 1000K), or the first rows=N of PostgreSQL's EXPLAIN.
+func estimatedRows(plan []string) (string, bool) {
+	rowsCol := -1
+	for _, line := range plan {
+		if m := rxPGRows.FindStringSubmatch(line); m != nil {
+			return m[1], true
+		}
+		cells := strings.Split(line, "|")
+		if rowsCol < 0 {
+			for i, c := range cells {
+				if strings.TrimSpace(c) == "Rows" {
+					rowsCol = i
+				}
+			}
+			continue
+		}
+		if rxOraclePlanRow.MatchString(line) && rowsCol < len(cells) {
+			if n := strings.TrimSpace(cells[rowsCol]); n != "" {
+				return n, true
+			}
+		}
+	}
+	return "", false
+}
+
 type queryer interface {
 	QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error)
 }
@@ -310,16 +2507,55 @@ type queryExecer interface {
 	execer
 }
 
-func doQuery(ctx context.Context, db queryExecer, qry string, params []interface{}, isCall, doSort bool) (*sql.Rows, []dbcsv.Column, error) {
+// cachedQuery is doQuery, reading the rows from the query cache on hit, and writing them into it on miss.
+// Calls are not cached, neither is anything without qc.Dir.
+func cachedQuery(ctx context.Context, qc dbcsv.QueryCache, db queryExecer, oracle bool, qry string, params []interface{}, isCall, doSort bool, page paging, Log func(...interface{}) error) (dbcsv.Rows, []dbcsv.Column, error) {
+	if qc.Dir == "" || isCall {
+		return doQuery(ctx, db, oracle, qry, params, isCall, doSort, page)
+	}
+	// the sorted rows differ from the unsorted ones, the pages from each other
+	key := dbcsv.QueryCacheKey(page.apply(qry, ""), append([]interface{}{doSort}, params...))
+	rows, columns, err := qc.Open(key)
+	if err == nil {
+		if Log != nil {
+			_ = Log("msg", "query cache hit", "key", key)
+		}
+		return rows, columns, nil
+	} else if !errors.Is(err, dbcsv.ErrCacheMiss) {
+		return nil, nil, err
+	}
+	qRows, columns, err := doQuery(ctx, db, oracle, qry, params, isCall, doSort, page)
+	if err != nil {
+		return nil, nil, err
+	}
+	if rows, err = qc.Tee(key, qRows, columns); err != nil {
+		qRows.Close()
+		return nil, nil, err
+	}
+	return rows, columns, nil
+}
+
+// doQuery executes the query (or calls the function/procedure with isCall) - with the godror specific options when oracle.
+// The query is sorted by its columns with doSort, then paged - a page of an unordered query is sorted, too,
+// as without an order, the rows of the pages may overlap.
+func doQuery(ctx context.Context, db queryExecer, oracle bool, qry string, params []interface{}, isCall, doSort bool, page paging) (*sql.Rows, []dbcsv.Column, error) {
 	var rows *sql.Rows
 	var err error
 	const batchSize = 1024
+	var opts []interface{}
+	if oracle {
+		// stream the LOBs, to read them whole (or up to -lob-max)
+		opts = []interface{}{godror.FetchRowCount(batchSize), godror.PrefetchCount(batchSize), godror.LobAsReader()}
+	}
 	if !isCall {
-		origQry := qry
-		if doSort && strings.HasPrefix(qry, "SELECT * FROM") {
-			rows, err := db.QueryContext(ctx, qry+" FETCH FIRST ROW ONLY")
+		var orderBy string
+		paged := page.Offset > 0 || page.Size > 0
+		if doSort && strings.HasPrefix(qry, "SELECT * FROM") ||
+			paged && !strings.Contains(strings.ToUpper(qry), "ORDER BY") {
+			first := paging{Size: 1, RowNum: page.RowNum, Limit: page.Limit}.apply(qry, "")
+			rows, err := db.QueryContext(ctx, first, params...)
 			if err != nil {
-				if rows, err = db.QueryContext(ctx, qry); err != nil {
+				if rows, err = db.QueryContext(ctx, qry, params...); err != nil {
 					return nil, nil, fmt.Errorf("%s: %w", qry, err)
 				}
 			}
@@ -333,26 +2569,25 @@ func doQuery(ctx context.Context, db queryExecer, qry string, params []interface
 				if strings.HasSuffix(c.DatabaseTypeName(), "LOB") {
 					continue
 				}
-				if i == 0 {
-					bld.WriteString(qry)
-					bld.WriteString(" ORDER BY ")
-				} else {
+				if bld.Len() != 0 {
 					bld.WriteByte(',')
 				}
 				fmt.Fprintf(&bld, "%d", i+1)
 			}
-			if bld.Len() != 0 {
-				qry = bld.String()
-			}
-		}
-		if rows, err = db.QueryContext(ctx, qry, godror.FetchRowCount(batchSize), godror.PrefetchCount(batchSize)); err != nil {
-			qry = origQry
-			rows, err = db.QueryContext(ctx, qry, godror.FetchRowCount(batchSize), godror.PrefetchCount(batchSize))
+			orderBy = bld.String()
 		}
+		// the ORDER BY precedes the paging
+		qry = page.apply(qry, orderBy)
+		// the bind parameters of the query (-param)
+		opts = append(opts, params...)
+		rows, err = db.QueryContext(ctx, qry, opts...)
+	} else if !oracle {
+		// CALL returns the OUT and INOUT parameters as a row
+		rows, err = db.QueryContext(ctx, qry, params...)
 	} else {
 		var dRows driver.Rows
-		params = append(append(make([]interface{}, 0, 2+len(params)),
-			sql.Out{Dest: &dRows}, godror.FetchRowCount(batchSize), godror.PrefetchCount(batchSize)),
+		params = append(append(append(make([]interface{}, 0, 1+len(opts)+len(params)),
+			sql.Out{Dest: &dRows}), opts...),
 			params...)
 		if _, err = db.ExecContext(ctx, qry, params...); err == nil {
 			rows, err = godror.WrapRows(ctx, db, dRows)
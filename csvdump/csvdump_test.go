@@ -0,0 +1,165 @@
+// Copyright 2020 Tamás Gulácsi.
+//
+//
+// SPDX-License-Identifier: UPL-1.0 OR Apache-2.0
+
+package main
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"testing"
+
+	"github.com/UNO-SOFT/dbcsv"
+	_ "modernc.org/sqlite"
+)
+
+func TestPagingApply(t *testing.T) {
+	const qry = "SELECT * FROM t"
+	for name, tc := range map[string]struct {
+		page    paging
+		orderBy string
+		want    string
+	}{
+		"none":           {paging{}, "", qry},
+		"none-sorted":    {paging{}, "1", qry + " ORDER BY 1"},
+		"fetch":          {paging{Offset: 10, Size: 5}, "1,2", qry + " ORDER BY 1,2 OFFSET 10 ROWS FETCH NEXT 5 ROWS ONLY"},
+		"fetch-size":     {paging{Size: 5}, "1", qry + " ORDER BY 1 FETCH NEXT 5 ROWS ONLY"},
+		"fetch-offset":   {paging{Offset: 10}, "", qry + " OFFSET 10 ROWS"},
+		"limit":          {paging{Offset: 10, Size: 5, Limit: true}, "1,2", qry + " ORDER BY 1,2 LIMIT 5 OFFSET 10"},
+		"limit-size":     {paging{Size: 5, Limit: true}, "", qry + " LIMIT 5"},
+		"limit-offset":   {paging{Offset: 10, Limit: true}, "1", qry + " ORDER BY 1 LIMIT 9223372036854775807 OFFSET 10"},
+		"rownum":         {paging{Offset: 10, Size: 5, RowNum: true}, "1,2", "SELECT * FROM (SELECT A.*, ROWNUM AS RN__ FROM (" + qry + " ORDER BY 1,2) A WHERE ROWNUM <= 15) WHERE RN__ > 10"},
+		"rownum-size":    {paging{Size: 5, RowNum: true}, "1", "SELECT * FROM (" + qry + " ORDER BY 1) WHERE ROWNUM <= 5"},
+		"rownum-offset":  {paging{Offset: 10, RowNum: true}, "1", "SELECT * FROM (SELECT A.*, ROWNUM AS RN__ FROM (" + qry + " ORDER BY 1) A) WHERE RN__ > 10"},
+		"rownum-ordered": {paging{Size: 5, RowNum: true}, "", "SELECT * FROM (" + qry + ") WHERE ROWNUM <= 5"},
+	} {
+		if got := tc.page.apply(qry, tc.orderBy); got != tc.want {
+			t.Errorf("%s: got %q, wanted %q", name, got, tc.want)
+		}
+	}
+}
+
+func TestDoQuerySortPage(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	ctx := context.Background()
+	if _, err = db.ExecContext(ctx, "CREATE TABLE t (a INTEGER); INSERT INTO t VALUES (3), (5), (1), (4), (2)"); err != nil {
+		t.Fatal(err)
+	}
+	// the pages of the unsorted queries are sorted, too
+	for _, doSort := range []bool{true, false} {
+		rows, _, err := doQuery(ctx, db, false, "SELECT * FROM t", nil, false, doSort, paging{Offset: 1, Size: 2, Limit: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []int64
+		for rows.Next() {
+			var a int64
+			if err = rows.Scan(&a); err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, a)
+		}
+		rows.Close()
+		if len(got) != 2 || got[0] != 2 || got[1] != 3 {
+			t.Errorf("sort=%t: got %v, wanted [2 3]", doSort, got)
+		}
+	}
+}
+
+func TestParseDateFormats(t *testing.T) {
+	columns := []dbcsv.Column{{Name: "CREATED"}, {Name: "Updated"}}
+	for _, tc := range []struct {
+		In      string
+		Def     string
+		Formats map[string]string
+		Err     bool
+	}{
+		{In: "2006-01-02", Def: "2006-01-02"},
+		{In: "Mon, 02 Jan 2006", Def: "Mon, 02 Jan 2006"},
+		{In: "2006-01-02,created:2006-01-02 15:04,UPDATED:15:04", Def: "2006-01-02",
+			Formats: map[string]string{"created": "2006-01-02 15:04", "UPDATED": "15:04"}},
+		{In: "CREATED:Mon, 02 Jan 2006", Formats: map[string]string{"CREATED": "Mon, 02 Jan 2006"}},
+		{In: "CREATED:", Err: true},
+		{In: "2006,CREATED:,UPDATED:15:04", Err: true},
+		{In: "CREATED:2006,created:15:04", Err: true},
+		{In: "DELETED:2006", Err: true},
+	} {
+		def, formats, err := parseDateFormats(tc.In)
+		if err == nil {
+			// the columns are checked after the query
+			err = checkDateFormats(formats, columns)
+		}
+		if err != nil {
+			if !tc.Err {
+				t.Errorf("%q: %+v", tc.In, err)
+			}
+			continue
+		}
+		if tc.Err {
+			t.Errorf("%q: wanted error, got %q %v", tc.In, def, formats)
+			continue
+		}
+		if def != tc.Def || !reflect.DeepEqual(formats, tc.Formats) {
+			t.Errorf("%q: got %q %v, wanted %q %v", tc.In, def, formats, tc.Def, tc.Formats)
+		}
+	}
+}
+
+func TestSplitRangeSpec(t *testing.T) {
+	for _, tc := range []struct {
+		In            string
+		Col, Min, Max string
+		Err           bool
+	}{
+		{In: "ID:1:10", Col: "ID", Min: "1", Max: "10"},
+		{In: "ID::10", Col: "ID", Max: "10"},
+		{In: "ID:1:", Col: "ID", Min: "1"},
+		{In: "ID::", Col: "ID"},
+		{In: "D:2024-01-01 10:00:00:", Col: "D", Min: "2024-01-01 10:00:00"},
+		{In: "D::2024-12-31 23:59:59", Col: "D", Max: "2024-12-31 23:59:59"},
+		{In: "D:2024-01-01 10:00:2024-12-31 23:59", Col: "D", Min: "2024-01-01 10:00", Max: "2024-12-31 23:59"},
+		{In: "ID", Err: true},
+		{In: "ID:1", Err: true},
+		{In: "D:10:00:12:00:00", Err: true},
+	} {
+		col, min, max, err := splitRangeSpec(tc.In)
+		if err != nil {
+			if !tc.Err {
+				t.Errorf("%q: %+v", tc.In, err)
+			}
+			continue
+		}
+		if tc.Err {
+			t.Errorf("%q: wanted error, got %q %q %q", tc.In, col, min, max)
+			continue
+		}
+		if col != tc.Col || min != tc.Min || max != tc.Max {
+			t.Errorf("%q: got %q %q %q, wanted %q %q %q", tc.In, col, min, max, tc.Col, tc.Min, tc.Max)
+		}
+	}
+}
+
+func TestIsXLSX(t *testing.T) {
+	for _, tc := range []struct {
+		In   string
+		Want bool
+	}{
+		{In: "out.xlsx", Want: true},
+		{In: "out.xlsx.gz", Want: true},
+		{In: "out.xlsx.zstd", Want: true},
+		{In: "out.ods"},
+		{In: "out.ods.zst"},
+		{In: "out.xlsx.ods.gz"},
+	} {
+		if got := isXLSX(tc.In); got != tc.Want {
+			t.Errorf("%q: got %t, wanted %t", tc.In, got, tc.Want)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright 2021 Tamás Gulácsi.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package dbcsv
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/text/language"
+)
+
+// DateNames are the month and weekday names of a locale.
+type DateNames struct {
+	Months   [12]string
+	Weekdays [7]string // from Sunday
+}
+
+var (
+	dateNamesTags = []language.Tag{
+		language.English, language.German, language.French, language.Spanish,
+		language.Italian, language.Dutch, language.Portuguese, language.Hungarian,
+	}
+	dateNames = []DateNames{
+		{
+			Months:   [12]string{"January", "February", "March", "April", "May", "June", "July", "August", "September", "October", "November", "December"},
+			Weekdays: [7]string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"},
+		},
+		{
+			Months:   [12]string{"Januar", "Februar", "März", "April", "Mai", "Juni", "Juli", "August", "September", "Oktober", "November", "Dezember"},
+			Weekdays: [7]string{"Sonntag", "Montag", "Dienstag", "Mittwoch", "Donnerstag", "Freitag", "Samstag"},
+		},
+		{
+			Months:   [12]string{"janvier", "février", "mars", "avril", "mai", "juin", "juillet", "août", "septembre", "octobre", "novembre", "décembre"},
+			Weekdays: [7]string{"dimanche", "lundi", "mardi", "mercredi", "jeudi", "vendredi", "samedi"},
+		},
+		{
+			Months:   [12]string{"enero", "febrero", "marzo", "abril", "mayo", "junio", "julio", "agosto", "septiembre", "octubre", "noviembre", "diciembre"},
+			Weekdays: [7]string{"domingo", "lunes", "martes", "miércoles", "jueves", "viernes", "sábado"},
+		},
+		{
+			Months:   [12]string{"gennaio", "febbraio", "marzo", "aprile", "maggio", "giugno", "luglio", "agosto", "settembre", "ottobre", "novembre", "dicembre"},
+			Weekdays: [7]string{"domenica", "lunedì", "martedì", "mercoledì", "giovedì", "venerdì", "sabato"},
+		},
+		{
+			Months:   [12]string{"januari", "februari", "maart", "april", "mei", "juni", "juli", "augustus", "september", "oktober", "november", "december"},
+			Weekdays: [7]string{"zondag", "maandag", "dinsdag", "woensdag", "donderdag", "vrijdag", "zaterdag"},
+		},
+		{
+			Months:   [12]string{"janeiro", "fevereiro", "março", "abril", "maio", "junho", "julho", "agosto", "setembro", "outubro", "novembro", "dezembro"},
+			Weekdays: [7]string{"domingo", "segunda-feira", "terça-feira", "quarta-feira", "quinta-feira", "sexta-feira", "sábado"},
+		},
+		{
+			Months:   [12]string{"január", "február", "március", "április", "május", "június", "július", "augusztus", "szeptember", "október", "november", "december"},
+			Weekdays: [7]string{"vasárnap", "hétfő", "kedd", "szerda", "csütörtök", "péntek", "szombat"},
+		},
+	}
+	dateNamesMatcher = language.NewMatcher(dateNamesTags)
+)
+
+// LookupDateNames returns the date names of the locale (such as de_DE or fr-FR).
+func LookupDateNames(locale string) (*DateNames, error) {
+	tag, err := language.Parse(strings.ReplaceAll(locale, "_", "-"))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", locale, err)
+	}
+	_, i, conf := dateNamesMatcher.Match(tag)
+	if conf == language.No {
+		return nil, fmt.Errorf("%s: no date names for this locale", locale)
+	}
+	return &dateNames[i], nil
+}
+
+// FormatDate formats t with layout, replacing the {MON} and {WEEKDAY} placeholders
+// with the month and weekday names of names (English if nil).
+func FormatDate(t time.Time, layout string, names *DateNames) string {
+	s := t.Format(layout)
+	if !strings.Contains(s, "{") {
+		return s
+	}
+	if names == nil {
+		names = &dateNames[0]
+	}
+	return strings.NewReplacer(
+		"{MON}", names.Months[t.Month()-1],
+		"{WEEKDAY}", names.Weekdays[t.Weekday()],
+	).Replace(s)
+}
+
+// LocalizedValTime is a ValTime formatted as FormatDate does, with its own format and locale.
+type LocalizedValTime struct {
+	ValTime
+}
+
+func (v LocalizedValTime) String() string {
+	if !v.Value.Valid || v.Value.Time.IsZero() || v.Value.Time.Year() < 0 {
+		return v.ValTime.String()
+	}
+	if v.Quote {
+		return `"` + FormatDate(v.inLocation(), v.layout(), v.Locale) + `"`
+	}
+	return FormatDate(v.inLocation(), v.layout(), v.Locale)
+}
+func (v LocalizedValTime) StringRaw() string {
+	if !v.Value.Valid || v.Value.Time.IsZero() || v.Value.Time.Year() < 0 {
+		return v.ValTime.StringRaw()
+	}
+	return FormatDate(v.inLocation(), v.layout(), v.Locale)
+}
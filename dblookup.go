@@ -0,0 +1,90 @@
+// Copyright 2021 Tamás Gulácsi.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package dbcsv
+
+import (
+	"container/list"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// QueryRower is the interface of *sql.DB, *sql.Conn and *sql.Tx used by NewDBLookupColumn.
+type QueryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// NewDBLookupColumn returns the name column, the result of the query (such as "SELECT value FROM ref_table WHERE key = :1")
+// executed with the raw string of the index-th column as its only parameter, or NULL if the query returns no rows.
+//
+// The results are cached for the last cacheSize distinct values (LRU); NULLs are not looked up.
+// Most drivers (except godror) cannot query on the connection of the open rows,
+// so db should be another connection (such as a *sql.Conn of its own).
+func NewDBLookupColumn(ctx context.Context, db QueryRower, name string, index int, qry string, cacheSize int) ComputedColumn {
+	cache := newLRUCache(cacheSize)
+	return ComputedColumn{
+		Column: Column{Name: name, Type: typeOfString},
+		Compute: func(values []interface{}) (interface{}, error) {
+			key, ok := formatValue(values[index])
+			if !ok {
+				return nil, nil
+			}
+			result, ok := cache.Get(key)
+			if !ok {
+				if err := db.QueryRowContext(ctx, qry, key).Scan(&result); err != nil && !errors.Is(err, sql.ErrNoRows) {
+					return nil, fmt.Errorf("%s [%q]: %w", qry, key, err)
+				}
+				cache.Add(key, result)
+			}
+			if !result.Valid {
+				return nil, nil
+			}
+			return result.String, nil
+		},
+	}
+}
+
+// lruCache is a string → sql.NullString cache, evicting the least recently used entries above its size.
+type lruCache struct {
+	size int
+	ll   *list.List
+	m    map[string]*list.Element
+}
+
+type lruEntry struct {
+	key   string
+	value sql.NullString
+}
+
+func newLRUCache(size int) *lruCache {
+	if size < 1 {
+		size = 1
+	}
+	return &lruCache{size: size, ll: list.New(), m: make(map[string]*list.Element)}
+}
+
+func (c *lruCache) Get(key string) (sql.NullString, bool) {
+	e, ok := c.m[key]
+	if !ok {
+		return sql.NullString{}, false
+	}
+	c.ll.MoveToFront(e)
+	return e.Value.(*lruEntry).value, true
+}
+
+func (c *lruCache) Add(key string, value sql.NullString) {
+	if e, ok := c.m[key]; ok {
+		e.Value.(*lruEntry).value = value
+		c.ll.MoveToFront(e)
+		return
+	}
+	c.m[key] = c.ll.PushFront(&lruEntry{key: key, value: value})
+	if c.ll.Len() > c.size {
+		e := c.ll.Back()
+		c.ll.Remove(e)
+		delete(c.m, e.Value.(*lruEntry).key)
+	}
+}
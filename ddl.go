@@ -0,0 +1,90 @@
+// Copyright 2021 Tamás Gulácsi.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package dbcsv
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ddlTypes are the SQL types of int, float, time, bool, []byte and string columns, per dialect.
+var ddlTypes = map[string][6]string{
+	"oracle":     {"NUMBER(19)", "BINARY_DOUBLE", "DATE", "NUMBER(1)", "BLOB", "VARCHAR2(4000)"},
+	"postgresql": {"BIGINT", "DOUBLE PRECISION", "TIMESTAMP", "BOOLEAN", "BYTEA", "TEXT"},
+	"mysql":      {"BIGINT", "DOUBLE", "DATETIME", "BOOLEAN", "LONGBLOB", "TEXT"},
+	"sqlite":     {"INTEGER", "REAL", "TEXT", "INTEGER", "BLOB", "TEXT"},
+	"bigquery":   {"INT64", "FLOAT64", "TIMESTAMP", "BOOL", "BYTES", "STRING"},
+}
+
+// CreateTableDDL returns the CREATE TABLE statement of a table with the columns,
+// in the SQL dialect (oracle, postgresql, mysql, sqlite or bigquery).
+func CreateTableDDL(table string, columns []Column, dialect string, primaryKey []string) (string, error) {
+	types, ok := ddlTypes[dialect]
+	if !ok {
+		return "", fmt.Errorf("unknown dialect %q (only oracle, postgresql, mysql, sqlite and bigquery)", dialect)
+	}
+	var buf strings.Builder
+	buf.WriteString("CREATE TABLE " + table + " (")
+	for i, col := range columns {
+		if i != 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteString("\n  " + col.Name + " " + types[ddlTypeIndex(col.Type)])
+		if col.NotNull {
+			buf.WriteString(" NOT NULL")
+		}
+	}
+	if len(primaryKey) != 0 {
+		for _, pk := range primaryKey {
+			var found bool
+			for _, col := range columns {
+				if found = strings.EqualFold(col.Name, pk); found {
+					break
+				}
+			}
+			if !found {
+				return "", fmt.Errorf("%s: unknown primary key column", pk)
+			}
+		}
+		buf.WriteString(",\n  PRIMARY KEY (" + strings.Join(primaryKey, ", ") + ")")
+		if dialect == "bigquery" {
+			buf.WriteString(" NOT ENFORCED")
+		}
+	}
+	buf.WriteString("\n);\n")
+	return buf.String(), nil
+}
+
+func ddlTypeIndex(typ reflect.Type) int {
+	if typ == nil {
+		return 5
+	}
+	switch typ {
+	case typeOfTime, typeOfNullTime:
+		return 2
+	case reflect.TypeOf(sql.NullInt64{}), reflect.TypeOf(sql.NullInt32{}):
+		return 0
+	case reflect.TypeOf(sql.NullFloat64{}):
+		return 1
+	case typeOfNullBool:
+		return 3
+	}
+	switch typ.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return 0
+	case reflect.Float32, reflect.Float64:
+		return 1
+	case reflect.Bool:
+		return 3
+	case reflect.Slice:
+		if typ.Elem().Kind() == reflect.Uint8 {
+			return 4
+		}
+	}
+	return 5
+}
@@ -0,0 +1,96 @@
+// Copyright 2021 Tamás Gulácsi.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package dbcsv
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// DecimalMinScale is the scale above which the NUMBER and DECIMAL columns are written
+// with ValDecimal, as float64 is precise only to about 15 digits.
+const DecimalMinScale = 15
+
+// isDecimal reports whether the column needs ValDecimal.
+func (col Column) isDecimal() bool {
+	switch strings.ToUpper(col.DatabaseTypeName) {
+	case "NUMBER", "DECIMAL", "NUMERIC":
+		return col.Scale > DecimalMinScale
+	}
+	return false
+}
+
+// ValDecimal is an arbitrary precision decimal number, written without exponent.
+type ValDecimal struct {
+	Value big.Rat
+	Valid bool
+	Sep   string
+}
+
+func (v ValDecimal) String() string { return csvQuoteString(v.Sep, v.StringRaw()) }
+func (v ValDecimal) StringRaw() string {
+	if !v.Valid {
+		return ""
+	}
+	s := v.Value.FloatString(decimalDigits(v.Value.Denom()))
+	if strings.IndexByte(s, '.') >= 0 {
+		s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	}
+	return s
+}
+func (v *ValDecimal) Pointer() interface{} { return v }
+func (v *ValDecimal) Scan(x interface{}) error {
+	v.Valid = x != nil
+	switch x := x.(type) {
+	case nil:
+	case int64:
+		v.Value.SetInt64(x)
+	case float64:
+		// the shortest decimal, not the exact binary fraction
+		if _, ok := v.Value.SetString(strconv.FormatFloat(x, 'f', -1, 64)); !ok {
+			return fmt.Errorf("%v is not a finite number", x)
+		}
+	case []byte:
+		if _, ok := v.Value.SetString(string(x)); !ok {
+			return fmt.Errorf("%q is not a number", x)
+		}
+	default:
+		// strings and string-based types such as godror.Number
+		s := fmt.Sprint(x)
+		if _, ok := v.Value.SetString(s); !ok {
+			return fmt.Errorf("%q is not a number", s)
+		}
+	}
+	return nil
+}
+
+// decimalDigits returns the number of fraction digits needed to write 1/denom exactly,
+// or 38 (the maximum precision of NUMBER) if it is not a finite decimal.
+func decimalDigits(denom *big.Int) int {
+	d := new(big.Int).Set(denom)
+	var m big.Int
+	var twos, fives int
+	for two := big.NewInt(2); d.Sign() != 0; twos++ {
+		if m.Mod(d, two); m.Sign() != 0 {
+			break
+		}
+		d.Quo(d, two)
+	}
+	for five := big.NewInt(5); d.Sign() != 0; fives++ {
+		if m.Mod(d, five); m.Sign() != 0 {
+			break
+		}
+		d.Quo(d, five)
+	}
+	if d.Cmp(big.NewInt(1)) != 0 {
+		return 38
+	}
+	if twos > fives {
+		return twos
+	}
+	return fives
+}
@@ -0,0 +1,148 @@
+// Copyright 2021 Tamás Gulácsi.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package dbcsv
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DOTOptions are the column mappings of DumpDOT.
+type DOTOptions struct {
+	// SourceColumn and TargetColumn are the columns of the edge endpoints.
+	SourceColumn, TargetColumn string
+	// LabelColumn is the column of the edge label, may be empty.
+	LabelColumn string
+	// WeightColumn is the column of the (integer) edge weight, may be empty.
+	WeightColumn string
+	// Undirected writes a graph instead of a digraph.
+	Undirected bool
+	// DumperOptions format the values (see Column.Converter).
+	DumperOptions
+}
+
+// DumpDOT writes the rows as a Graphviz DOT graph to w: each row is an edge from the source to the target,
+// and the nodes are the distinct source and target values. Rows with NULL target only declare the source node,
+// rows with NULL source are skipped.
+func DumpDOT(ctx context.Context, w io.Writer, rows Rows, columns []Column, opts DOTOptions, Log func(...interface{}) error) (int, error) {
+	colIndex := func(name string) (int, error) {
+		if name == "" {
+			return -1, nil
+		}
+		for i, c := range columns {
+			if strings.EqualFold(c.Name, name) {
+				return i, nil
+			}
+		}
+		return -1, fmt.Errorf("%s: unknown column", name)
+	}
+	src, err := colIndex(opts.SourceColumn)
+	if err != nil {
+		return 0, err
+	}
+	dst, err := colIndex(opts.TargetColumn)
+	if err != nil {
+		return 0, err
+	}
+	if src < 0 || dst < 0 {
+		return 0, fmt.Errorf("source and target columns are required")
+	}
+	label, err := colIndex(opts.LabelColumn)
+	if err != nil {
+		return 0, err
+	}
+	weight, err := colIndex(opts.WeightColumn)
+	if err != nil {
+		return 0, err
+	}
+
+	dest := make([]interface{}, len(columns))
+	values := make([]Stringer, len(columns))
+	for i, col := range columns {
+		c := col.Converter(opts.DumperOptions)
+		values[i] = c
+		dest[i] = c.Pointer()
+	}
+	bw := bufio.NewWriterSize(w, 65536)
+	defer bw.Flush()
+	graph, edge := "digraph", " -> "
+	if opts.Undirected {
+		graph, edge = "graph", " -- "
+	}
+	bw.WriteString(graph + " {\n")
+	nodes := make(map[string]struct{})
+	node := func(s string) string {
+		id := dotID(s)
+		if _, ok := nodes[s]; !ok {
+			nodes[s] = struct{}{}
+			bw.WriteString("  " + id + ";\n")
+		}
+		return id
+	}
+	start := time.Now()
+	n := 0
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return n, fmt.Errorf("scan into %#v: %w", dest, err)
+		}
+		n++
+		if IsNull(values[src]) {
+			continue
+		}
+		from := node(StringRaw(values[src]))
+		if IsNull(values[dst]) {
+			continue
+		}
+		to := node(StringRaw(values[dst]))
+		var attrs []string
+		if label >= 0 && !IsNull(values[label]) {
+			attrs = append(attrs, "label="+dotID(StringRaw(values[label])))
+		}
+		if weight >= 0 {
+			switch x := TypedValue(values[weight]).(type) {
+			case nil:
+			case int64:
+				attrs = append(attrs, "weight="+strconv.FormatInt(x, 10))
+			case float64:
+				attrs = append(attrs, "weight="+strconv.FormatFloat(math.Round(x), 'f', 0, 64))
+			default:
+				return n, fmt.Errorf("%d. row: weight %q is not a number", n, StringRaw(values[weight]))
+			}
+		}
+		bw.WriteString("  " + from + edge + to)
+		if len(attrs) != 0 {
+			bw.WriteString(" [" + strings.Join(attrs, ", ") + "]")
+		}
+		if _, err := bw.WriteString(";\n"); err != nil {
+			return n, err
+		}
+		if err := ctx.Err(); err != nil {
+			return n, err
+		}
+	}
+	err = rows.Err()
+	dur := time.Since(start)
+	if Log != nil {
+		_ = Log("msg", "dump finished", "rows", n, "dur", dur, "speed", float64(n)/float64(dur)*float64(time.Second), "error", err)
+	}
+	if err != nil {
+		return n, err
+	}
+	if _, err := bw.WriteString("}\n"); err != nil {
+		return n, err
+	}
+	return n, bw.Flush()
+}
+
+var dotReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", "")
+
+// dotID returns s as a double quoted DOT ID.
+func dotID(s string) string { return `"` + dotReplacer.Replace(s) + `"` }
@@ -0,0 +1,121 @@
+// Copyright 2021 Tamás Gulácsi.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package dbcsv dumps database query results (or any Rows) as CSV or spreadsheets,
+// and reads CSV and spreadsheet files - the library behind csvdump, csvload and friends.
+package dbcsv
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/text/message"
+)
+
+// DumperOptions are the options of the Dumper, and of the Converter of the columns.
+// The zero value writes the dates as DefaultDateFormat, the booleans as true and false,
+// and the binary values as base64.
+type DumperOptions struct {
+	// Sep is the field separator of the CSV output.
+	Sep string
+	// Header writes the column names as the first CSV line.
+	Header bool
+	// Raw writes the raw values, without separators and quoting.
+	Raw bool
+	// TSV writes the raw values separated by tabs, with the backslashes, tabs and newlines escaped
+	// (as \\, \t, \n and \r) instead of quoting, as PostgreSQL's text COPY format. Sep is ignored.
+	TSV bool
+	// DateFormat is the layout of the dates (in Go notation), DefaultDateFormat if empty;
+	// {MON} and {WEEKDAY} are replaced by the month and weekday names of DateLocale (English if nil).
+	// DateEnd is the string of the dates with negative years (the end of times).
+	DateFormat, DateEnd string
+	DateLocale          *DateNames
+	// DateFormats are the date formats of the columns (by name, case insensitive), overriding DateFormat.
+	DateFormats map[string]string
+	// Location (if not nil) is the time zone the dates are converted into.
+	Location *time.Location
+	// NumberPrinter (if not nil) formats the numbers by the conventions of its locale
+	// (grouping, decimal separator, digits).
+	NumberPrinter *message.Printer
+	// BoolTrue and BoolFalse are the strings of the booleans, "true" and "false" if empty.
+	BoolTrue, BoolFalse string
+	// NullValue is written for the NULLs in the CSV; empty by default.
+	NullValue string
+	// MaxColWidth (if positive) is the maximum number of characters of the string and LOB values,
+	// the longer ones are truncated and MaxColWidthSuffix (such as "…") is appended.
+	MaxColWidth       int
+	MaxColWidthSuffix string
+	// LobMax (if positive) is the maximum number of bytes read from a LOB.
+	LobMax int64
+	// BlobEncoding is the encoding of the binary values: base64 (if empty), hex or skip (writes an empty cell).
+	BlobEncoding string
+	// ProgressInterval (if positive) is the interval of the progress reports (rows=N elapsed=T speed=R)
+	// of DumpCSV and DumpSheet, written to ProgressWriter (os.Stderr if nil).
+	ProgressInterval time.Duration
+	ProgressWriter   io.Writer
+}
+
+// DefaultDateFormat is the format of the dates if DumperOptions.DateFormat is empty.
+const DefaultDateFormat = "2006-01-02"
+
+// dateFormat returns the date format of the named column.
+func (opts DumperOptions) dateFormat(name string) string {
+	if f, ok := opts.DateFormats[name]; ok {
+		return f
+	}
+	for k, f := range opts.DateFormats {
+		if strings.EqualFold(k, name) {
+			return f
+		}
+	}
+	return opts.DateFormat
+}
+
+// withDefaults returns opts with the empty fields set to their defaults.
+func (opts DumperOptions) withDefaults() DumperOptions {
+	if opts.DateFormat == "" {
+		opts.DateFormat = DefaultDateFormat
+	}
+	if opts.BoolTrue == "" {
+		opts.BoolTrue = "true"
+	}
+	if opts.BoolFalse == "" {
+		opts.BoolFalse = "false"
+	}
+	if opts.BlobEncoding == "" {
+		opts.BlobEncoding = "base64"
+	}
+	if opts.ProgressWriter == nil {
+		opts.ProgressWriter = os.Stderr
+	}
+	return opts
+}
+
+// Dumper dumps rows with its DumperOptions, logging to Log (if not nil).
+// Its methods - as the Dump functions, but DumpCSV and DumpSheet - return the number of rows dumped.
+//
+// It is the embeddable counterpart of csvdump:
+//
+//	rows, err := db.QueryContext(ctx, qry)
+//	...
+//	n, err := dbcsv.Dumper{DumperOptions: dbcsv.DumperOptions{Sep: ";", Header: true}}.DumpRows(ctx, os.Stdout, rows)
+type Dumper struct {
+	Log func(...interface{}) error
+	DumperOptions
+}
+
+// DumpRows writes the rows as CSV to w, with the columns from GetColumns, and closes the rows.
+// It returns the number of rows written.
+func (d Dumper) DumpRows(ctx context.Context, w io.Writer, rows *sql.Rows) (int, error) {
+	defer rows.Close()
+	columns, err := GetColumns(rows)
+	if err != nil {
+		return 0, err
+	}
+	return d.DumpCSV(ctx, w, rows, columns)
+}
@@ -0,0 +1,54 @@
+// Copyright 2021 Tamás Gulácsi.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package dbcsv
+
+// ForwardFillStringer is a Stringer that writes the last non-NULL value instead of NULLs
+// (an empty string before the first non-NULL value).
+type ForwardFillStringer struct {
+	Stringer
+	last *string
+	Sep  string
+}
+
+// NewForwardFillWrapper returns a StringerWrapper that wraps with a ForwardFillStringer.
+// Each call of the wrapper (column) has its own last value.
+func NewForwardFillWrapper() StringerWrapper {
+	return func(s Stringer, sep string) Stringer {
+		return &ForwardFillStringer{Stringer: s, last: new(string), Sep: sep}
+	}
+}
+
+func (f ForwardFillStringer) String() string { return csvQuoteString(f.Sep, f.StringRaw()) }
+func (f ForwardFillStringer) StringRaw() string {
+	if IsNull(f.Stringer) {
+		return *f.last
+	}
+	*f.last = StringRaw(f.Stringer)
+	return *f.last
+}
+
+// BackFill reads all the rows into memory, and returns them with the NULLs of the named columns
+// replaced by the next non-NULL value. Trailing NULLs remain NULL.
+func BackFill(rows Rows, columns []Column, names ...string) (Rows, error) {
+	indexes, err := columnIndexes(columns, names)
+	if err != nil {
+		return nil, err
+	}
+	buffered, err := bufferRows(rows, columns)
+	if err != nil {
+		return nil, err
+	}
+	for _, i := range indexes {
+		var next interface{}
+		for j := len(buffered) - 1; j >= 0; j-- {
+			if v := buffered[j][i]; v != nil {
+				next = v
+			} else {
+				buffered[j][i] = next
+			}
+		}
+	}
+	return &replayRows{Rows: rows, buffered: buffered}, nil
+}
@@ -0,0 +1,61 @@
+// Copyright 2021 Tamás Gulácsi.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package dbcsv_test
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/UNO-SOFT/dbcsv"
+)
+
+func TestBackFillLob(t *testing.T) {
+	// the LOBs are read from io.Readers, as *godror.Lob with godror.LobAsReader
+	columns := []dbcsv.Column{
+		{Name: "TXT", Type: typeOfString, DatabaseTypeName: "CLOB"},
+		{Name: "BIN", Type: reflect.TypeOf([]byte(nil)), DatabaseTypeName: "BLOB"},
+	}
+	rows := &sliceRows{values: [][]interface{}{
+		{strings.NewReader("first, long"), bytes.NewReader([]byte{0xff, 0})},
+		{nil, nil},
+		{strings.NewReader("ár"), bytes.NewReader([]byte("x"))},
+	}}
+	filled, err := dbcsv.BackFill(rows, columns, "TXT", "BIN")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err = dbcsv.DumpCSV(context.Background(), &buf, filled, columns, true, ",", false, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), "TXT,BIN\n\"first, long\",/wA=\nár,eA==\nár,eA==\n"; got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+}
+
+func TestImputeStatsNumber(t *testing.T) {
+	// godror returns the NUMBER columns as strings
+	columns := []dbcsv.Column{
+		{Name: "N", Type: typeOfString, DatabaseTypeName: "NUMBER"},
+		{Name: "M", Type: typeOfString, DatabaseTypeName: "NUMBER"},
+		{Name: "S", Type: typeOfString},
+	}
+	newRows := func() *sliceRows {
+		return &sliceRows{values: [][]interface{}{{"1", "1.5", "a"}, {nil, nil, nil}, {"4", "2", "b"}, {"10", "-1", "c"}}}
+	}
+	_, values, err := dbcsv.ImputeStats(newRows(), columns, map[int]string{0: dbcsv.ImputeMedian, 1: dbcsv.ImputeMean})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[int]string{0: "4", 1: "0.8333333333333334"}; !reflect.DeepEqual(values, want) {
+		t.Errorf("got %v, wanted %v", values, want)
+	}
+	if _, _, err = dbcsv.ImputeStats(newRows(), columns, map[int]string{2: dbcsv.ImputeMean}); err == nil {
+		t.Error("wanted error for a string column")
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright 2021 Tamás Gulácsi.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package dbcsv
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	_ "modernc.org/sqlite" // sqlite driver for FilterSQL
+)
+
+// FilterSQL loads all the rows into an in-memory SQLite table (named "t", with the same columns),
+// and returns the rows matching the SQLite WHERE expression, in the original order.
+//
+// The values are loaded raw, without the Wrappers of the columns.
+func FilterSQL(ctx context.Context, rows Rows, columns []Column, where string) (Rows, []Column, error) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		return nil, nil, err
+	}
+	// every connection would get a new in-memory database
+	db.SetMaxOpenConns(1)
+	fr, err := filterSQL(ctx, db, rows, columns, where)
+	if err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+	return &filteredRows{Rows: fr, db: db}, columns, nil
+}
+
+func filterSQL(ctx context.Context, db *sql.DB, rows Rows, columns []Column, where string) (*sql.Rows, error) {
+	const batchSize = 1000
+	names := make([]string, len(columns))
+	defs := make([]string, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i, col := range columns {
+		names[i] = `"` + strings.ReplaceAll(col.Name, `"`, `""`) + `"`
+		c := col.scanConverter()
+		dest[i] = c.Pointer()
+		typ := "TEXT"
+		switch c.(type) {
+		case *ValInt, *ValUint, *ValBool:
+			typ = "INTEGER"
+		case *ValFloat:
+			typ = "REAL"
+		case *ValTime:
+			typ = "TIMESTAMP"
+		case *ValBlob:
+			typ = "BLOB"
+		}
+		defs[i] = names[i] + " " + typ
+	}
+	qry := "CREATE TABLE t (" + strings.Join(defs, ", ") + ")"
+	if _, err := db.ExecContext(ctx, qry); err != nil {
+		return nil, fmt.Errorf("%s: %w", qry, err)
+	}
+	qry = "INSERT INTO t (" + strings.Join(names, ", ") + ") VALUES (" + strings.Repeat(",?", len(names))[1:] + ")"
+	var tx *sql.Tx
+	var stmt *sql.Stmt
+	values := make([]interface{}, len(dest))
+	for n := 0; rows.Next(); n++ {
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		if tx == nil {
+			var err error
+			if tx, err = db.BeginTx(ctx, nil); err != nil {
+				return nil, err
+			}
+			if stmt, err = tx.PrepareContext(ctx, qry); err != nil {
+				tx.Rollback()
+				return nil, fmt.Errorf("%s: %w", qry, err)
+			}
+		}
+		for i, d := range dest {
+			values[i] = replayValue(d)
+		}
+		if _, err := stmt.ExecContext(ctx, values...); err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("%s %v: %w", qry, values, err)
+		}
+		if n%batchSize == batchSize-1 {
+			stmt.Close()
+			if err := tx.Commit(); err != nil {
+				return nil, err
+			}
+			tx, stmt = nil, nil
+		}
+	}
+	if tx != nil {
+		stmt.Close()
+		if err := tx.Commit(); err != nil {
+			return nil, err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	qry = "SELECT " + strings.Join(names, ", ") + " FROM t WHERE " + where + " ORDER BY rowid" //nolint:gas
+	fr, err := db.QueryContext(ctx, qry)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", qry, err)
+	}
+	return fr, nil
+}
+
+type filteredRows struct {
+	*sql.Rows
+	db *sql.DB
+}
+
+func (fr *filteredRows) Close() error {
+	err := fr.Rows.Close()
+	if closeErr := fr.db.Close(); closeErr != nil && err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,144 @@
+// Copyright 2021 Tamás Gulácsi.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package dbcsv
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// FixedOptions are the options of DumpFixed.
+type FixedOptions struct {
+	// Widths are the widths of the columns (in characters); if empty, the width of the longest value
+	// (or column name, with Header) of each column, which needs all the rows to be kept in memory.
+	Widths []int
+	// DumperOptions format the values; Sep is written between the columns,
+	// and the column names are written first with Header.
+	DumperOptions
+}
+
+// fixedReplacer replaces the characters which would break the fixed width lines.
+var fixedReplacer = strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ", "\t", " ")
+
+// DumpFixed writes the rows as fixed width columns to w: the numbers right-aligned,
+// everything else left-aligned, padded with spaces (NULLs are all spaces).
+// The longer values than the given Widths are truncated, the newlines and tabs are replaced by spaces.
+func DumpFixed(ctx context.Context, w io.Writer, rows Rows, columns []Column, opts FixedOptions, Log func(...interface{}) error) (int, error) {
+	widths := opts.Widths
+	if len(widths) != 0 && len(widths) != len(columns) {
+		return 0, fmt.Errorf("got %d widths for %d columns", len(widths), len(columns))
+	}
+	conv := opts.DumperOptions
+	conv.Sep = ""
+	dest := make([]interface{}, len(columns))
+	values := make([]Stringer, len(columns))
+	right := make([]bool, len(columns))
+	for i, col := range columns {
+		c := col.Converter(conv)
+		values[i] = c
+		dest[i] = c.Pointer()
+		switch c.(type) {
+		case *ValInt, *LocalizedValInt, *ValUint, *ValFloat, *LocalizedValFloat, *ValDecimal:
+			right[i] = true
+		}
+	}
+
+	bw := bufio.NewWriterSize(w, 65536)
+	defer bw.Flush()
+	writeRecord := func(rec []string) error {
+		for i, s := range rec {
+			if i != 0 {
+				bw.WriteString(opts.Sep)
+			}
+			n := utf8.RuneCountInString(s)
+			if n > widths[i] {
+				s = string([]rune(s)[:widths[i]])
+				n = widths[i]
+			}
+			pad := strings.Repeat(" ", widths[i]-n)
+			if right[i] {
+				bw.WriteString(pad)
+				bw.WriteString(s)
+			} else {
+				bw.WriteString(s)
+				bw.WriteString(pad)
+			}
+		}
+		return bw.WriteByte('\n')
+	}
+	var header []string
+	if opts.Header {
+		header = make([]string, len(columns))
+		for i, col := range columns {
+			header[i] = fixedReplacer.Replace(col.Name)
+		}
+	}
+	// buffer the records to measure the widths
+	var records [][]string
+	if len(widths) == 0 {
+		widths = make([]int, len(columns))
+		for i, s := range header {
+			widths[i] = utf8.RuneCountInString(s)
+		}
+	} else if header != nil {
+		if err := writeRecord(header); err != nil {
+			return 0, err
+		}
+	}
+
+	start := time.Now()
+	n := 0
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return n, fmt.Errorf("scan into %#v: %w", dest, err)
+		}
+		rec := make([]string, len(values))
+		for i, v := range values {
+			if !IsNull(v) {
+				rec[i] = fixedReplacer.Replace(StringRaw(v))
+			}
+		}
+		n++
+		if len(opts.Widths) != 0 {
+			if err := writeRecord(rec); err != nil {
+				return n, err
+			}
+		} else {
+			for i, s := range rec {
+				if k := utf8.RuneCountInString(s); k > widths[i] {
+					widths[i] = k
+				}
+			}
+			records = append(records, rec)
+		}
+		if err := ctx.Err(); err != nil {
+			return n, err
+		}
+	}
+	err := rows.Err()
+	if err == nil && len(opts.Widths) == 0 {
+		if header != nil {
+			records = append([][]string{header}, records...)
+		}
+		for _, rec := range records {
+			if err = writeRecord(rec); err != nil {
+				break
+			}
+		}
+	}
+	if flushErr := bw.Flush(); flushErr != nil && err == nil {
+		err = flushErr
+	}
+	dur := time.Since(start)
+	if Log != nil {
+		_ = Log("msg", "dump finished", "rows", n, "dur", dur, "speed", float64(n)/float64(dur)*float64(time.Second), "error", err)
+	}
+	return n, err
+}
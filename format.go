@@ -0,0 +1,481 @@
+// Copyright 2021 Tamás Gulácsi.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package dbcsv
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/text/encoding"
+)
+
+// NormalizeIP returns the canonical form of the IP address (or CIDR) s:
+// IPv4-mapped IPv6 addresses are converted to IPv4, leading zeros are removed.
+//
+// version 4 or 6 forces that representation, 0 keeps the natural one.
+func NormalizeIP(s string, version int) (string, error) {
+	s = strings.TrimSpace(s)
+	addr, prefix := s, ""
+	if i := strings.IndexByte(s, '/'); i >= 0 {
+		addr, prefix = s[:i], s[i+1:]
+	}
+	if !strings.Contains(addr, ":") {
+		// net.ParseIP refuses leading zeros in IPv4 addresses
+		parts := strings.Split(addr, ".")
+		if len(parts) == 4 {
+			for i, p := range parts {
+				if n, err := strconv.ParseUint(p, 10, 8); err == nil {
+					parts[i] = strconv.FormatUint(n, 10)
+				}
+			}
+			addr = strings.Join(parts, ".")
+		}
+	}
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return s, fmt.Errorf("%q: not an IP address", s)
+	}
+	// bits is the prefix length difference between the input and the output
+	var bits int
+	isV4 := ip.To4() != nil
+	inputV6 := strings.Contains(addr, ":")
+	switch version {
+	case 0:
+		if isV4 {
+			addr = ip.To4().String()
+			if inputV6 {
+				bits = -96
+			}
+		} else {
+			addr = ip.String()
+		}
+	case 4:
+		if !isV4 {
+			return s, fmt.Errorf("%q: not an IPv4 address", s)
+		}
+		addr = ip.To4().String()
+		if inputV6 {
+			bits = -96
+		}
+	case 6:
+		if isV4 {
+			addr = "::ffff:" + ip.To4().String()
+			if !inputV6 {
+				bits = 96
+			}
+		} else {
+			addr = ip.String()
+		}
+	default:
+		return s, fmt.Errorf("unknown IP version %d", version)
+	}
+	if prefix == "" {
+		return addr, nil
+	}
+	n, err := strconv.Atoi(prefix)
+	if err != nil || n+bits < 0 {
+		return s, fmt.Errorf("%q: bad prefix length %q", s, prefix)
+	}
+	return addr + "/" + strconv.Itoa(n+bits), nil
+}
+
+// ConvertBase converts the integer s from the fromBase to the toBase (2, 8, 10 or 16) representation.
+// A 0x (0b, 0o) prefix of s matching fromBase is accepted; with prefix, 0x and 0b is prepended
+// to hexadecimal and binary outputs.
+func ConvertBase(s string, fromBase, toBase int, prefix bool) (string, error) {
+	switch toBase {
+	case 2, 8, 10, 16:
+	default:
+		return s, fmt.Errorf("unsupported base %d (only 2, 8, 10 and 16)", toBase)
+	}
+	t := strings.TrimSpace(s)
+	var neg bool
+	if strings.HasPrefix(t, "-") {
+		neg, t = true, t[1:]
+	}
+	if len(t) > 2 && t[0] == '0' {
+		switch {
+		case fromBase == 16 && (t[1] == 'x' || t[1] == 'X'),
+			fromBase == 8 && (t[1] == 'o' || t[1] == 'O'),
+			fromBase == 2 && (t[1] == 'b' || t[1] == 'B'):
+			t = t[2:]
+		}
+	}
+	n, err := strconv.ParseUint(t, fromBase, 64)
+	if err != nil {
+		return s, fmt.Errorf("%q: %w", s, err)
+	}
+	out := strconv.FormatUint(n, toBase)
+	if prefix {
+		switch toBase {
+		case 16:
+			out = "0x" + out
+		case 2:
+			out = "0b" + out
+		}
+	}
+	if neg {
+		out = "-" + out
+	}
+	return out, nil
+}
+
+// NewBaseConvertWrapper returns a StringerWrapper converting the values with ConvertBase.
+// ValInt values are always read as decimal.
+func NewBaseConvertWrapper(fromBase, toBase int, prefix bool, name string) StringerWrapper {
+	return func(s Stringer, sep string) Stringer {
+		from := fromBase
+		if _, ok := s.(*ValInt); ok {
+			from = 10
+		}
+		return &MapStringer{Stringer: s, Sep: sep, Map: func(v string) string {
+			t, err := ConvertBase(v, from, toBase, prefix)
+			if err != nil {
+				log.Printf("[WARN] %s: %+v", name, err)
+			}
+			return t
+		}}
+	}
+}
+
+var (
+	siPrefixes  = []string{"", "k", "M", "G", "T", "P", "E"}
+	iecPrefixes = []string{"", "Ki", "Mi", "Gi", "Ti", "Pi", "Ei"}
+)
+
+// formatSI formats v with precision decimals and the SI magnitude prefix (k, M, G...),
+// or with binary, the IEC prefix (Ki, Mi, Gi...) of the powers of 1024.
+// Integers below 1000 (1024) are written without decimals.
+func formatSI(v float64, binary bool, precision int) string {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	base, prefixes := 1000.0, siPrefixes
+	if binary {
+		base, prefixes = 1024, iecPrefixes
+	}
+	a := math.Abs(v)
+	if a < base && a == math.Trunc(a) {
+		return strconv.FormatFloat(v, 'f', 0, 64)
+	}
+	a, i := siScale(a, base, len(prefixes)-1, precision)
+	if v < 0 {
+		a = -a
+	}
+	return strconv.FormatFloat(a, 'f', precision, 64) + prefixes[i]
+}
+
+// siScale divides a by base at most maxExp times, while it is at least base (after rounding to precision),
+// and returns the quotient and the number of divisions.
+func siScale(a, base float64, maxExp, precision int) (float64, int) {
+	var i int
+	for ; i < maxExp && a >= base; i++ {
+		a /= base
+	}
+	// rounding may reach the next magnitude (999.999k => 1000.00k => 1.00M)
+	pow := math.Pow(10, float64(precision))
+	if math.Round(a*pow)/pow >= base && i < maxExp {
+		a /= base
+		i++
+	}
+	return a, i
+}
+
+var (
+	fileSizeUnits       = []string{"kB", "MB", "GB", "TB", "PB", "EB"}
+	binaryFileSizeUnits = []string{"KiB", "MiB", "GiB", "TiB", "PiB", "EiB"}
+)
+
+// formatFileSize formats the byte count v as a human-readable size with 2 decimals, such as 1.23 GB,
+// or with binary, with the powers of 1024, such as 1.15 GiB. Sizes below 1 kB (KiB) are written in bytes.
+func formatFileSize(v int64, binary bool) string {
+	base, units := 1000.0, fileSizeUnits
+	if binary {
+		base, units = 1024, binaryFileSizeUnits
+	}
+	a := math.Abs(float64(v))
+	if a < base {
+		if v == 1 || v == -1 {
+			return strconv.FormatInt(v, 10) + " byte"
+		}
+		return strconv.FormatInt(v, 10) + " bytes"
+	}
+	a, i := siScale(a, base, len(units), 2)
+	if v < 0 {
+		a = -a
+	}
+	return strconv.FormatFloat(a, 'f', 2, 64) + " " + units[i-1]
+}
+
+// NewFileSizeWrapper returns a StringerWrapper that writes the integers (byte counts)
+// with formatFileSize, such as 1.23 GB (or with binary, 1.15 GiB). Other values are written as is.
+func NewFileSizeWrapper(binary bool) StringerWrapper {
+	return func(s Stringer, sep string) Stringer {
+		return &MapStringer{Stringer: s, Sep: sep, Map: func(v string) string {
+			if x, ok := TypedValue(s).(int64); ok {
+				return formatFileSize(x, binary)
+			}
+			return v
+		}}
+	}
+}
+
+// NewSIFormatWrapper returns a StringerWrapper that writes the integers and floats
+// with SI (or with binary, IEC) magnitude prefixes, with precision decimals, such as 1.50k or 2.00Mi.
+// Other values are written as is.
+func NewSIFormatWrapper(binary bool, precision int) StringerWrapper {
+	return func(s Stringer, sep string) Stringer {
+		return &MapStringer{Stringer: s, Sep: sep, Map: func(v string) string {
+			switch x := TypedValue(s).(type) {
+			case int64:
+				return formatSI(float64(x), binary, precision)
+			case float64:
+				return formatSI(x, binary, precision)
+			}
+			return v
+		}}
+	}
+}
+
+// NewPercentWrapper returns a StringerWrapper that writes the numbers multiplied by 100
+// (unless alreadyScaled) with places decimals, followed by %, such as 12.34% for 0.1234.
+// Other values are written as is, NULLs as empty strings.
+func NewPercentWrapper(places int, alreadyScaled bool) StringerWrapper {
+	return func(s Stringer, sep string) Stringer {
+		return &MapStringer{Stringer: s, Sep: sep, Map: func(v string) string {
+			var f float64
+			switch x := TypedValue(s).(type) {
+			case int64:
+				f = float64(x)
+			case float64:
+				f = x
+			default:
+				return v
+			}
+			if !alreadyScaled {
+				f *= 100
+			}
+			return strconv.FormatFloat(f, 'f', places, 64) + "%"
+		}}
+	}
+}
+
+// NewScientificWrapper returns a StringerWrapper that writes the floats (and integers) in scientific notation
+// with prec decimals (-1 means the fewest needed), such as 1.23e+06 (or with upper, 1.23E+06).
+// With threshold > 0, only the values whose absolute value is above threshold or below 1/threshold
+// are written so, the rest is written as is.
+func NewScientificWrapper(prec int, upper bool, threshold float64) StringerWrapper {
+	fmtByte := byte('e')
+	if upper {
+		fmtByte = 'E'
+	}
+	return func(s Stringer, sep string) Stringer {
+		return &MapStringer{Stringer: s, Sep: sep, Map: func(v string) string {
+			var f float64
+			switch x := TypedValue(s).(type) {
+			case int64:
+				f = float64(x)
+			case float64:
+				f = x
+			default:
+				return v
+			}
+			if threshold > 0 {
+				if a := math.Abs(f); a <= threshold && (a == 0 || a >= 1/threshold) {
+					return v
+				}
+			}
+			return strconv.FormatFloat(f, fmtByte, prec, 64)
+		}}
+	}
+}
+
+// NewBoolLabelsWrapper returns a StringerWrapper that writes trueLabel for the true booleans and
+// the 1, Y, T and TRUE (case-insensitive) values, and falseLabel for any other non-NULL value.
+func NewBoolLabelsWrapper(trueLabel, falseLabel string) StringerWrapper {
+	return func(s Stringer, sep string) Stringer {
+		return &MapStringer{Stringer: s, Sep: sep, Map: func(v string) string {
+			if b, ok := TypedValue(s).(bool); ok {
+				v = strconv.FormatBool(b)
+			}
+			switch strings.ToUpper(strings.TrimSpace(v)) {
+			case "1", "Y", "T", "TRUE":
+				return trueLabel
+			}
+			return falseLabel
+		}}
+	}
+}
+
+// DurationUnits are the units of NewDurationWrapper, by name.
+var DurationUnits = map[string]time.Duration{
+	"ns": time.Nanosecond, "us": time.Microsecond, "µs": time.Microsecond, "ms": time.Millisecond,
+	"s": time.Second, "min": time.Minute, "h": time.Hour,
+}
+
+var durationComponents = []struct {
+	Unit time.Duration
+	Name string
+}{
+	{time.Hour, "h"}, {time.Minute, "m"}, {time.Second, "s"},
+	{time.Millisecond, "ms"}, {time.Microsecond, "us"}, {time.Nanosecond, "ns"},
+}
+
+// FormatDuration formats d as its non-zero components, such as 1h23m45s or 1s500ms.
+// With precision > 0, only the first precision components are written (1h23m for 1h23m45s),
+// the rest is truncated.
+func FormatDuration(d time.Duration, precision int) string {
+	if d == 0 {
+		return "0s"
+	}
+	var buf strings.Builder
+	u := uint64(d)
+	if d < 0 {
+		buf.WriteByte('-')
+		// -d overflows for math.MinInt64, the unsigned negation does not
+		u = -u
+	}
+	var n int
+	for _, c := range durationComponents {
+		if precision > 0 && n == precision {
+			break
+		}
+		k := u / uint64(c.Unit)
+		if k == 0 {
+			if n != 0 {
+				// the components are counted from the first non-zero one
+				n++
+			}
+			continue
+		}
+		buf.WriteString(strconv.FormatUint(k, 10))
+		buf.WriteString(c.Name)
+		u -= k * uint64(c.Unit)
+		n++
+	}
+	return buf.String()
+}
+
+// NewDurationWrapper returns a StringerWrapper that writes the integers and floats
+// as durations (with FormatDuration) of unit, such as 1h23m45s. Other values are written as is.
+func NewDurationWrapper(unit time.Duration, precision int) StringerWrapper {
+	return func(s Stringer, sep string) Stringer {
+		return &MapStringer{Stringer: s, Sep: sep, Map: func(v string) string {
+			switch x := TypedValue(s).(type) {
+			case int64:
+				return FormatDuration(time.Duration(x)*unit, precision)
+			case float64:
+				return FormatDuration(time.Duration(math.Round(x*float64(unit))), precision)
+			}
+			return v
+		}}
+	}
+}
+
+// CaseWhenStringer is a Stringer that substitutes the values found in Cases,
+// and writes Default for NULLs and for the values not in Cases (like SQL's DECODE).
+type CaseWhenStringer struct {
+	Stringer
+	Cases      map[string]string
+	Default    string
+	IgnoreCase bool
+	Sep        string
+}
+
+// NewCaseWhenWrapper returns a StringerWrapper that wraps with a CaseWhenStringer.
+// With ignoreCase, the values are matched case-insensitively.
+func NewCaseWhenWrapper(cases map[string]string, def string, ignoreCase bool) StringerWrapper {
+	if ignoreCase {
+		lower := make(map[string]string, len(cases))
+		for k, v := range cases {
+			lower[strings.ToLower(k)] = v
+		}
+		cases = lower
+	}
+	return func(s Stringer, sep string) Stringer {
+		return &CaseWhenStringer{Stringer: s, Cases: cases, Default: def, IgnoreCase: ignoreCase, Sep: sep}
+	}
+}
+
+func (c CaseWhenStringer) String() string { return csvQuoteString(c.Sep, c.StringRaw()) }
+func (c CaseWhenStringer) StringRaw() string {
+	if IsNull(c.Stringer) {
+		return c.Default
+	}
+	k := StringRaw(c.Stringer)
+	if c.IgnoreCase {
+		k = strings.ToLower(k)
+	}
+	if v, ok := c.Cases[k]; ok {
+		return v
+	}
+	return c.Default
+}
+
+// EmojiMapStringer is a Stringer that substitutes the values found in Map (such as OK → ✅),
+// and writes Default for NULLs and for the values not in Map - or the original value if there is no Default.
+type EmojiMapStringer struct {
+	Stringer
+	Map     map[string]string
+	Default string
+	Sep     string
+}
+
+// NewEmojiMapWrapper returns a StringerWrapper that wraps with an EmojiMapStringer.
+func NewEmojiMapWrapper(m map[string]string, def string) StringerWrapper {
+	return func(s Stringer, sep string) Stringer {
+		return &EmojiMapStringer{Stringer: s, Map: m, Default: def, Sep: sep}
+	}
+}
+
+func (e EmojiMapStringer) String() string { return csvQuoteString(e.Sep, e.StringRaw()) }
+func (e EmojiMapStringer) StringRaw() string {
+	if IsNull(e.Stringer) {
+		return e.Default
+	}
+	k := StringRaw(e.Stringer)
+	if v, ok := e.Map[k]; ok {
+		return v
+	}
+	if e.Default != "" {
+		return e.Default
+	}
+	return k
+}
+
+// EncodingConvertStringer is a Stringer that decodes the raw string of the wrapped Stringer
+// from Encoding to UTF-8. Undecodable values are written as is.
+type EncodingConvertStringer struct {
+	Stringer
+	Encoding encoding.Encoding
+	Name     string
+	Sep      string
+}
+
+// NewEncodingConvertWrapper returns a StringerWrapper that wraps with an EncodingConvertStringer using enc.
+func NewEncodingConvertWrapper(enc encoding.Encoding, name string) StringerWrapper {
+	return func(s Stringer, sep string) Stringer {
+		return &EncodingConvertStringer{Stringer: s, Encoding: enc, Name: name, Sep: sep}
+	}
+}
+
+func (e EncodingConvertStringer) String() string { return csvQuoteString(e.Sep, e.StringRaw()) }
+func (e EncodingConvertStringer) StringRaw() string {
+	if IsNull(e.Stringer) {
+		return ""
+	}
+	s := StringRaw(e.Stringer)
+	t, err := e.Encoding.NewDecoder().String(s)
+	if err != nil {
+		log.Printf("[WARN] %s: decode %q: %+v", e.Name, s, err)
+		return s
+	}
+	return t
+}
@@ -0,0 +1,195 @@
+// Copyright 2021 Tamás Gulácsi.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package dbcsv_test
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/UNO-SOFT/dbcsv"
+)
+
+// wrapped returns the raw string of the int64 or float64 x, wrapped by w.
+func wrapped(t *testing.T, w dbcsv.StringerWrapper, x interface{}) string {
+	t.Helper()
+	var c interface {
+		dbcsv.Stringer
+		Scan(interface{}) error
+	}
+	switch x.(type) {
+	case int64:
+		c = &dbcsv.ValInt{}
+	default:
+		c = &dbcsv.ValFloat{}
+	}
+	if err := c.Scan(x); err != nil {
+		t.Fatal(err)
+	}
+	return dbcsv.StringRaw(w(c, ""))
+}
+
+func TestSIFormat(t *testing.T) {
+	for _, tc := range []struct {
+		In     interface{}
+		Binary bool
+		Prec   int
+		Want   string
+	}{
+		{int64(0), false, 2, "0"},
+		{int64(999), false, 2, "999"},
+		{int64(-999), false, 2, "-999"},
+		{int64(1000), false, 2, "1.00k"},
+		{int64(1500), false, 1, "1.5k"},
+		{int64(-2500000), false, 2, "-2.50M"},
+		{999999.0, false, 2, "1.00M"},
+		{1.5, false, 2, "1.50"},
+		{int64(1023), true, 2, "1023"},
+		{int64(1024), true, 2, "1.00Ki"},
+		{int64(3 << 20), true, 0, "3Mi"},
+		{math.Inf(1), false, 2, "+Inf"},
+	} {
+		if got := wrapped(t, dbcsv.NewSIFormatWrapper(tc.Binary, tc.Prec), tc.In); got != tc.Want {
+			t.Errorf("%v (binary=%t, precision=%d): got %q, wanted %q", tc.In, tc.Binary, tc.Prec, got, tc.Want)
+		}
+	}
+}
+
+func TestFileSize(t *testing.T) {
+	for _, tc := range []struct {
+		In     int64
+		Binary bool
+		Want   string
+	}{
+		{0, false, "0 bytes"},
+		{1, false, "1 byte"},
+		{-1, false, "-1 byte"},
+		{999, false, "999 bytes"},
+		{1000, false, "1.00 kB"},
+		{1234567, false, "1.23 MB"},
+		{-1234567890, false, "-1.23 GB"},
+		{999999, false, "1.00 MB"},
+		{1023, true, "1023 bytes"},
+		{1024, true, "1.00 KiB"},
+		{1234567890, true, "1.15 GiB"},
+		{math.MaxInt64, false, "9.22 EB"},
+	} {
+		if got := wrapped(t, dbcsv.NewFileSizeWrapper(tc.Binary), tc.In); got != tc.Want {
+			t.Errorf("%d (binary=%t): got %q, wanted %q", tc.In, tc.Binary, got, tc.Want)
+		}
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	for _, tc := range []struct {
+		In   time.Duration
+		Prec int
+		Want string
+	}{
+		{0, 0, "0s"},
+		{time.Nanosecond, 0, "1ns"},
+		{1500 * time.Millisecond, 0, "1s500ms"},
+		{time.Hour + 23*time.Minute + 45*time.Second, 0, "1h23m45s"},
+		{time.Hour + 23*time.Minute + 45*time.Second, 2, "1h23m"},
+		{time.Hour + 45*time.Second, 2, "1h"},
+		{-90 * time.Second, 0, "-1m30s"},
+		{math.MaxInt64, 0, "2562047h47m16s854ms775us807ns"},
+		{math.MinInt64, 0, "-2562047h47m16s854ms775us808ns"},
+		{math.MinInt64, 1, "-2562047h"},
+	} {
+		if got := dbcsv.FormatDuration(tc.In, tc.Prec); got != tc.Want {
+			t.Errorf("%d (precision=%d): got %q, wanted %q", int64(tc.In), tc.Prec, got, tc.Want)
+		}
+	}
+}
+
+func TestNormalizeIP(t *testing.T) {
+	for _, tc := range []struct {
+		In      string
+		Version int
+		Want    string
+		Err     bool
+	}{
+		{In: "192.168.001.010", Want: "192.168.1.10"},
+		{In: " 10.0.0.1 ", Want: "10.0.0.1"},
+		{In: "::ffff:10.0.0.1", Want: "10.0.0.1"},
+		{In: "::FFFF:10.0.0.1/120", Want: "10.0.0.1/24"},
+		{In: "2001:0DB8:0000::0001", Want: "2001:db8::1"},
+		{In: "010.0.0.0/8", Version: 6, Want: "::ffff:10.0.0.0/104"},
+		{In: "::ffff:10.0.0.1", Version: 4, Want: "10.0.0.1"},
+		{In: "2001:db8::1", Version: 4, Err: true},
+		{In: "10.0.0.1/x", Err: true},
+		{In: "::ffff:10.0.0.0/8", Err: true},
+		{In: "not an ip", Err: true},
+		{In: "10.0.0.1", Version: 5, Err: true},
+	} {
+		got, err := dbcsv.NormalizeIP(tc.In, tc.Version)
+		if err != nil {
+			if !tc.Err {
+				t.Errorf("%q (v%d): %+v", tc.In, tc.Version, err)
+			}
+			continue
+		}
+		if tc.Err {
+			t.Errorf("%q (v%d): wanted error, got %q", tc.In, tc.Version, got)
+		} else if got != tc.Want {
+			t.Errorf("%q (v%d): got %q, wanted %q", tc.In, tc.Version, got, tc.Want)
+		}
+	}
+}
+
+func TestConvertBase(t *testing.T) {
+	for _, tc := range []struct {
+		In       string
+		From, To int
+		Prefix   bool
+		Want     string
+		Err      bool
+	}{
+		{In: "255", From: 10, To: 16, Want: "ff"},
+		{In: "255", From: 10, To: 16, Prefix: true, Want: "0xff"},
+		{In: "0xFF", From: 16, To: 10, Want: "255"},
+		{In: "ff", From: 16, To: 2, Prefix: true, Want: "0b11111111"},
+		{In: "0b101", From: 2, To: 8, Want: "5"},
+		{In: "0o17", From: 8, To: 10, Prefix: true, Want: "15"},
+		{In: " -10 ", From: 10, To: 16, Prefix: true, Want: "-0xa"},
+		{In: "18446744073709551615", From: 10, To: 16, Want: "ffffffffffffffff"},
+		{In: "0x10", From: 10, To: 16, Err: true},
+		{In: "12", From: 2, To: 10, Err: true},
+		{In: "12", From: 10, To: 3, Err: true},
+	} {
+		got, err := dbcsv.ConvertBase(tc.In, tc.From, tc.To, tc.Prefix)
+		if err != nil {
+			if !tc.Err {
+				t.Errorf("%q (%d->%d): %+v", tc.In, tc.From, tc.To, err)
+			}
+			continue
+		}
+		if tc.Err {
+			t.Errorf("%q (%d->%d): wanted error, got %q", tc.In, tc.From, tc.To, got)
+		} else if got != tc.Want {
+			t.Errorf("%q (%d->%d): got %q, wanted %q", tc.In, tc.From, tc.To, got, tc.Want)
+		}
+	}
+}
+
+func TestDumpNQuads(t *testing.T) {
+	columns := []dbcsv.Column{{Name: "ID", Type: typeOfString}, {Name: "X", Type: reflect.TypeOf(float64(0))}}
+	rows := &sliceRows{values: [][]interface{}{{"a\tb", 1.5}, {"c", math.NaN()}, {"d", math.Inf(-1)}}}
+	var buf bytes.Buffer
+	if _, err := dbcsv.DumpNQuads(context.Background(), &buf, rows, columns,
+		dbcsv.NQuadsOptions{SubjectColumn: "ID", PredicatePrefix: "urn:x:"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	const double = "^^<http://www.w3.org/2001/XMLSchema#double> .\n"
+	if got, want := buf.String(), `<urn:x:a%09b> <urn:x:X> "1.5E+00"`+double+
+		`<urn:x:c> <urn:x:X> "NaN"`+double+
+		`<urn:x:d> <urn:x:X> "-INF"`+double; got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+}
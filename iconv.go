@@ -0,0 +1,86 @@
+// Copyright 2021 Tamás Gulácsi.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package dbcsv
+
+import (
+	"database/sql"
+	"fmt"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/ianaindex"
+	"golang.org/x/text/transform"
+)
+
+// IconvRows converts the scanned values of string columns from one character set to another.
+type IconvRows struct {
+	Rows
+	Columns []Column
+	// transformers by column index
+	transformers map[int]transform.Transformer
+}
+
+// NewIconvRows returns an IconvRows wrapping rows, without conversions yet.
+func NewIconvRows(rows Rows, columns []Column) *IconvRows {
+	return &IconvRows{Rows: rows, Columns: columns, transformers: make(map[int]transform.Transformer)}
+}
+
+// Add the conversion of the index-th (string) column from the from charset to the to charset (IANA names).
+func (ir *IconvRows) Add(index int, from, to string) error {
+	col := ir.Columns[index]
+	switch col.scanConverter().(type) {
+	case *ValString, *ValLob:
+	default:
+		return fmt.Errorf("%s: iconv needs a string column", col.Name)
+	}
+	fromEnc, err := ianaEncoding(from)
+	if err != nil {
+		return fmt.Errorf("%s: %w", col.Name, err)
+	}
+	toEnc, err := ianaEncoding(to)
+	if err != nil {
+		return fmt.Errorf("%s: %w", col.Name, err)
+	}
+	ir.transformers[index] = transform.Chain(fromEnc.NewDecoder(), toEnc.NewEncoder())
+	return nil
+}
+
+func ianaEncoding(name string) (encoding.Encoding, error) {
+	enc, err := ianaindex.IANA.Encoding(name)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	if enc == nil {
+		return nil, fmt.Errorf("%s: unsupported charset", name)
+	}
+	return enc, nil
+}
+
+func (ir *IconvRows) Scan(dest ...interface{}) error {
+	if err := ir.Rows.Scan(dest...); err != nil {
+		return err
+	}
+	for i, t := range ir.transformers {
+		var p *string
+		switch x := dest[i].(type) {
+		case *sql.NullString:
+			if x.Valid {
+				p = &x.String
+			}
+		case *ValLob:
+			if x.Valid {
+				p = &x.Value
+			}
+		}
+		if p == nil {
+			continue
+		}
+		s, _, err := transform.String(t, *p)
+		if err != nil {
+			return fmt.Errorf("%s: iconv %q: %w", ir.Columns[i].Name, *p, err)
+		}
+		*p = s
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+// Copyright 2021 Tamás Gulácsi.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package dbcsv
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Imputation strategies. Any other strategy is used as a literal value.
+const (
+	ImputeMean   = "mean"
+	ImputeMedian = "median"
+	ImputeMode   = "mode"
+	ImputeZero   = "zero"
+	ImputeEmpty  = "empty"
+)
+
+// ImputeNeedsStats reports whether the strategy needs the statistics of the column (see ImputeStats).
+func ImputeNeedsStats(strategy string) bool {
+	switch strategy {
+	case ImputeMean, ImputeMedian, ImputeMode:
+		return true
+	}
+	return false
+}
+
+// ImputeValue returns the value of the single-pass strategies: "0" for zero, "" for empty, and the literal otherwise.
+func ImputeValue(strategy string) (string, error) {
+	switch strategy {
+	case ImputeZero:
+		return "0", nil
+	case ImputeEmpty:
+		return "", nil
+	}
+	if ImputeNeedsStats(strategy) {
+		return "", fmt.Errorf("%s imputation needs the statistics of the column", strategy)
+	}
+	return strategy, nil
+}
+
+// ImputeStringer is a Stringer that writes Value instead of NULLs.
+type ImputeStringer struct {
+	Stringer
+	Value string
+	Sep   string
+}
+
+// NewImputeWrapper returns a StringerWrapper that wraps with an ImputeStringer using value.
+func NewImputeWrapper(value string) StringerWrapper {
+	return func(s Stringer, sep string) Stringer { return &ImputeStringer{Stringer: s, Value: value, Sep: sep} }
+}
+
+func (m ImputeStringer) String() string { return csvQuoteString(m.Sep, m.StringRaw()) }
+func (m ImputeStringer) StringRaw() string {
+	if IsNull(m.Stringer) {
+		return m.Value
+	}
+	return StringRaw(m.Stringer)
+}
+
+// ImputeStats reads all the rows into memory, and returns the imputed values of the mean, median or mode
+// strategies (by column index) computed from the non-NULL values, and the rows to replay them.
+//
+// mean and median needs numeric columns (their numeric strings, such as the NUMBER columns', are parsed), mode is the most frequent value (the first one on ties).
+// The value of a column without any non-NULL value is "".
+func ImputeStats(rows Rows, columns []Column, strategies map[int]string) (Rows, map[int]string, error) {
+	buffered, err := bufferRows(rows, columns)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	values := make(map[int]string, len(strategies))
+	for i, strategy := range strategies {
+		if values[i], err = imputeStat(buffered, i, strategy, columns[i].isNumericString()); err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", columns[i].Name, err)
+		}
+	}
+	return &replayRows{Rows: rows, buffered: buffered}, values, nil
+}
+
+func imputeStat(buffered [][]interface{}, index int, strategy string, numeric bool) (string, error) {
+	if strategy == ImputeMode {
+		counts := make(map[string]int)
+		var mode string
+		var max int
+		for _, vals := range buffered {
+			s, ok := formatValue(vals[index])
+			if !ok {
+				continue
+			}
+			counts[s]++
+			if n := counts[s]; n > max {
+				mode, max = s, n
+			}
+		}
+		return mode, nil
+	}
+
+	nums := make([]float64, 0, len(buffered))
+	allInt := true
+	for _, vals := range buffered {
+		switch x := vals[index].(type) {
+		case nil:
+		case int64:
+			nums = append(nums, float64(x))
+		case float64:
+			nums = append(nums, x)
+			allInt = false
+		case string:
+			f, err := strconv.ParseFloat(x, 64)
+			if !numeric || err != nil {
+				return "", fmt.Errorf("%s needs numbers, got %q", strategy, x)
+			}
+			nums = append(nums, f)
+			allInt = allInt && !strings.ContainsAny(x, ".eE")
+		default:
+			return "", fmt.Errorf("%s needs numbers, got %v (%T)", strategy, x, x)
+		}
+	}
+	if len(nums) == 0 {
+		return "", nil
+	}
+	var f float64
+	switch strategy {
+	case ImputeMean:
+		for _, x := range nums {
+			f += x
+		}
+		f /= float64(len(nums))
+	case ImputeMedian:
+		sort.Float64s(nums)
+		if n := len(nums); n%2 == 1 {
+			f = nums[n/2]
+		} else {
+			f = (nums[n/2-1] + nums[n/2]) / 2
+			allInt = allInt && f == float64(int64(f))
+		}
+	default:
+		return "", fmt.Errorf("unknown statistics %q", strategy)
+	}
+	if strategy == ImputeMedian && allInt {
+		return strconv.FormatInt(int64(f), 10), nil
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64), nil
+}
+
+// bufferRows reads all the rows into memory, as ScannedValues.
+func bufferRows(rows Rows, columns []Column) ([][]interface{}, error) {
+	dest := make([]interface{}, len(columns))
+	for i, col := range columns {
+		dest[i] = col.scanConverter().Pointer()
+	}
+	var buffered [][]interface{}
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		vals := make([]interface{}, len(dest))
+		for i, d := range dest {
+			vals[i] = replayValue(d)
+		}
+		buffered = append(buffered, vals)
+	}
+	return buffered, rows.Err()
+}
+
+// replayRows returns the buffered values.
+type replayRows struct {
+	Rows
+	buffered [][]interface{}
+	i        int
+}
+
+func (rr *replayRows) Next() bool {
+	if rr.i < len(rr.buffered) {
+		rr.i++
+		return true
+	}
+	rr.buffered = nil
+	return false
+}
+func (rr *replayRows) Err() error { return nil }
+func (rr *replayRows) Scan(dest ...interface{}) error {
+	vals := rr.buffered[rr.i-1]
+	for j, d := range dest {
+		scanner, ok := d.(sql.Scanner)
+		if !ok {
+			return fmt.Errorf("%d. column: cannot scan into %T", j, d)
+		}
+		if err := scanner.Scan(vals[j]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
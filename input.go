@@ -0,0 +1,387 @@
+// Copyright 2021 Tamás Gulácsi.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package dbcsv
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/360EntSecGroup-Skylar/excelize/v2"
+)
+
+// InputDateFormats are the layouts tried when inferring date columns of string records.
+var InputDateFormats = []string{"2006-01-02", time.RFC3339, "2006-01-02T15:04:05", "2006-01-02 15:04:05"}
+
+var (
+	typeOfInt64   = reflect.TypeOf(int64(0))
+	typeOfFloat64 = reflect.TypeOf(float64(0))
+	typeOfString  = reflect.TypeOf("")
+)
+
+// StringRows is Rows over string records (such as the rows of a spreadsheet),
+// converting the values to the types of the columns. Empty strings are NULLs.
+type StringRows struct {
+	records [][]string
+	columns []Column
+	i       int
+}
+
+// NewStringRows returns StringRows for the records, using the first record as the column names,
+// inferring the types of the columns from the values.
+func NewStringRows(records [][]string) (*StringRows, []Column) {
+	if len(records) == 0 {
+		return &StringRows{}, nil
+	}
+	header, records := records[0], records[1:]
+	columns := make([]Column, len(header))
+	for j, nm := range header {
+		columns[j] = Column{Name: nm, Type: inferType(records, j)}
+	}
+	return &StringRows{records: records, columns: columns}, columns
+}
+
+// inferType returns the most specific type (int64, float64, time.Time or string)
+// that all the non-empty values of the j-th column can be parsed as.
+func inferType(records [][]string, j int) reflect.Type {
+	isInt, isFloat, isDate := true, true, true
+	var seen bool
+	for _, rec := range records {
+		if j >= len(rec) || rec[j] == "" {
+			continue
+		}
+		seen = true
+		s := rec[j]
+		if isInt {
+			_, err := strconv.ParseInt(s, 10, 64)
+			isInt = err == nil
+		}
+		if isFloat {
+			_, err := strconv.ParseFloat(s, 64)
+			isFloat = err == nil
+		}
+		if isDate {
+			_, err := ParseInputDate(s)
+			isDate = err == nil
+		}
+		if !(isInt || isFloat || isDate) {
+			break
+		}
+	}
+	switch {
+	case !seen:
+		return typeOfString
+	case isInt:
+		return typeOfInt64
+	case isFloat:
+		return typeOfFloat64
+	case isDate:
+		return typeOfTime
+	}
+	return typeOfString
+}
+
+// ParseInputDate parses s with the first matching layout of InputDateFormats.
+func ParseInputDate(s string) (time.Time, error) {
+	var firstErr error
+	for _, layout := range InputDateFormats {
+		t, err := time.Parse(layout, s)
+		if err == nil {
+			return t, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return time.Time{}, firstErr
+}
+
+// parseTyped parses s as the inferred type typ.
+func parseTyped(typ reflect.Type, s string) (interface{}, error) {
+	switch typ {
+	case typeOfInt64:
+		return strconv.ParseInt(s, 10, 64)
+	case typeOfFloat64:
+		return strconv.ParseFloat(s, 64)
+	case typeOfTime:
+		return ParseInputDate(s)
+	}
+	return s, nil
+}
+
+func (sr *StringRows) Next() bool   { sr.i++; return sr.i <= len(sr.records) }
+func (sr *StringRows) Err() error   { return nil }
+func (sr *StringRows) Close() error { sr.records = nil; return nil }
+func (sr *StringRows) Scan(dest ...interface{}) error {
+	rec := sr.records[sr.i-1]
+	for j, d := range dest {
+		var v interface{}
+		if j < len(rec) && rec[j] != "" {
+			v = rec[j]
+			if j < len(sr.columns) {
+				var err error
+				if v, err = parseTyped(sr.columns[j].Type, rec[j]); err != nil {
+					return fmt.Errorf("%d. row %q: %w", sr.i, sr.columns[j].Name, err)
+				}
+			}
+		}
+		scanner, ok := d.(sql.Scanner)
+		if !ok {
+			return fmt.Errorf("%d. column: cannot scan into %T", j, d)
+		}
+		if err := scanner.Scan(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadRows reads all the rows of the Sheet of the opened file,
+// and returns them as StringRows, with the first row as the header.
+//
+// The column types are inferred from the ODS cell value types, or from the values for other types.
+func (cfg *Config) LoadRows(ctx context.Context) (*StringRows, []Column, error) {
+	if cfg.typ.Type == Ods {
+		if err := cfg.Rewind(); err != nil {
+			return nil, nil, err
+		}
+		return loadODSRows(ctx, cfg.fileName, cfg.Sheet)
+	}
+	var records [][]string
+	if err := cfg.ReadRows(ctx, func(_ string, row Row) error {
+		records = append(records, append(make([]string, 0, len(row.Values)), row.Values...))
+		return nil
+	}); err != nil {
+		return nil, nil, err
+	}
+	rows, columns := NewStringRows(records)
+	return rows, columns, nil
+}
+
+// SheetIndex returns the index of the named sheet, usable as Sheet.
+func (cfg *Config) SheetIndex(ctx context.Context, name string) (int, error) {
+	if cfg.typ.Type == XlsX {
+		// ReadXLSXFile uses the position in the sheet list, not the sheet ID.
+		xlFile, err := excelize.OpenFile(cfg.fileName)
+		if err != nil {
+			return 0, err
+		}
+		list := xlFile.GetSheetList()
+		for i, nm := range list {
+			if strings.EqualFold(nm, name) {
+				return i, nil
+			}
+		}
+		return 0, fmt.Errorf("%s (only: %v): %w", name, list, ErrUnknownSheet)
+	}
+	sheets, err := cfg.ReadSheets(ctx)
+	if err != nil {
+		return 0, err
+	}
+	for i, nm := range sheets {
+		if strings.EqualFold(nm, name) {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("%s (only: %v): %w", name, sheets, ErrUnknownSheet)
+}
+
+// CommonDateLayouts are the date layouts tried by InferTypes with AutoDate.
+var CommonDateLayouts = []string{
+	"2006-01-02", "2006-01-02 15:04:05", "2006-01-02T15:04:05", time.RFC3339, "2006-01-02 15:04",
+	"2006-01-02 15:04:05.999999999", "2006/01/02", "2006.01.02", "2006.01.02.",
+	"02.01.2006", "02.01.2006 15:04:05", "02/01/2006", "02/01/2006 15:04:05", "02/01/2006 15:04",
+	"01/02/2006", "01/02/2006 15:04:05", "01/02/2006 15:04", "20060102", "02-Jan-2006", "02-Jan-06",
+	"Jan 2, 2006", "2 Jan 2006", time.RFC1123, time.RFC1123Z,
+}
+
+// InferOptions are the options of InferTypes.
+type InferOptions struct {
+	// Rows is the number of rows to read for the inference.
+	Rows int
+	// AutoDate promotes a string column to time.Time if all its values match the same layout
+	// of the CommonDateLayouts, instead of any of the InputDateFormats.
+	AutoDate bool
+	// TimestampFormat, if not empty, is the output format of the AutoDate columns whose layout has a time part.
+	TimestampFormat string
+	Log             func(...interface{}) error
+}
+
+// InferTypes reads the first opts.Rows rows, and infers the types of the string columns from their values
+// (int64, float64, time.Time or string, as NewStringRows).
+//
+// The returned Rows replays the read rows, then continues with the rest,
+// converting the values of the string columns to the inferred types.
+func InferTypes(rows Rows, columns []Column, opts InferOptions) (Rows, []Column, error) {
+	n := opts.Rows
+	ir := inferringRows{Rows: rows, columns: make([]Column, len(columns)), layouts: make([]string, len(columns))}
+	copy(ir.columns, columns)
+	ir.dest = make([]interface{}, len(columns))
+	for j, col := range columns {
+		c := col.scanConverter()
+		ir.dest[j] = c.Pointer()
+		if _, ok := c.(*ValString); ok {
+			ir.stringCols = append(ir.stringCols, j)
+		}
+	}
+	if len(ir.stringCols) == 0 {
+		return rows, columns, nil
+	}
+	records := make([][]string, 0, n)
+	for len(ir.buffered) < n && rows.Next() {
+		vals, err := ir.scan()
+		if err != nil {
+			return nil, nil, err
+		}
+		ir.buffered = append(ir.buffered, vals)
+		rec := make([]string, len(ir.stringCols))
+		for k, j := range ir.stringCols {
+			rec[k], _ = vals[j].(string)
+		}
+		records = append(records, rec)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+	for k, j := range ir.stringCols {
+		if opts.AutoDate {
+			if layout := detectDateLayout(records, k); layout != "" {
+				ir.columns[j].Type, ir.layouts[j] = typeOfTime, layout
+				if opts.Log != nil {
+					_ = opts.Log("msg", "date column detected", "column", ir.columns[j].Name, "layout", layout)
+				}
+				if opts.TimestampFormat != "" && strings.Contains(layout, "15") {
+					ir.columns[j].Wrappers = append(ir.columns[j].Wrappers, NewTimeFormatWrapper(opts.TimestampFormat))
+				}
+				continue
+			}
+		}
+		ir.columns[j].Type = inferType(records, k)
+	}
+	return &ir, ir.columns, nil
+}
+
+// detectDateLayout returns the first of the CommonDateLayouts all the non-empty values
+// of the j-th column can be parsed with, or "" if there is no such layout.
+func detectDateLayout(records [][]string, j int) string {
+	candidates := append(make([]string, 0, len(CommonDateLayouts)), CommonDateLayouts...)
+	var seen bool
+	for _, rec := range records {
+		if j >= len(rec) || rec[j] == "" {
+			continue
+		}
+		seen = true
+		kept := candidates[:0]
+		for _, layout := range candidates {
+			if _, err := time.Parse(layout, rec[j]); err == nil {
+				kept = append(kept, layout)
+			}
+		}
+		if candidates = kept; len(candidates) == 0 {
+			return ""
+		}
+	}
+	if !seen {
+		return ""
+	}
+	return candidates[0]
+}
+
+// TimeFormatStringer is a Stringer writing the time of the wrapped (ValTime) Stringer with Layout,
+// instead of its DumperOptions.DateFormat.
+type TimeFormatStringer struct {
+	Stringer
+	Layout, Sep string
+}
+
+// NewTimeFormatWrapper returns a StringerWrapper that wraps with a TimeFormatStringer using the layout.
+func NewTimeFormatWrapper(layout string) StringerWrapper {
+	return func(s Stringer, sep string) Stringer {
+		return &TimeFormatStringer{Stringer: s, Layout: layout, Sep: sep}
+	}
+}
+
+func (t TimeFormatStringer) String() string { return csvQuoteString(t.Sep, t.StringRaw()) }
+func (t TimeFormatStringer) StringRaw() string {
+	if v, ok := TypedValue(t.Stringer).(time.Time); ok {
+		return v.Format(t.Layout)
+	}
+	return StringRaw(t.Stringer)
+}
+
+type inferringRows struct {
+	Rows
+	columns []Column
+	// layouts are the detected date layouts of the columns (by AutoDate)
+	layouts    []string
+	stringCols []int
+	dest       []interface{}
+	buffered   [][]interface{}
+	i          int
+}
+
+// scan the underlying Rows, returning the ScannedValue of each column.
+func (ir *inferringRows) scan() ([]interface{}, error) {
+	if err := ir.Rows.Scan(ir.dest...); err != nil {
+		return nil, err
+	}
+	vals := make([]interface{}, len(ir.dest))
+	for j, d := range ir.dest {
+		vals[j] = replayValue(d)
+	}
+	return vals, nil
+}
+
+func (ir *inferringRows) Next() bool {
+	if ir.i < len(ir.buffered) {
+		ir.i++
+		return true
+	}
+	ir.buffered, ir.i = nil, 0
+	return ir.Rows.Next()
+}
+
+func (ir *inferringRows) Scan(dest ...interface{}) error {
+	var vals []interface{}
+	if ir.i != 0 {
+		vals = ir.buffered[ir.i-1]
+	} else {
+		var err error
+		if vals, err = ir.scan(); err != nil {
+			return err
+		}
+	}
+	for _, j := range ir.stringCols {
+		if s, ok := vals[j].(string); ok && ir.columns[j].Type != typeOfString {
+			if s == "" {
+				vals[j] = nil
+				continue
+			}
+			var err error
+			if layout := ir.layouts[j]; layout != "" {
+				vals[j], err = time.Parse(layout, s)
+			} else {
+				vals[j], err = parseTyped(ir.columns[j].Type, s)
+			}
+			if err != nil {
+				return fmt.Errorf("%q: %w", ir.columns[j].Name, err)
+			}
+		}
+	}
+	for j, d := range dest {
+		scanner, ok := d.(sql.Scanner)
+		if !ok {
+			return fmt.Errorf("%d. column: cannot scan into %T", j, d)
+		}
+		if err := scanner.Scan(vals[j]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,218 @@
+// Copyright 2021 Tamás Gulácsi.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package dbcsv
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+)
+
+// JSONValue returns the value of the Stringer (created with Converter(""))
+// suitable for json.Marshal: nil for NULL, numbers for ValInt and ValFloat, booleans for ValBool,
+// and strings for everything else.
+func JSONValue(v Stringer) interface{} {
+	if _, ok := TypedValue(v).(time.Time); ok {
+		return StringRaw(v)
+	}
+	return TypedValue(v)
+}
+
+// appendJSONObject appends the JSON object of the column names and values (in order) to buf,
+// leaving out the values for which omit (if not nil) returns true.
+func appendJSONObject(buf *bytes.Buffer, columns []Column, values []Stringer, omit func(Stringer) bool) error {
+	buf.WriteByte('{')
+	first := true
+	for i, v := range values {
+		if omit != nil && omit(v) {
+			continue
+		}
+		if !first {
+			buf.WriteByte(',')
+		}
+		first = false
+		b, err := json.Marshal(columns[i].Name)
+		if err != nil {
+			return err
+		}
+		buf.Write(b)
+		buf.WriteByte(':')
+		if ss, ok := v.(*SerializeStructStringer); ok && ss.Value != nil {
+			// embed as a nested object, not as a string
+			if b, err = json.Marshal(ss.Value); err == nil {
+				buf.Write(b)
+				continue
+			}
+		}
+		if b, err = json.Marshal(JSONValue(v)); err != nil {
+			return err
+		}
+		buf.Write(b)
+	}
+	buf.WriteByte('}')
+	return nil
+}
+
+// JSONOptions are the options of DumpJSON.
+type JSONOptions struct {
+	// Sparse leaves out the NULL values, and the values whose raw string is SparseDefault.
+	Sparse        bool
+	SparseDefault string
+	// DumperOptions format the values (see Column.Converter).
+	DumperOptions
+}
+
+// DumpJSON writes the rows as a JSON array of objects, with the column names as keys, to w,
+// one object per line. NULLs are null, numbers and booleans are written as such (see JSONValue).
+func DumpJSON(ctx context.Context, w io.Writer, rows Rows, columns []Column, opts JSONOptions, Log func(...interface{}) error) (int, error) {
+	dest := make([]interface{}, len(columns))
+	values := make([]Stringer, len(columns))
+	for i, col := range columns {
+		c := col.Converter(opts.DumperOptions)
+		values[i] = c
+		dest[i] = c.Pointer()
+	}
+	var omit func(Stringer) bool
+	if opts.Sparse {
+		omit = func(v Stringer) bool {
+			s, ok := formatValue(TypedValue(v))
+			return !ok || s == opts.SparseDefault
+		}
+	}
+	bw := bufio.NewWriterSize(w, 65536)
+	bw.WriteByte('[')
+	var buf bytes.Buffer
+	start := time.Now()
+	n := 0
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return n, fmt.Errorf("scan into %#v: %w", dest, err)
+		}
+		buf.Reset()
+		if n != 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteByte('\n')
+		if err := appendJSONObject(&buf, columns, values, omit); err != nil {
+			return n, err
+		}
+		if _, err := bw.Write(buf.Bytes()); err != nil {
+			return n, err
+		}
+		n++
+		if err := ctx.Err(); err != nil {
+			return n, err
+		}
+	}
+	err := rows.Err()
+	bw.WriteString("\n]\n")
+	if flushErr := bw.Flush(); flushErr != nil && err == nil {
+		err = flushErr
+	}
+	dur := time.Since(start)
+	if Log != nil {
+		_ = Log("msg", "dump finished", "rows", n, "dur", dur, "speed", float64(n)/float64(dur)*float64(time.Second), "error", err)
+	}
+	return n, err
+}
+
+// KeyValueJSONOptions are the options of DumpKeyValueJSON.
+type KeyValueJSONOptions struct {
+	// KeyColumn is the column of the keys.
+	KeyColumn string
+	// Merge writes one JSON object of all the rows, instead of one object per line (JSON Lines).
+	Merge bool
+	// DumperOptions format the values (see Column.Converter).
+	DumperOptions
+}
+
+// DumpKeyValueJSON writes the rows as {"KEY": {"COL2": VAL2, "COL3": VAL3, ...}} objects to w,
+// where KEY is the value of the KeyColumn, and the object holds all the other columns (as DumpJSON does).
+//
+// The objects are written one per line (JSON Lines), or, with Merge, as the members of one object
+// (the later duplicate keys win with most parsers). The rows with NULL key are skipped with a warning.
+func DumpKeyValueJSON(ctx context.Context, w io.Writer, rows Rows, columns []Column, opts KeyValueJSONOptions, Log func(...interface{}) error) (int, error) {
+	key := -1
+	for i, c := range columns {
+		if strings.EqualFold(c.Name, opts.KeyColumn) {
+			key = i
+			break
+		}
+	}
+	if key < 0 {
+		return 0, fmt.Errorf("%s: unknown key column", opts.KeyColumn)
+	}
+	dest := make([]interface{}, len(columns))
+	values := make([]Stringer, len(columns))
+	for i, col := range columns {
+		c := col.Converter(opts.DumperOptions)
+		values[i] = c
+		dest[i] = c.Pointer()
+	}
+	omit := func(v Stringer) bool { return v == values[key] }
+	bw := bufio.NewWriterSize(w, 65536)
+	if opts.Merge {
+		bw.WriteByte('{')
+	}
+	var buf bytes.Buffer
+	start := time.Now()
+	n, skipped := 0, 0
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return n, fmt.Errorf("scan into %#v: %w", dest, err)
+		}
+		if TypedValue(values[key]) == nil {
+			log.Printf("[WARN] %s: NULL key, skipping the row", columns[key].Name)
+			skipped++
+			continue
+		}
+		b, err := json.Marshal(StringRaw(values[key]))
+		if err != nil {
+			return n, err
+		}
+		buf.Reset()
+		if opts.Merge {
+			if n != 0 {
+				buf.WriteByte(',')
+			}
+			buf.WriteByte('\n')
+		} else {
+			buf.WriteByte('{')
+		}
+		buf.Write(b)
+		buf.WriteByte(':')
+		if err := appendJSONObject(&buf, columns, values, omit); err != nil {
+			return n, err
+		}
+		if !opts.Merge {
+			buf.WriteString("}\n")
+		}
+		if _, err := bw.Write(buf.Bytes()); err != nil {
+			return n, err
+		}
+		n++
+		if err := ctx.Err(); err != nil {
+			return n, err
+		}
+	}
+	err := rows.Err()
+	if opts.Merge {
+		bw.WriteString("\n}\n")
+	}
+	if flushErr := bw.Flush(); flushErr != nil && err == nil {
+		err = flushErr
+	}
+	dur := time.Since(start)
+	if Log != nil {
+		_ = Log("msg", "dump finished", "rows", n, "skipped", skipped, "dur", dur, "speed", float64(n)/float64(dur)*float64(time.Second), "error", err)
+	}
+	return n, err
+}
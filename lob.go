@@ -0,0 +1,67 @@
+// Copyright 2021 Tamás Gulácsi.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package dbcsv
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// isLob reports whether the column needs ValLob.
+func (col Column) isLob() bool {
+	switch strings.ToUpper(col.DatabaseTypeName) {
+	case "CLOB", "NCLOB":
+		return true
+	}
+	return false
+}
+
+// ValLob is the (at most Max bytes long, if positive, cut at a character boundary) content of a LOB column,
+// scanned from a string, []byte or an io.Reader (such as *godror.Lob, with godror.LobAsReader).
+type ValLob struct {
+	Value string
+	Valid bool
+	Sep   string
+	// MaxWidth (if positive) is the maximum number of characters written, followed by Suffix if truncated.
+	MaxWidth int
+	Suffix   string
+	Max      int64
+}
+
+func (v ValLob) String() string        { return csvQuoteString(v.Sep, v.StringRaw()) }
+func (v ValLob) StringRaw() string     { return truncateString(v.Value, v.MaxWidth, v.Suffix) }
+func (v *ValLob) Pointer() interface{} { return v }
+func (v *ValLob) Scan(x interface{}) error {
+	v.Value, v.Valid = "", x != nil
+	switch x := x.(type) {
+	case nil:
+	case string:
+		v.Value = x
+	case []byte:
+		v.Value = string(x)
+	case io.Reader:
+		if v.Max > 0 {
+			// the byte after Max tells whether Max splits a character
+			x = io.LimitReader(x, v.Max+1)
+		}
+		var buf strings.Builder
+		if _, err := io.Copy(&buf, x); err != nil {
+			return fmt.Errorf("read LOB: %w", err)
+		}
+		v.Value = buf.String()
+	default:
+		return fmt.Errorf("unknown LOB type %T", x)
+	}
+	if v.Max > 0 && int64(len(v.Value)) > v.Max {
+		i := int(v.Max)
+		for i > 0 && !utf8.RuneStart(v.Value[i]) {
+			i--
+		}
+		v.Value = v.Value[:i]
+	}
+	return nil
+}
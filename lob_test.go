@@ -0,0 +1,38 @@
+// Copyright 2021 Tamás Gulácsi.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package dbcsv_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/UNO-SOFT/dbcsv"
+)
+
+func TestValLobMax(t *testing.T) {
+	for _, tc := range []struct {
+		In   string
+		Max  int64
+		Want string
+	}{
+		{"árvíz", 0, "árvíz"},
+		{"árvíz", 1, ""},
+		{"árvíz", 2, "á"},
+		{"árvíz", 3, "ár"},
+		{"árvíz", 5, "árv"},
+		{"árvíz", 7, "árvíz"},
+		{"€uro", 2, ""},
+	} {
+		for _, x := range []interface{}{tc.In, strings.NewReader(tc.In)} {
+			v := dbcsv.ValLob{Max: tc.Max}
+			if err := v.Scan(x); err != nil {
+				t.Fatal(err)
+			}
+			if v.Value != tc.Want {
+				t.Errorf("%q[:%d] (%T): got %q, wanted %q", tc.In, tc.Max, x, v.Value, tc.Want)
+			}
+		}
+	}
+}
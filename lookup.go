@@ -0,0 +1,85 @@
+// Copyright 2021 Tamás Gulácsi.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package dbcsv
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ReadLookup reads the keyCol → valueCol map from the CSV (with header) of r, separated by comma.
+// With ignoreCase, the keys are lower cased (see NewLookupColumn).
+// The column names are matched case-insensitively; for duplicate keys the first value wins.
+func ReadLookup(r io.Reader, comma rune, keyCol, valueCol string, ignoreCase bool) (map[string]string, error) {
+	cr := csv.NewReader(r)
+	cr.Comma = comma
+	cr.FieldsPerRecord = -1
+	cr.ReuseRecord = true
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read header: %w", err)
+	}
+	key, value := -1, -1
+	for i, nm := range header {
+		if key < 0 && strings.EqualFold(nm, keyCol) {
+			key = i
+		}
+		if value < 0 && strings.EqualFold(nm, valueCol) {
+			value = i
+		}
+	}
+	if key < 0 {
+		return nil, fmt.Errorf("%s: unknown column (have %q)", keyCol, header)
+	}
+	if value < 0 {
+		return nil, fmt.Errorf("%s: unknown column (have %q)", valueCol, header)
+	}
+	m := make(map[string]string)
+	for {
+		rec, err := cr.Read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return m, nil
+			}
+			return m, err
+		}
+		if key >= len(rec) {
+			continue
+		}
+		k := rec[key]
+		if ignoreCase {
+			k = strings.ToLower(k)
+		}
+		if _, ok := m[k]; ok {
+			continue
+		}
+		if value < len(rec) {
+			m[k] = rec[value]
+		} else {
+			m[k] = ""
+		}
+	}
+}
+
+// NewLookupColumn returns the name column, the value of the index-th column's raw string in lookup,
+// or the empty string if it is not there (or NULL). With ignoreCase the keys of lookup must be lower case.
+func NewLookupColumn(name string, index int, lookup map[string]string, ignoreCase bool) ComputedColumn {
+	return ComputedColumn{
+		Column: Column{Name: name, Type: typeOfString},
+		Compute: func(values []interface{}) (interface{}, error) {
+			s, ok := formatValue(values[index])
+			if !ok {
+				return "", nil
+			}
+			if ignoreCase {
+				s = strings.ToLower(s)
+			}
+			return lookup[s], nil
+		},
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright 2021 Tamás Gulácsi.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package dbcsv
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// markdownReplacer escapes the characters which would break the Markdown table cells.
+var markdownReplacer = strings.NewReplacer("|", `\|`, "\r\n", "<br>", "\n", "<br>", "\r", "<br>")
+
+// DumpMarkdown writes the rows as a GitHub-flavored Markdown table to w,
+// the numbers right-aligned, NULLs as empty cells.
+//
+// All the rows are kept in memory to measure the widths of the columns.
+func DumpMarkdown(ctx context.Context, w io.Writer, rows Rows, columns []Column, opts DumperOptions, Log func(...interface{}) error) (int, error) {
+	dest := make([]interface{}, len(columns))
+	values := make([]Stringer, len(columns))
+	right := make([]bool, len(columns))
+	header := make([]string, len(columns))
+	widths := make([]int, len(columns))
+	for i, col := range columns {
+		c := col.Converter(opts)
+		values[i] = c
+		dest[i] = c.Pointer()
+		switch c.(type) {
+		case *ValInt, *LocalizedValInt, *ValUint, *ValFloat, *LocalizedValFloat, *ValDecimal:
+			right[i] = true
+		}
+		header[i] = markdownReplacer.Replace(col.Name)
+		// the separator needs at least 3 dashes
+		if widths[i] = utf8.RuneCountInString(header[i]); widths[i] < 3 {
+			widths[i] = 3
+		}
+	}
+
+	start := time.Now()
+	var records [][]string
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return len(records), fmt.Errorf("scan into %#v: %w", dest, err)
+		}
+		rec := make([]string, len(values))
+		for i, v := range values {
+			if !IsNull(v) {
+				rec[i] = markdownReplacer.Replace(StringRaw(v))
+				if k := utf8.RuneCountInString(rec[i]); k > widths[i] {
+					widths[i] = k
+				}
+			}
+		}
+		records = append(records, rec)
+		if err := ctx.Err(); err != nil {
+			return len(records), err
+		}
+	}
+	err := rows.Err()
+	if err == nil {
+		bw := bufio.NewWriterSize(w, 65536)
+		writeRecord := func(rec []string) {
+			for i, s := range rec {
+				bw.WriteString("| ")
+				pad := strings.Repeat(" ", widths[i]-utf8.RuneCountInString(s))
+				if right[i] {
+					bw.WriteString(pad)
+					bw.WriteString(s)
+				} else {
+					bw.WriteString(s)
+					bw.WriteString(pad)
+				}
+				bw.WriteByte(' ')
+			}
+			bw.WriteString("|\n")
+		}
+		writeRecord(header)
+		for i, width := range widths {
+			bw.WriteString("| ")
+			if right[i] {
+				bw.WriteString(strings.Repeat("-", width-1))
+				bw.WriteByte(':')
+			} else {
+				bw.WriteString(strings.Repeat("-", width))
+			}
+			bw.WriteByte(' ')
+		}
+		bw.WriteString("|\n")
+		for _, rec := range records {
+			writeRecord(rec)
+		}
+		err = bw.Flush()
+	}
+	dur := time.Since(start)
+	if Log != nil {
+		_ = Log("msg", "dump finished", "rows", len(records), "dur", dur, "speed", float64(len(records))/float64(dur)*float64(time.Second), "error", err)
+	}
+	return len(records), err
+}
@@ -0,0 +1,101 @@
+// Copyright 2021 Tamás Gulácsi.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package dbcsv
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// MinMax is a (closed) range of numbers.
+type MinMax struct {
+	Min float64 `json:"min"`
+	Max float64 `json:"max"`
+}
+
+// Scale x from the m range to the out range:
+// (x - m.Min) / (m.Max - m.Min) * (out.Max - out.Min) + out.Min.
+// If m is a single point, the result is out.Min.
+func (m MinMax) Scale(x float64, out MinMax) float64 {
+	if m.Max == m.Min {
+		return out.Min
+	}
+	return (x-m.Min)/(m.Max-m.Min)*(out.Max-out.Min) + out.Min
+}
+
+// MinMaxes are the ranges of the columns, by column name.
+type MinMaxes map[string]MinMax
+
+// ReadMinMaxes reads the JSON written by WriteTo.
+func ReadMinMaxes(r io.Reader) (MinMaxes, error) {
+	var mm MinMaxes
+	if err := json.NewDecoder(r).Decode(&mm); err != nil {
+		return nil, err
+	}
+	return mm, nil
+}
+
+// WriteTo writes the ranges as JSON.
+func (mm MinMaxes) WriteTo(w io.Writer) (int64, error) {
+	b, err := json.MarshalIndent(mm, "", "  ")
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(append(b, '\n'))
+	return int64(n), err
+}
+
+// ComputeMinMaxes reads all the rows into memory, and returns the ranges of the non-NULL values
+// of the named columns, and the rows to replay them.
+func ComputeMinMaxes(rows Rows, columns []Column, names ...string) (Rows, MinMaxes, error) {
+	indexes, err := columnIndexes(columns, names)
+	if err != nil {
+		return nil, nil, err
+	}
+	buffered, err := bufferRows(rows, columns)
+	if err != nil {
+		return nil, nil, err
+	}
+	mm := make(MinMaxes, len(names))
+	for _, i := range indexes {
+		numeric := columns[i].isNumericString()
+		var m MinMax
+		var seen bool
+		for _, vals := range buffered {
+			if vals[i] == nil {
+				continue
+			}
+			x, err := toFloat(vals[i], numeric)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s: %w", columns[i].Name, err)
+			}
+			if !seen {
+				m, seen = MinMax{Min: x, Max: x}, true
+			} else if x < m.Min {
+				m.Min = x
+			} else if x > m.Max {
+				m.Max = x
+			}
+		}
+		mm[columns[i].Name] = m
+	}
+	return &replayRows{Rows: rows, buffered: buffered}, mm, nil
+}
+
+// MinMaxScale returns the rows with the values of the columns in mm scaled to the output range
+// of the column in out (by name, [0, 1] if missing). These columns become float columns.
+func MinMaxScale(rows Rows, columns []Column, mm MinMaxes, out MinMaxes) (Rows, []Column, error) {
+	scale := make(map[string]func(float64) float64, len(mm))
+	for name, m := range mm {
+		m := m
+		o, ok := out[name]
+		if !ok {
+			o = MinMax{Min: 0, Max: 1}
+		}
+		scale[name] = func(x float64) float64 { return m.Scale(x, o) }
+	}
+	return scaleColumns(rows, columns, scale)
+}
@@ -0,0 +1,182 @@
+// Copyright 2021 Tamás Gulácsi.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package dbcsv
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const xsdNS = "http://www.w3.org/2001/XMLSchema#"
+
+// NQuadsOptions are the column mappings of DumpNQuads.
+type NQuadsOptions struct {
+	// SubjectColumn is the column of the subject IRI.
+	SubjectColumn string
+	// PredicatePrefix is prepended to the column names to get the predicate IRIs,
+	// and to the subject and graph values that are not absolute IRIs.
+	PredicatePrefix string
+	// ObjectColumn is the column of the object; if empty, all the other columns are objects.
+	ObjectColumn string
+	// GraphColumn is the column of the graph IRI; if empty, the quads are in the default graph.
+	GraphColumn string
+	// DumperOptions format the values (see Column.Converter).
+	DumperOptions
+}
+
+// DumpNQuads writes the rows as RDF N-Quads to w:
+// "<subject> <predicate/colname> "value"^^<xsd:type> <graph> ." for each non-NULL object.
+//
+// The type of ValInt is xsd:integer, of ValFloat xsd:decimal, of ValTime xsd:dateTime,
+// everything else is a plain string literal.
+func DumpNQuads(ctx context.Context, w io.Writer, rows Rows, columns []Column, opts NQuadsOptions, Log func(...interface{}) error) (int, error) {
+	colIndex := func(name string) (int, error) {
+		if name == "" {
+			return -1, nil
+		}
+		for i, c := range columns {
+			if strings.EqualFold(c.Name, name) {
+				return i, nil
+			}
+		}
+		return -1, fmt.Errorf("%s: unknown column", name)
+	}
+	subj, err := colIndex(opts.SubjectColumn)
+	if err != nil {
+		return 0, err
+	}
+	if subj < 0 {
+		return 0, fmt.Errorf("subject column is required")
+	}
+	obj, err := colIndex(opts.ObjectColumn)
+	if err != nil {
+		return 0, err
+	}
+	graph, err := colIndex(opts.GraphColumn)
+	if err != nil {
+		return 0, err
+	}
+	var objects []int
+	for i := range columns {
+		if obj >= 0 && i == obj || obj < 0 && i != subj && i != graph {
+			objects = append(objects, i)
+		}
+	}
+	predicates := make([]string, len(columns))
+	for _, i := range objects {
+		predicates[i] = nquadsIRI(opts.PredicatePrefix + columns[i].Name)
+	}
+
+	dest := make([]interface{}, len(columns))
+	values := make([]Stringer, len(columns))
+	for i, col := range columns {
+		c := col.Converter(opts.DumperOptions)
+		values[i] = c
+		dest[i] = c.Pointer()
+	}
+	bw := bufio.NewWriterSize(w, 65536)
+	defer bw.Flush()
+	start := time.Now()
+	n := 0
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return n, fmt.Errorf("scan into %#v: %w", dest, err)
+		}
+		n++
+		if IsNull(values[subj]) {
+			return n, fmt.Errorf("%d. row: NULL subject", n)
+		}
+		subject := nquadsIRI(nquadsAbsolute(opts.PredicatePrefix, StringRaw(values[subj])))
+		var graphIRI string
+		if graph >= 0 && !IsNull(values[graph]) {
+			graphIRI = nquadsIRI(nquadsAbsolute(opts.PredicatePrefix, StringRaw(values[graph])))
+		}
+		for _, i := range objects {
+			var literal string
+			switch x := TypedValue(values[i]).(type) {
+			case nil:
+				continue
+			case int64:
+				literal = nquadsLiteral(strconv.FormatInt(x, 10)) + "^^<" + xsdNS + "integer>"
+			case float64:
+				literal = nquadsLiteral(xsdDouble(x)) + "^^<" + xsdNS + "double>"
+			case time.Time:
+				literal = nquadsLiteral(x.Format(time.RFC3339Nano)) + "^^<" + xsdNS + "dateTime>"
+			default:
+				literal = nquadsLiteral(StringRaw(values[i]))
+			}
+			bw.WriteString(subject)
+			bw.WriteByte(' ')
+			bw.WriteString(predicates[i])
+			bw.WriteByte(' ')
+			bw.WriteString(literal)
+			if graphIRI != "" {
+				bw.WriteByte(' ')
+				bw.WriteString(graphIRI)
+			}
+			if _, err := bw.WriteString(" .\n"); err != nil {
+				return n, err
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			return n, err
+		}
+	}
+	err = rows.Err()
+	dur := time.Since(start)
+	if Log != nil {
+		_ = Log("msg", "dump finished", "rows", n, "dur", dur, "speed", float64(n)/float64(dur)*float64(time.Second), "error", err)
+	}
+	if err != nil {
+		return n, err
+	}
+	return n, bw.Flush()
+}
+
+// nquadsAbsolute prepends the prefix to s if s is not an absolute IRI.
+func nquadsAbsolute(prefix, s string) string {
+	if strings.Contains(s, ":") {
+		return s
+	}
+	return prefix + s
+}
+
+var nquadsIRIReplacer = func() *strings.Replacer {
+	oldnew := []string{
+		" ", "%20", "<", "%3C", ">", "%3E", `"`, "%22", "{", "%7B", "}", "%7D",
+		"|", "%7C", "^", "%5E", "`", "%60", `\`, "%5C",
+	}
+	// the control characters are not allowed, either
+	for c := 0; c < 0x20; c++ {
+		oldnew = append(oldnew, string(rune(c)), fmt.Sprintf("%%%02X", c))
+	}
+	return strings.NewReplacer(oldnew...)
+}()
+
+// nquadsIRI returns the IRI reference of s, with the characters not allowed in IRIREF percent-encoded.
+func nquadsIRI(s string) string { return "<" + nquadsIRIReplacer.Replace(s) + ">" }
+
+// xsdDouble returns the xsd:double lexical form of x, with NaN, INF and -INF for the non-finite values.
+func xsdDouble(x float64) string {
+	switch {
+	case math.IsNaN(x):
+		return "NaN"
+	case math.IsInf(x, 1):
+		return "INF"
+	case math.IsInf(x, -1):
+		return "-INF"
+	}
+	return strconv.FormatFloat(x, 'E', -1, 64)
+}
+
+var nquadsLiteralReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`)
+
+func nquadsLiteral(s string) string { return `"` + nquadsLiteralReplacer.Replace(s) + `"` }
@@ -0,0 +1,64 @@
+// Copyright 2021 Tamás Gulácsi.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package dbcsv
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"golang.org/x/text/number"
+)
+
+// NewNumberPrinter returns the message.Printer of the locale (such as de_DE or ar).
+func NewNumberPrinter(locale string) (*message.Printer, error) {
+	tag, err := language.Parse(strings.ReplaceAll(locale, "_", "-"))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", locale, err)
+	}
+	return message.NewPrinter(tag), nil
+}
+
+// formatLocalizedFloat formats f with p, with as many fraction digits as the shortest representation of f has.
+func formatLocalizedFloat(p *message.Printer, f float64) string {
+	s := strconv.FormatFloat(f, 'f', -1, 64)
+	var frac int
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		frac = len(s) - i - 1
+	}
+	return p.Sprint(number.Decimal(f, number.MaxFractionDigits(frac)))
+}
+
+// LocalizedValFloat is a ValFloat formatted with Printer.
+type LocalizedValFloat struct {
+	ValFloat
+	Sep     string
+	Printer *message.Printer
+}
+
+func (v LocalizedValFloat) String() string { return csvQuoteString(v.Sep, v.StringRaw()) }
+func (v LocalizedValFloat) StringRaw() string {
+	if !v.Value.Valid {
+		return ""
+	}
+	return formatLocalizedFloat(v.Printer, v.Value.Float64)
+}
+
+// LocalizedValInt is a ValInt formatted with Printer.
+type LocalizedValInt struct {
+	ValInt
+	Sep     string
+	Printer *message.Printer
+}
+
+func (v LocalizedValInt) String() string { return csvQuoteString(v.Sep, v.StringRaw()) }
+func (v LocalizedValInt) StringRaw() string {
+	if !v.Value.Valid {
+		return ""
+	}
+	return v.Printer.Sprint(number.Decimal(v.Value.Int64))
+}
@@ -0,0 +1,294 @@
+// Copyright 2021 Tamás Gulácsi.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package dbcsv
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const (
+	odsMimeType = "application/vnd.oasis.opendocument.spreadsheet"
+	odsTableNS  = "urn:oasis:names:tc:opendocument:xmlns:table:1.0"
+	odsOfficeNS = "urn:oasis:names:tc:opendocument:xmlns:office:1.0"
+	odsTextNS   = "urn:oasis:names:tc:opendocument:xmlns:text:1.0"
+)
+
+// odsCell is a cell of an ODS table, with its office:value-type.
+type odsCell struct {
+	Type, Value string
+}
+
+// ReadODSFile reads the sheetIndex-th (0-based) table of the ODS file.
+func ReadODSFile(ctx context.Context, fn func(string, Row) error, filename string, sheetIndex int, columns []int, skip int) error {
+	n := 0
+	return readODS(ctx, filename, sheetIndex, func(sheetName string, i int, cells []odsCell) error {
+		if i < skip {
+			return nil
+		}
+		var vals []string
+		if len(columns) != 0 {
+			vals = make([]string, len(columns))
+			for k, j := range columns {
+				if j < len(cells) {
+					vals[k] = cells[j].Value
+				}
+			}
+		} else {
+			vals = make([]string, len(cells))
+			for j, c := range cells {
+				vals[j] = c.Value
+			}
+		}
+		err := fn(sheetName, Row{Line: n, Values: vals})
+		n++
+		return err
+	})
+}
+
+// ReadODSSheets returns the names of the tables of the ODS file.
+func ReadODSSheets(ctx context.Context, filename string) (map[int]string, error) {
+	m := make(map[int]string)
+	err := readODS(ctx, filename, -1, func(sheetName string, i int, _ []odsCell) error {
+		m[len(m)] = sheetName
+		return nil
+	})
+	return m, err
+}
+
+// loadODSRows reads the table as StringRows, with the column types inferred from the cell value types.
+func loadODSRows(ctx context.Context, filename string, sheetIndex int) (*StringRows, []Column, error) {
+	var header []string
+	var records [][]string
+	var types [][]string
+	if err := readODS(ctx, filename, sheetIndex, func(_ string, i int, cells []odsCell) error {
+		if header == nil {
+			header = make([]string, len(cells))
+			for j, c := range cells {
+				header[j] = c.Value
+			}
+			return nil
+		}
+		rec := make([]string, len(cells))
+		for j, c := range cells {
+			rec[j] = c.Value
+			if c.Value == "" {
+				continue
+			}
+			for len(types) <= j {
+				types = append(types, nil)
+			}
+			types[j] = append(types[j], c.Type)
+		}
+		records = append(records, rec)
+		return nil
+	}); err != nil {
+		return nil, nil, err
+	}
+	columns := make([]Column, len(header))
+	for j, nm := range header {
+		columns[j] = Column{Name: nm, Type: typeOfString}
+		if j >= len(types) {
+			continue
+		}
+		var typ reflect.Type
+	Loop:
+		for _, t := range types[j] {
+			var tt reflect.Type
+			switch t {
+			case "float", "percentage", "currency":
+				tt = typeOfFloat64
+			case "date":
+				tt = typeOfTime
+			default:
+				typ = typeOfString
+				break Loop
+			}
+			if typ != nil && typ != tt {
+				typ = typeOfString
+				break Loop
+			}
+			typ = tt
+		}
+		if typ == typeOfFloat64 && inferType(records, j) == typeOfInt64 {
+			typ = typeOfInt64
+		}
+		if typ != nil {
+			columns[j].Type = typ
+		}
+	}
+	return &StringRows{records: records, columns: columns}, columns, nil
+}
+
+// readODS calls fn with each non-empty row of the sheetIndex-th table (all tables if sheetIndex < 0).
+// Rows without cells are not emitted, but counted in i.
+func readODS(ctx context.Context, filename string, sheetIndex int, fn func(sheetName string, i int, cells []odsCell) error) error {
+	zr, err := zip.OpenReader(filename)
+	if err != nil {
+		return fmt.Errorf("open %q: %w", filename, err)
+	}
+	defer zr.Close()
+	var rc io.ReadCloser
+	for _, f := range zr.File {
+		if f.Name == "content.xml" {
+			if rc, err = f.Open(); err != nil {
+				return fmt.Errorf("open %q/%s: %w", filename, f.Name, err)
+			}
+			break
+		}
+	}
+	if rc == nil {
+		return fmt.Errorf("%q: no content.xml", filename)
+	}
+	defer rc.Close()
+
+	dec := xml.NewDecoder(rc)
+	tableNo := -1
+	var sheetName string
+	var row []odsCell
+	var rowNo int
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		switch x := tok.(type) {
+		case xml.StartElement:
+			if x.Name.Space != odsTableNS {
+				continue
+			}
+			switch x.Name.Local {
+			case "table":
+				tableNo++
+				if sheetIndex >= 0 && tableNo != sheetIndex {
+					if err = dec.Skip(); err != nil {
+						return err
+					}
+					continue
+				}
+				sheetName, rowNo = odsAttr(x, odsTableNS, "name"), 0
+				if sheetIndex < 0 {
+					if err = fn(sheetName, 0, nil); err != nil {
+						return err
+					}
+					if err = dec.Skip(); err != nil {
+						return err
+					}
+				}
+			case "table-row":
+				row = row[:0]
+			case "table-cell", "covered-table-cell":
+				c := odsCell{Type: odsAttr(x, odsOfficeNS, "value-type")}
+				text, err := odsText(dec)
+				if err != nil {
+					return err
+				}
+				switch c.Type {
+				case "float", "percentage", "currency":
+					c.Value = odsAttr(x, odsOfficeNS, "value")
+				case "date":
+					c.Value = odsAttr(x, odsOfficeNS, "date-value")
+				case "boolean":
+					c.Value = odsAttr(x, odsOfficeNS, "boolean-value")
+				default:
+					c.Value = text
+				}
+				repeat := 1
+				if s := odsAttr(x, odsTableNS, "number-columns-repeated"); s != "" {
+					if repeat, err = strconv.Atoi(s); err != nil {
+						return fmt.Errorf("number-columns-repeated=%q: %w", s, err)
+					}
+				}
+				for k := 0; k < repeat; k++ {
+					row = append(row, c)
+				}
+			}
+		case xml.EndElement:
+			if x.Name.Space != odsTableNS || x.Name.Local != "table-row" {
+				continue
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			// trailing empty cells are just formatting
+			for len(row) != 0 && row[len(row)-1].Value == "" {
+				row = row[:len(row)-1]
+			}
+			// number-rows-repeated is not tracked: repeated empty rows are formatting, too
+			if len(row) != 0 {
+				if err := fn(sheetName, rowNo, row); err != nil {
+					return err
+				}
+			}
+			rowNo++
+		}
+	}
+}
+
+func odsAttr(se xml.StartElement, space, local string) string {
+	for _, a := range se.Attr {
+		if a.Name.Space == space && a.Name.Local == local {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// odsText returns the text of the cell just started, consuming its end element.
+func odsText(dec *xml.Decoder) (string, error) {
+	var buf strings.Builder
+	var paragraphs int
+	for depth := 1; depth > 0; {
+		tok, err := dec.Token()
+		if err != nil {
+			return buf.String(), err
+		}
+		switch x := tok.(type) {
+		case xml.StartElement:
+			if x.Name.Space == odsOfficeNS && x.Name.Local == "annotation" {
+				if err = dec.Skip(); err != nil {
+					return buf.String(), err
+				}
+				continue
+			}
+			depth++
+			if x.Name.Space != odsTextNS {
+				continue
+			}
+			switch x.Name.Local {
+			case "p":
+				if paragraphs != 0 {
+					buf.WriteByte('\n')
+				}
+				paragraphs++
+			case "s":
+				n := 1
+				if s := odsAttr(x, odsTextNS, "c"); s != "" {
+					n, _ = strconv.Atoi(s)
+				}
+				buf.WriteString(strings.Repeat(" ", n))
+			case "tab":
+				buf.WriteByte('\t')
+			case "line-break":
+				buf.WriteByte('\n')
+			}
+		case xml.EndElement:
+			depth--
+		case xml.CharData:
+			buf.Write(x)
+		}
+	}
+	return buf.String(), nil
+}
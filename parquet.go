@@ -0,0 +1,123 @@
+// Copyright 2021 Tamás Gulácsi.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package dbcsv
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/apache/arrow/go/v10/arrow"
+	"github.com/apache/arrow/go/v10/arrow/array"
+	"github.com/apache/arrow/go/v10/arrow/memory"
+	"github.com/apache/arrow/go/v10/parquet"
+	"github.com/apache/arrow/go/v10/parquet/compress"
+	"github.com/apache/arrow/go/v10/parquet/pqarrow"
+)
+
+// ParquetRowGroupSize is the number of rows in a Parquet row group.
+const ParquetRowGroupSize = 64 << 10
+
+// parquetType returns the Arrow type of the Stringer (created with Converter("")).
+func parquetType(s Stringer) arrow.DataType {
+	switch s.(type) {
+	case *ValInt, *LocalizedValInt:
+		return arrow.PrimitiveTypes.Int64
+	case *ValFloat, *LocalizedValFloat:
+		return arrow.PrimitiveTypes.Float64
+	case *ValTime, *LocalizedValTime:
+		// TIMESTAMP(MILLIS) of the wall clock
+		return &arrow.TimestampType{Unit: arrow.Millisecond}
+	case *ValBool:
+		return arrow.FixedWidthTypes.Boolean
+	}
+	return arrow.BinaryTypes.String
+}
+
+// DumpParquet writes the rows as a (Snappy compressed) Parquet file to w.
+//
+// The integers are INT64, the floats DOUBLE, the dates TIMESTAMP(MILLIS) (their wall clock, as UTC),
+// the booleans BOOLEAN, everything else (including the wrapped columns) UTF-8 strings (BYTE_ARRAY).
+// The rows are written in row groups of ParquetRowGroupSize rows.
+func DumpParquet(ctx context.Context, w io.Writer, rows Rows, columns []Column, opts DumperOptions, Log func(...interface{}) error) (int, error) {
+	dest := make([]interface{}, len(columns))
+	values := make([]Stringer, len(columns))
+	fields := make([]arrow.Field, len(columns))
+	for i, col := range columns {
+		c := col.Converter(opts)
+		values[i] = c
+		dest[i] = c.Pointer()
+		fields[i] = arrow.Field{Name: col.Name, Type: parquetType(c), Nullable: !col.NotNull}
+	}
+	schema := arrow.NewSchema(fields, nil)
+	// the file writer would close w
+	fw, err := pqarrow.NewFileWriter(schema, struct{ io.Writer }{w},
+		parquet.NewWriterProperties(parquet.WithCompression(compress.Codecs.Snappy)),
+		pqarrow.NewArrowWriterProperties(pqarrow.WithStoreSchema()),
+	)
+	if err != nil {
+		return 0, err
+	}
+	defer fw.Close()
+	rb := array.NewRecordBuilder(memory.DefaultAllocator, schema)
+	defer rb.Release()
+	flush := func() error {
+		rec := rb.NewRecord()
+		defer rec.Release()
+		return fw.Write(rec)
+	}
+
+	start := time.Now()
+	n := 0
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return n, fmt.Errorf("scan into %#v: %w", dest, err)
+		}
+		for i, v := range values {
+			b := rb.Field(i)
+			x := TypedValue(v)
+			if x == nil {
+				b.AppendNull()
+				continue
+			}
+			switch b := b.(type) {
+			case *array.Int64Builder:
+				b.Append(x.(int64))
+			case *array.Float64Builder:
+				b.Append(x.(float64))
+			case *array.TimestampBuilder:
+				t := x.(time.Time)
+				wall := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+				b.Append(arrow.Timestamp(wall.UnixNano() / int64(time.Millisecond)))
+			case *array.BooleanBuilder:
+				b.Append(x.(bool))
+			case *array.StringBuilder:
+				b.Append(StringRaw(v))
+			}
+		}
+		n++
+		if n%ParquetRowGroupSize == 0 {
+			if err := flush(); err != nil {
+				return n, err
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			return n, err
+		}
+	}
+	err = rows.Err()
+	if err == nil && (n == 0 || n%ParquetRowGroupSize != 0) {
+		err = flush()
+	}
+	if closeErr := fw.Close(); closeErr != nil && err == nil {
+		err = closeErr
+	}
+	dur := time.Since(start)
+	if Log != nil {
+		_ = Log("msg", "dump finished", "rows", n, "dur", dur, "speed", float64(n)/float64(dur)*float64(time.Second), "error", err)
+	}
+	return n, err
+}
@@ -0,0 +1,238 @@
+// Copyright 2021 Tamás Gulácsi.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package dbcsv
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"time"
+)
+
+// Pickle opcodes, see https://github.com/python/cpython/blob/main/Lib/pickletools.py
+const (
+	pickleProto           = 0x80
+	pickleStop            = '.'
+	pickleMark            = '('
+	pickleNone            = 'N'
+	pickleNewTrue         = 0x88
+	pickleNewFalse        = 0x89
+	pickleLong1           = 0x8a
+	pickleBinFloat        = 'G'
+	pickleShortBinUnicode = 0x8c
+	pickleBinUnicode      = 'X'
+	pickleStackGlobal     = 0x93
+	pickleEmptyList       = ']'
+	pickleAppends         = 'e'
+	pickleEmptyDict       = '}'
+	pickleSetItems        = 'u'
+	pickleTuple           = 't'
+	pickleTuple1          = 0x85
+	pickleReduce          = 'R'
+)
+
+// pickleBatchSize is the number of items between MARKs, as Python's pickler does.
+const pickleBatchSize = 1000
+
+// DumpPandasPickle writes the rows as a (protocol 5) Python pickle of a pandas.DataFrame to w,
+// loadable with pandas.read_pickle.
+//
+// The DataFrame is built from a dict of pandas.Series (so it has a RangeIndex), one for each column.
+// The dtype of a Series is int64, float64, bool or datetime64[ns] if all its values are of that type,
+// and object otherwise; the integer and boolean columns with NULLs get the nullable Int64 and boolean dtypes.
+// Times are written as their wall clock, without time zone.
+//
+// All the rows are read into memory first.
+func DumpPandasPickle(ctx context.Context, w io.Writer, rows Rows, columns []Column, opts DumperOptions, Log func(...interface{}) error) (int, error) {
+	dest := make([]interface{}, len(columns))
+	values := make([]Stringer, len(columns))
+	for i, col := range columns {
+		c := col.Converter(opts)
+		values[i] = c
+		dest[i] = c.Pointer()
+	}
+	data := make([][]interface{}, len(columns))
+	start := time.Now()
+	n := 0
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return n, fmt.Errorf("scan into %#v: %w", dest, err)
+		}
+		for i, v := range values {
+			data[i] = append(data[i], TypedValue(v))
+		}
+		n++
+		if err := ctx.Err(); err != nil {
+			return n, err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return n, err
+	}
+
+	pw := pickleWriter{bw: bufio.NewWriter(w)}
+	pw.bw.Write([]byte{pickleProto, 5})
+	pw.global("pandas", "DataFrame")
+	pw.bw.WriteByte(pickleEmptyDict)
+	seen := make(map[string]int, len(columns))
+	for i, col := range columns {
+		if i%pickleBatchSize == 0 {
+			if i != 0 {
+				pw.bw.WriteByte(pickleSetItems)
+			}
+			pw.bw.WriteByte(pickleMark)
+		}
+		name := col.Name
+		if k := seen[name]; k != 0 {
+			seen[name]++
+			name += "_" + strconv.Itoa(k+1)
+		} else {
+			seen[name] = 1
+		}
+		pw.str(name)
+		// pandas.Series(data, index, dtype, name)
+		pw.global("pandas", "Series")
+		pw.bw.WriteByte(pickleMark)
+		dtype := pw.list(data[i])
+		pw.bw.WriteByte(pickleNone)
+		pw.str(dtype)
+		pw.str(name)
+		pw.bw.Write([]byte{pickleTuple, pickleReduce})
+	}
+	if len(columns) != 0 {
+		pw.bw.WriteByte(pickleSetItems)
+	}
+	// pandas.DataFrame(dict)
+	pw.bw.Write([]byte{pickleTuple1, pickleReduce, pickleStop})
+	err := pw.bw.Flush()
+	dur := time.Since(start)
+	if Log != nil {
+		_ = Log("msg", "dump finished", "rows", n, "dur", dur, "speed", float64(n)/float64(dur)*float64(time.Second), "error", err)
+	}
+	return n, err
+}
+
+type pickleWriter struct {
+	bw      *bufio.Writer
+	scratch [8]byte
+}
+
+// global pushes module.name.
+func (pw *pickleWriter) global(module, name string) {
+	pw.str(module)
+	pw.str(name)
+	pw.bw.WriteByte(pickleStackGlobal)
+}
+
+func (pw *pickleWriter) str(s string) {
+	if len(s) < 256 {
+		pw.bw.Write([]byte{pickleShortBinUnicode, byte(len(s))})
+	} else {
+		pw.bw.WriteByte(pickleBinUnicode)
+		binary.LittleEndian.PutUint32(pw.scratch[:4], uint32(len(s)))
+		pw.bw.Write(pw.scratch[:4])
+	}
+	pw.bw.WriteString(s)
+}
+
+// list pushes the list of the values, converted to the returned pandas dtype.
+func (pw *pickleWriter) list(values []interface{}) string {
+	var ints, floats, times, bools, nulls int
+	for _, v := range values {
+		switch v.(type) {
+		case nil:
+			nulls++
+		case int64:
+			ints++
+		case float64:
+			floats++
+		case time.Time:
+			times++
+		case bool:
+			bools++
+		}
+	}
+	dtype := "object"
+	switch nonNull := len(values) - nulls; {
+	case nonNull == 0:
+	case ints == nonNull:
+		dtype = "int64"
+		if nulls != 0 {
+			dtype = "Int64"
+		}
+	case floats == nonNull || ints+floats == nonNull:
+		dtype = "float64"
+	case times == nonNull:
+		dtype = "datetime64[ns]"
+	case bools == nonNull:
+		dtype = "bool"
+		if nulls != 0 {
+			dtype = "boolean"
+		}
+	}
+
+	pw.bw.WriteByte(pickleEmptyList)
+	for i, v := range values {
+		if i%pickleBatchSize == 0 {
+			if i != 0 {
+				pw.bw.WriteByte(pickleAppends)
+			}
+			pw.bw.WriteByte(pickleMark)
+		}
+		switch x := v.(type) {
+		case nil:
+			pw.bw.WriteByte(pickleNone)
+		case int64:
+			if dtype == "float64" {
+				pw.float(float64(x))
+			} else {
+				pw.int(x)
+			}
+		case float64:
+			pw.float(x)
+		case time.Time:
+			// datetime64[ns] is the nanoseconds since the epoch, NaT (None) when out of range
+			wall := time.Date(x.Year(), x.Month(), x.Day(), x.Hour(), x.Minute(), x.Second(), x.Nanosecond(), time.UTC)
+			if y := wall.Year(); y <= 1677 || y >= 2262 {
+				pw.bw.WriteByte(pickleNone)
+			} else if dtype == "object" {
+				pw.str(wall.Format(time.RFC3339Nano))
+			} else {
+				pw.int(wall.UnixNano())
+			}
+		case bool:
+			if x {
+				pw.bw.WriteByte(pickleNewTrue)
+			} else {
+				pw.bw.WriteByte(pickleNewFalse)
+			}
+		case string:
+			pw.str(x)
+		default:
+			pw.str(fmt.Sprint(x))
+		}
+	}
+	if len(values) != 0 {
+		pw.bw.WriteByte(pickleAppends)
+	}
+	return dtype
+}
+
+// int pushes i as an 8-byte LONG1.
+func (pw *pickleWriter) int(i int64) {
+	pw.bw.Write([]byte{pickleLong1, 8})
+	binary.LittleEndian.PutUint64(pw.scratch[:], uint64(i))
+	pw.bw.Write(pw.scratch[:])
+}
+
+func (pw *pickleWriter) float(f float64) {
+	pw.bw.WriteByte(pickleBinFloat)
+	binary.BigEndian.PutUint64(pw.scratch[:], math.Float64bits(f))
+	pw.bw.Write(pw.scratch[:])
+}
@@ -0,0 +1,46 @@
+// Copyright 2021 Tamás Gulácsi.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package dbcsv_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"reflect"
+	"testing"
+
+	"github.com/UNO-SOFT/dbcsv"
+)
+
+func TestDumpPandasPickle(t *testing.T) {
+	columns := []dbcsv.Column{{Name: "ID", Type: typeOfInt64}, {Name: "NAME", Type: typeOfString}, {Name: "X", Type: reflect.TypeOf(float64(0))}}
+	rows := &sliceRows{values: [][]interface{}{{int64(1), "a", 1.5}, {nil, "á", nil}, {int64(-2), nil, float64(2)}}}
+	var buf bytes.Buffer
+	n, err := dbcsv.DumpPandasPickle(context.Background(), &buf, rows, columns, dbcsv.DumperOptions{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Errorf("got %d rows, wanted 3", n)
+	}
+	// pandas.DataFrame({"ID": pandas.Series([1, None, -2], None, "Int64", "ID"),
+	//   "NAME": pandas.Series(["a", "á", None], None, "object", "NAME"),
+	//   "X": pandas.Series([1.5, None, 2.0], None, "float64", "X")}),
+	// as python3 -m pickletools shows.
+	want := "80058c0670616e6461738c09446174614672616d65937d28" +
+		"8c0249448c0670616e6461738c0653657269657393285d28" +
+		"8a0801000000000000004e8a08feffffffffffffff65" +
+		"4e8c05496e7436348c0249447452" +
+		"8c044e414d458c0670616e6461738c0653657269657393285d28" +
+		"8c01618c02c3a14e65" +
+		"4e8c066f626a6563748c044e414d457452" +
+		"8c01588c0670616e6461738c0653657269657393285d28" +
+		"473ff80000000000004e47400000000000000065" +
+		"4e8c07666c6f617436348c01587452" +
+		"7585522e"
+	if got := hex.EncodeToString(buf.Bytes()); got != want {
+		t.Errorf("got\n%s\nwanted\n%s", got, want)
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright 2021 Tamás Gulácsi.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package dbcsv
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+)
+
+// PipeWriter pipes the written lines through an external command (such as awk, sed or jq),
+// writing the command's output to the underlying writer, line by line.
+type PipeWriter struct {
+	w    io.Writer
+	pw   *io.PipeWriter
+	cmd  *exec.Cmd
+	done chan error
+	mu   sync.Mutex // of w
+	// skip is the number of lines still to be written to w directly
+	skip int
+}
+
+// NewPipeWriter starts the command and returns a PipeWriter piping to its standard input.
+// The first skipLines lines (such as the header) are written to w directly, not through the command.
+func NewPipeWriter(ctx context.Context, w io.Writer, skipLines int, name string, args ...string) (*PipeWriter, error) {
+	pr, pw := io.Pipe()
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdin = pr
+	cmd.Stderr = os.Stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err = cmd.Start(); err != nil {
+		return nil, fmt.Errorf("%s: %w", cmd.Args, err)
+	}
+	p := &PipeWriter{w: w, pw: pw, cmd: cmd, done: make(chan error, 1), skip: skipLines}
+	go func() {
+		var err error
+		br := bufio.NewReader(stdout)
+		for {
+			line, readErr := br.ReadSlice('\n')
+			if len(line) != 0 && err == nil {
+				p.mu.Lock()
+				_, err = w.Write(line)
+				p.mu.Unlock()
+			}
+			if readErr == bufio.ErrBufferFull {
+				continue
+			} else if readErr != nil {
+				if readErr != io.EOF && err == nil {
+					err = readErr
+				}
+				break
+			}
+		}
+		if err != nil {
+			// unblock the writes, then drain the output, so the command can finish
+			pr.CloseWithError(err)
+			_, _ = io.Copy(io.Discard, br)
+		}
+		if waitErr := cmd.Wait(); waitErr != nil && err == nil {
+			err = fmt.Errorf("%s: %w", cmd.Args, waitErr)
+		}
+		// the command may exit without reading all its input
+		pr.CloseWithError(fmt.Errorf("%s: %w", cmd.Args, io.ErrClosedPipe))
+		p.done <- err
+	}()
+	return p, nil
+}
+
+// Write writes p to the command's standard input (or the first lines directly to the underlying writer).
+func (p *PipeWriter) Write(b []byte) (int, error) {
+	var n int
+	for p.skip > 0 && len(b) != 0 {
+		i := bytes.IndexByte(b, '\n')
+		if i < 0 {
+			i = len(b) - 1
+		} else {
+			p.skip--
+		}
+		p.mu.Lock()
+		k, err := p.w.Write(b[:i+1])
+		p.mu.Unlock()
+		n += k
+		if err != nil {
+			return n, err
+		}
+		b = b[i+1:]
+	}
+	if len(b) == 0 {
+		return n, nil
+	}
+	k, err := p.pw.Write(b)
+	return n + k, err
+}
+
+// Close closes the command's standard input, waits for it to finish,
+// then closes the underlying writer if it is an io.Closer.
+func (p *PipeWriter) Close() error {
+	p.pw.Close()
+	err := <-p.done
+	if c, ok := p.w.(io.Closer); ok {
+		if closeErr := c.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
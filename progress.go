@@ -0,0 +1,61 @@
+// Copyright 2021 Tamás Gulácsi.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package dbcsv
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// progress reports the number of rows dumped every DumperOptions.ProgressInterval, till Stop or the context is done.
+// A nil *progress is a no-op.
+type progress struct {
+	n       int64
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+func startProgress(ctx context.Context, start time.Time, opts DumperOptions) *progress {
+	if opts.ProgressInterval <= 0 {
+		return nil
+	}
+	opts = opts.withDefaults()
+	p := &progress{done: make(chan struct{}), stopped: make(chan struct{})}
+	go func() {
+		defer close(p.stopped)
+		ticker := time.NewTicker(opts.ProgressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-p.done:
+				return
+			case <-ticker.C:
+				n, elapsed := atomic.LoadInt64(&p.n), time.Since(start)
+				fmt.Fprintf(opts.ProgressWriter, "rows=%d elapsed=%s speed=%.1f\n",
+					n, elapsed.Round(time.Millisecond), float64(n)/elapsed.Seconds())
+			}
+		}
+	}()
+	return p
+}
+
+// Set sets the number of rows dumped.
+func (p *progress) Set(n int) {
+	if p != nil {
+		atomic.StoreInt64(&p.n, int64(n))
+	}
+}
+
+// Stop stops the reports, and waits for the reporting goroutine to exit.
+func (p *progress) Stop() {
+	if p != nil {
+		close(p.done)
+		<-p.stopped
+	}
+}
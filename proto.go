@@ -0,0 +1,101 @@
+// Copyright 2021 Tamás Gulácsi.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package dbcsv
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// protoTypes are the Protobuf scalar types of int, float, time, bool, []byte and string columns.
+// Times are RFC 3339 strings.
+var protoTypes = [6]descriptorpb.FieldDescriptorProto_Type{
+	descriptorpb.FieldDescriptorProto_TYPE_INT64,
+	descriptorpb.FieldDescriptorProto_TYPE_DOUBLE,
+	descriptorpb.FieldDescriptorProto_TYPE_STRING,
+	descriptorpb.FieldDescriptorProto_TYPE_BOOL,
+	descriptorpb.FieldDescriptorProto_TYPE_BYTES,
+	descriptorpb.FieldDescriptorProto_TYPE_STRING,
+}
+
+// ProtoDescriptor returns the (proto3) file descriptor of the "dbcsv" package with one message,
+// which has a field for each column, numbered from 1, and compiles it in-memory.
+//
+// The field names are the column names in lower case, with the characters not allowed replaced by _.
+func ProtoDescriptor(message string, columns []Column) (*descriptorpb.FileDescriptorProto, protoreflect.MessageDescriptor, error) {
+	msg := &descriptorpb.DescriptorProto{Name: proto.String(message)}
+	seen := make(map[string]int, len(columns))
+	for i, col := range columns {
+		name := protoFieldName(col.Name)
+		if n := seen[name]; n != 0 {
+			seen[name]++
+			name += "_" + strconv.Itoa(n+1)
+		} else {
+			seen[name] = 1
+		}
+		msg.Field = append(msg.Field, &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String(name),
+			Number:   proto.Int32(int32(i + 1)),
+			Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+			Type:     protoTypes[ddlTypeIndex(col.Type)].Enum(),
+			JsonName: proto.String(col.Name),
+		})
+	}
+	fdp := &descriptorpb.FileDescriptorProto{
+		Name:        proto.String(strings.ToLower(message) + ".proto"),
+		Package:     proto.String("dbcsv"),
+		Syntax:      proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{msg},
+	}
+	fd, err := protodesc.NewFile(fdp, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", message, err)
+	}
+	return fdp, fd.Messages().Get(0), nil
+}
+
+// WriteProtoFile writes the .proto source of the file descriptor (as returned by ProtoDescriptor).
+func WriteProtoFile(w io.Writer, fdp *descriptorpb.FileDescriptorProto) error {
+	var buf strings.Builder
+	buf.WriteString("syntax = \"" + fdp.GetSyntax() + "\";\n\npackage " + fdp.GetPackage() + ";\n")
+	for _, msg := range fdp.GetMessageType() {
+		buf.WriteString("\nmessage " + msg.GetName() + " {\n")
+		for _, f := range msg.GetField() {
+			typ := strings.ToLower(strings.TrimPrefix(f.GetType().String(), "TYPE_"))
+			fmt.Fprintf(&buf, "  %s %s = %d", typ, f.GetName(), f.GetNumber())
+			if f.GetJsonName() != f.GetName() {
+				fmt.Fprintf(&buf, " [json_name = %q]", f.GetJsonName())
+			}
+			buf.WriteString(";\n")
+		}
+		buf.WriteString("}\n")
+	}
+	_, err := io.WriteString(w, buf.String())
+	return err
+}
+
+// protoFieldName returns the column name as a valid Protobuf field name.
+func protoFieldName(name string) string {
+	s := strings.Map(func(r rune) rune {
+		switch {
+		case 'a' <= r && r <= 'z', '0' <= r && r <= '9', r == '_':
+			return r
+		case 'A' <= r && r <= 'Z':
+			return r - 'A' + 'a'
+		}
+		return '_'
+	}, name)
+	if s == "" || s[0] < 'a' || s[0] > 'z' {
+		s = "f_" + s
+	}
+	return s
+}
@@ -0,0 +1,212 @@
+// Copyright 2021 Tamás Gulácsi.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package dbcsv
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// ParseProtoFile parses the .proto file, and returns the descriptor of the message
+// (its full name, or just its name if that is unique).
+func ParseProtoFile(filename, message string) (protoreflect.MessageDescriptor, error) {
+	fds, err := protoparse.Parser{}.ParseFiles(filename)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	files, err := protodesc.NewFiles(desc.ToFileDescriptorSet(fds...))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	if d, err := files.FindDescriptorByName(protoreflect.FullName(message)); err == nil {
+		if md, ok := d.(protoreflect.MessageDescriptor); ok {
+			return md, nil
+		}
+	}
+	var found []protoreflect.MessageDescriptor
+	files.RangeFiles(func(fd protoreflect.FileDescriptor) bool {
+		msgs := fd.Messages()
+		for i := 0; i < msgs.Len(); i++ {
+			if md := msgs.Get(i); string(md.Name()) == message {
+				found = append(found, md)
+			}
+		}
+		return true
+	})
+	switch len(found) {
+	case 0:
+		return nil, fmt.Errorf("%s: no message %q", filename, message)
+	case 1:
+		return found[0], nil
+	}
+	return nil, fmt.Errorf("%s: message %q is ambiguous, use its full name", filename, message)
+}
+
+// protoFieldOf returns the field of the message for the column: by its name (as in ProtoDescriptor),
+// or by its name or JSON name, case-insensitively.
+func protoFieldOf(md protoreflect.MessageDescriptor, name string) protoreflect.FieldDescriptor {
+	fields := md.Fields()
+	if fd := fields.ByName(protoreflect.Name(protoFieldName(name))); fd != nil {
+		return fd
+	}
+	for i := 0; i < fields.Len(); i++ {
+		if fd := fields.Get(i); strings.EqualFold(string(fd.Name()), name) || strings.EqualFold(fd.JSONName(), name) {
+			return fd
+		}
+	}
+	return nil
+}
+
+// DumpProtoJSON writes the rows as messages of md in the canonical Protobuf JSON encoding (protojson),
+// one message per line.
+//
+// Each column must have a (singular) field in the message, see protoFieldOf;
+// NULLs leave the field unset. Dates can be written to string and google.protobuf.Timestamp fields.
+func DumpProtoJSON(ctx context.Context, w io.Writer, rows Rows, columns []Column, md protoreflect.MessageDescriptor, opts DumperOptions, Log func(...interface{}) error) (int, error) {
+	dest := make([]interface{}, len(columns))
+	values := make([]Stringer, len(columns))
+	fields := make([]protoreflect.FieldDescriptor, len(columns))
+	for i, col := range columns {
+		c := col.Converter(opts)
+		values[i] = c
+		dest[i] = c.Pointer()
+		fd := protoFieldOf(md, col.Name)
+		if fd == nil {
+			return 0, fmt.Errorf("%s: no field in %s", col.Name, md.FullName())
+		}
+		if fd.Cardinality() == protoreflect.Repeated {
+			return 0, fmt.Errorf("%s: %s is repeated", col.Name, fd.FullName())
+		}
+		fields[i] = fd
+	}
+	bw := bufio.NewWriter(w)
+	var buf bytes.Buffer
+	msg := dynamicpb.NewMessage(md)
+	start := time.Now()
+	n := 0
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return n, fmt.Errorf("scan into %#v: %w", dest, err)
+		}
+		msg.Reset()
+		for i, fd := range fields {
+			x := TypedValue(values[i])
+			if x == nil {
+				continue
+			}
+			pv, err := protoValue(fd, x, values[i])
+			if err != nil {
+				return n, fmt.Errorf("%s: %w", columns[i].Name, err)
+			}
+			msg.Set(fd, pv)
+		}
+		b, err := protojson.Marshal(msg)
+		if err != nil {
+			return n, err
+		}
+		// protojson's output is deliberately unstable in its whitespace
+		buf.Reset()
+		if err = json.Compact(&buf, b); err != nil {
+			return n, err
+		}
+		buf.WriteByte('\n')
+		if _, err = bw.Write(buf.Bytes()); err != nil {
+			return n, err
+		}
+		n++
+		if err := ctx.Err(); err != nil {
+			return n, err
+		}
+	}
+	err := rows.Err()
+	if flushErr := bw.Flush(); flushErr != nil && err == nil {
+		err = flushErr
+	}
+	dur := time.Since(start)
+	if Log != nil {
+		_ = Log("msg", "dump finished", "rows", n, "dur", dur, "speed", float64(n)/float64(dur)*float64(time.Second), "error", err)
+	}
+	return n, err
+}
+
+// protoValue converts the (non-nil) TypedValue x of s to the value of the field.
+func protoValue(fd protoreflect.FieldDescriptor, x interface{}, s Stringer) (protoreflect.Value, error) {
+	raw := StringRaw(s)
+	if t, ok := x.(time.Time); ok {
+		switch {
+		case fd.Kind() == protoreflect.StringKind:
+			return protoreflect.ValueOfString(t.Format(time.RFC3339Nano)), nil
+		case fd.Kind() == protoreflect.MessageKind && fd.Message().FullName() == "google.protobuf.Timestamp":
+			return protoreflect.ValueOfMessage(timestamppb.New(t).ProtoReflect()), nil
+		}
+		return protoreflect.Value{}, fmt.Errorf("cannot write date to %s field %s", fd.Kind(), fd.FullName())
+	}
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString(raw), nil
+	case protoreflect.BytesKind:
+		return protoreflect.ValueOfBytes([]byte(raw)), nil
+	case protoreflect.BoolKind:
+		if b, ok := x.(bool); ok {
+			return protoreflect.ValueOfBool(b), nil
+		}
+		b, err := strconv.ParseBool(raw)
+		return protoreflect.ValueOfBool(b), err
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		i, err := strconv.ParseInt(raw, 10, 32)
+		return protoreflect.ValueOfInt32(int32(i)), err
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		i, err := strconv.ParseInt(raw, 10, 64)
+		return protoreflect.ValueOfInt64(i), err
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		i, err := strconv.ParseUint(raw, 10, 32)
+		return protoreflect.ValueOfUint32(uint32(i)), err
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		i, err := strconv.ParseUint(raw, 10, 64)
+		return protoreflect.ValueOfUint64(i), err
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		f, ok := x.(float64)
+		if i, isInt := x.(int64); isInt {
+			f, ok = float64(i), true
+		}
+		var err error
+		if !ok {
+			f, err = strconv.ParseFloat(raw, 64)
+		}
+		if fd.Kind() == protoreflect.FloatKind {
+			if math.Abs(f) > math.MaxFloat32 && !math.IsInf(f, 0) {
+				return protoreflect.Value{}, fmt.Errorf("%v overflows float", f)
+			}
+			return protoreflect.ValueOfFloat32(float32(f)), err
+		}
+		return protoreflect.ValueOfFloat64(f), err
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByName(protoreflect.Name(raw)); ev != nil {
+			return protoreflect.ValueOfEnum(ev.Number()), nil
+		}
+		i, err := strconv.ParseInt(raw, 10, 32)
+		if err != nil {
+			return protoreflect.Value{}, fmt.Errorf("%q is not a value of %s", raw, fd.Enum().FullName())
+		}
+		return protoreflect.ValueOfEnum(protoreflect.EnumNumber(i)), nil
+	}
+	return protoreflect.Value{}, fmt.Errorf("unsupported %s field %s", fd.Kind(), fd.FullName())
+}
@@ -0,0 +1,35 @@
+// Copyright 2021 Tamás Gulácsi.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package dbcsv
+
+import (
+	"log"
+	"strings"
+
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// QRMaxLength is the maximum number of bytes a QR code can hold (with the lowest error correction).
+const QRMaxLength = 4296
+
+// NewQRWrapper returns a StringerWrapper that writes the values as QR codes drawn with
+// (Unicode half block) characters, in multiple lines - so the CSV values get quoted.
+// This is practical only for terminal output. The values longer than QRMaxLength are written as is, with a warning.
+func NewQRWrapper(name string) StringerWrapper {
+	return func(s Stringer, sep string) Stringer {
+		return &MapStringer{Stringer: s, Sep: sep, Map: func(v string) string {
+			if len(v) > QRMaxLength {
+				log.Printf("[WARN] %s: %d bytes is longer than the QR code capacity (%d)", name, len(v), QRMaxLength)
+				return v
+			}
+			q, err := qrcode.New(v, qrcode.Low)
+			if err != nil {
+				log.Printf("[WARN] %s: %q: %+v", name, v, err)
+				return v
+			}
+			return strings.TrimRight(q.ToSmallString(false), "\n")
+		}}
+	}
+}
@@ -0,0 +1,336 @@
+// Copyright 2021 Tamás Gulácsi.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package dbcsv
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apache/arrow/go/v10/arrow"
+	"github.com/apache/arrow/go/v10/arrow/array"
+	"github.com/apache/arrow/go/v10/arrow/ipc"
+	"github.com/apache/arrow/go/v10/arrow/memory"
+)
+
+// ErrCacheMiss is returned by QueryCache.Open when there is no fresh cache file.
+var ErrCacheMiss = errors.New("query cache miss")
+
+// QueryCache caches the query results in Arrow IPC stream files in Dir, for TTL (forever if 0).
+//
+// The integers, floats, booleans and binary values are cached as such, the dates as their wall clock
+// (the time zone is lost), everything else as strings.
+type QueryCache struct {
+	Dir string
+	TTL time.Duration
+}
+
+// QueryCacheKey returns the cache key of the query and its parameters:
+// the hex SHA-256 of the query with its whitespace collapsed and the trailing semicolon trimmed, and the parameters.
+func QueryCacheKey(qry string, params []interface{}) string {
+	h := sha256.New()
+	h.Write([]byte(strings.TrimRight(strings.Join(strings.Fields(qry), " "), "; ")))
+	for _, p := range params {
+		fmt.Fprintf(h, "\x00%#v", p)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (qc QueryCache) fileName(key string) string { return filepath.Join(qc.Dir, key+".arrows") }
+
+// Open returns the cached rows of the key, or ErrCacheMiss if they are missing or older than TTL.
+func (qc QueryCache) Open(key string) (Rows, []Column, error) {
+	fn := qc.fileName(key)
+	fi, err := os.Stat(fn)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil, ErrCacheMiss
+		}
+		return nil, nil, err
+	}
+	if qc.TTL > 0 && time.Since(fi.ModTime()) > qc.TTL {
+		return nil, nil, ErrCacheMiss
+	}
+	fh, err := os.Open(fn)
+	if err != nil {
+		return nil, nil, err
+	}
+	rdr, err := ipc.NewReader(fh)
+	if err != nil {
+		fh.Close()
+		return nil, nil, fmt.Errorf("%s: %w", fn, err)
+	}
+	fields := rdr.Schema().Fields()
+	columns := make([]Column, len(fields))
+	for i, f := range fields {
+		columns[i] = Column{Name: f.Name, NotNull: !f.Nullable, Type: cacheGoType(f.Type)}
+		if j := f.Metadata.FindKey("database_type"); j >= 0 {
+			columns[i].DatabaseTypeName = f.Metadata.Values()[j]
+		}
+		if j := f.Metadata.FindKey("scale"); j >= 0 {
+			columns[i].Scale, _ = strconv.ParseInt(f.Metadata.Values()[j], 10, 64)
+		}
+	}
+	return &cacheRows{rdr: rdr, fh: fh}, columns, nil
+}
+
+// Tee returns the rows, writing them into the cache file of the key, too.
+// The cache file is written only if all the rows are read without error, when the returned rows are closed.
+func (qc QueryCache) Tee(key string, rows Rows, columns []Column) (Rows, error) {
+	if err := os.MkdirAll(qc.Dir, 0750); err != nil {
+		return nil, err
+	}
+	fn := qc.fileName(key)
+	fh, err := os.CreateTemp(qc.Dir, filepath.Base(fn)+".*")
+	if err != nil {
+		return nil, err
+	}
+	fields := make([]arrow.Field, len(columns))
+	tr := teeRows{Rows: rows, fh: fh, fileName: fn,
+		dest: make([]interface{}, len(columns)), values: make([]interface{}, len(columns)),
+		converters: make([]Stringer, len(columns)),
+	}
+	for i, col := range columns {
+		c := col.Converter(DumperOptions{})
+		tr.converters[i] = c
+		tr.dest[i] = c.Pointer()
+		fields[i] = arrow.Field{Name: col.Name, Type: cacheArrowType(c), Nullable: !col.NotNull,
+			Metadata: arrow.NewMetadata(
+				[]string{"database_type", "scale"},
+				[]string{col.DatabaseTypeName, strconv.FormatInt(col.Scale, 10)},
+			),
+		}
+	}
+	schema := arrow.NewSchema(fields, nil)
+	tr.w = ipc.NewWriter(fh, ipc.WithSchema(schema))
+	tr.rb = array.NewRecordBuilder(memory.DefaultAllocator, schema)
+	return &tr, nil
+}
+
+// cacheRecordSize is the number of rows of an Arrow record of the cache file.
+const cacheRecordSize = 1024
+
+// cacheArrowType returns the Arrow type of the values of the Stringer in the cache.
+func cacheArrowType(s Stringer) arrow.DataType {
+	switch s.(type) {
+	case *ValInt, *LocalizedValInt:
+		return arrow.PrimitiveTypes.Int64
+	case *ValUint:
+		return arrow.PrimitiveTypes.Uint64
+	case *ValFloat, *LocalizedValFloat:
+		return arrow.PrimitiveTypes.Float64
+	case *ValTime, *LocalizedValTime:
+		return &arrow.TimestampType{Unit: arrow.Microsecond}
+	case *ValBool:
+		return arrow.FixedWidthTypes.Boolean
+	case *ValBlob:
+		return arrow.BinaryTypes.Binary
+	}
+	return arrow.BinaryTypes.String
+}
+
+// cacheGoType returns the Go type of the column of the cached Arrow type.
+func cacheGoType(typ arrow.DataType) reflect.Type {
+	switch typ.ID() {
+	case arrow.INT64:
+		return typeOfInt64
+	case arrow.UINT64:
+		return reflect.TypeOf(uint64(0))
+	case arrow.FLOAT64:
+		return typeOfFloat64
+	case arrow.TIMESTAMP:
+		return typeOfTime
+	case arrow.BOOL:
+		return reflect.TypeOf(false)
+	case arrow.BINARY:
+		return reflect.TypeOf([]byte(nil))
+	}
+	return typeOfString
+}
+
+// teeRows writes the rows into the cache file while they are read.
+type teeRows struct {
+	Rows
+	w          *ipc.Writer
+	rb         *array.RecordBuilder
+	fh         *os.File
+	fileName   string
+	dest       []interface{}
+	values     []interface{}
+	converters []Stringer
+	n          int
+	done       bool
+	err        error
+}
+
+func (tr *teeRows) Next() bool {
+	if tr.err != nil {
+		return false
+	}
+	if tr.Rows.Next() {
+		return true
+	}
+	tr.done = tr.Rows.Err() == nil
+	return false
+}
+
+func (tr *teeRows) Err() error {
+	if tr.err != nil {
+		return tr.err
+	}
+	return tr.Rows.Err()
+}
+
+func (tr *teeRows) Scan(dest ...interface{}) error {
+	if tr.err = tr.Rows.Scan(tr.dest...); tr.err != nil {
+		return tr.err
+	}
+	for i, c := range tr.converters {
+		var v interface{}
+		if x, ok := c.(*ValBlob); ok {
+			if x.Valid {
+				v = x.Value
+			}
+		} else {
+			v = ScannedValue(tr.dest[i])
+		}
+		tr.values[i] = v
+		if v == nil {
+			tr.rb.Field(i).AppendNull()
+			continue
+		}
+		switch b := tr.rb.Field(i).(type) {
+		case *array.Int64Builder:
+			b.Append(v.(int64))
+		case *array.Uint64Builder:
+			b.Append(uint64(c.(*ValUint).Value.Int64))
+		case *array.Float64Builder:
+			b.Append(v.(float64))
+		case *array.TimestampBuilder:
+			t := v.(time.Time)
+			wall := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+			b.Append(arrow.Timestamp(wall.UnixMicro()))
+		case *array.BooleanBuilder:
+			b.Append(v.(bool))
+		case *array.BinaryBuilder:
+			b.Append(v.([]byte))
+		case *array.StringBuilder:
+			s, _ := formatValue(v)
+			b.Append(s)
+		}
+	}
+	if tr.n++; tr.n%cacheRecordSize == 0 {
+		if tr.err = tr.flush(); tr.err != nil {
+			return tr.err
+		}
+	}
+	return scanValues(dest, tr.values)
+}
+
+func (tr *teeRows) flush() error {
+	rec := tr.rb.NewRecord()
+	defer rec.Release()
+	return tr.w.Write(rec)
+}
+
+// Close the rows, and rename the cache file into place if all the rows are written.
+func (tr *teeRows) Close() error {
+	err := tr.Rows.Close()
+	defer tr.rb.Release()
+	ok := tr.done && tr.err == nil
+	if ok && tr.n%cacheRecordSize != 0 {
+		ok = tr.flush() == nil
+	}
+	if closeErr := tr.w.Close(); closeErr != nil {
+		ok = false
+	}
+	if closeErr := tr.fh.Close(); closeErr != nil {
+		ok = false
+	}
+	if !ok || os.Rename(tr.fh.Name(), tr.fileName) != nil {
+		_ = os.Remove(tr.fh.Name())
+	}
+	return err
+}
+
+// cacheRows reads the rows from a cache file.
+type cacheRows struct {
+	rdr    *ipc.Reader
+	fh     *os.File
+	rec    arrow.Record
+	i      int
+	values []interface{}
+}
+
+func (cr *cacheRows) Next() bool {
+	for cr.rec == nil || cr.i+1 >= int(cr.rec.NumRows()) {
+		if !cr.rdr.Next() {
+			cr.rec = nil
+			return false
+		}
+		cr.rec, cr.i = cr.rdr.Record(), -1
+	}
+	cr.i++
+	if cr.values == nil {
+		cr.values = make([]interface{}, cr.rec.NumCols())
+	}
+	for j, col := range cr.rec.Columns() {
+		cr.values[j] = cacheValue(col, cr.i)
+	}
+	return true
+}
+
+// cacheValue returns the i-th value of the cached column arr.
+func cacheValue(arr arrow.Array, i int) interface{} {
+	if arr.IsNull(i) {
+		return nil
+	}
+	switch a := arr.(type) {
+	case *array.Int64:
+		return a.Value(i)
+	case *array.Uint64:
+		return a.Value(i)
+	case *array.Float64:
+		return a.Value(i)
+	case *array.Timestamp:
+		return time.UnixMicro(int64(a.Value(i))).UTC()
+	case *array.Boolean:
+		return a.Value(i)
+	case *array.Binary:
+		return a.Value(i)
+	case *array.String:
+		return a.Value(i)
+	}
+	return nil
+}
+
+func (cr *cacheRows) Scan(dest ...interface{}) error { return scanValues(dest, cr.values) }
+func (cr *cacheRows) Err() error                     { return cr.rdr.Err() }
+func (cr *cacheRows) Close() error {
+	cr.rdr.Release()
+	return cr.fh.Close()
+}
+
+// scanValues scans the values into dest (sql.Scanners).
+func scanValues(dest []interface{}, values []interface{}) error {
+	for j, d := range dest {
+		scanner, ok := d.(sql.Scanner)
+		if !ok {
+			return fmt.Errorf("%d. column: cannot scan into %T", j, d)
+		}
+		if err := scanner.Scan(values[j]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
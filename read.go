@@ -75,6 +75,7 @@ const (
 	Csv     = FType("csv")
 	Xls     = FType("xls")
 	XlsX    = FType("xlsx")
+	Ods     = FType("ods")
 	Gzip    = FType("gzip")
 	Zstd    = FType("zstd")
 )
@@ -88,7 +89,13 @@ func DetectReaderType(r io.Reader, fileName string) (FileType, error) {
 	}
 	if bytes.Equal(b[:], []byte{0xd0, 0xcf, 0x11, 0xe0}) { // OLE2
 		return FileType{Type: Xls}, nil
-	} else if bytes.Equal(b[:], []byte{0x50, 0x4b, 0x03, 0x04}) { //PKZip, so xlsx
+	} else if bytes.Equal(b[:], []byte{0x50, 0x4b, 0x03, 0x04}) { //PKZip, so xlsx or ods
+		// ODS starts with the uncompressed "mimetype" file
+		var rest [26 + len("mimetype") + len(odsMimeType)]byte
+		if _, err := io.ReadFull(r, rest[:]); err == nil &&
+			bytes.HasSuffix(rest[:], []byte("mimetype"+odsMimeType)) {
+			return FileType{Type: Ods}, nil
+		}
 		return FileType{Type: XlsX}, nil
 	}
 	if bytes.Equal(b[:3], []byte{0x1f, 0x8b, 0x8}) { // GZIP
@@ -125,8 +132,10 @@ type Config struct {
 	Charset       string
 	ColumnsString string
 	fileName      string
-	columns       []int
-	Sheet, Skip   int
+	// tmpName is the temporary copy of the input, removed by Close
+	tmpName     string
+	columns     []int
+	Sheet, Skip int
 }
 
 func (cfg *Config) Encoding() (encoding.Encoding, error) {
@@ -219,7 +228,12 @@ func (cfg *Config) Open(fileName string) error {
 		}
 		defer fh.Close()
 		fileName = fh.Name()
-		defer os.Remove(fileName)
+		removeTmp := true
+		defer func() {
+			if removeTmp {
+				os.Remove(fileName)
+			}
+		}()
 
 		compress := cfg.typ.Type == Csv
 		w := io.WriteCloser(fh)
@@ -247,12 +261,14 @@ func (cfg *Config) Open(fileName string) error {
 		if cfg.file, err = os.Open(fh.Name()); err != nil {
 			return err
 		}
-		os.Remove(fh.Name())
 		if compress {
 			if cfg.zr, err = zstd.NewReader(cfg.file); err != nil {
 				return err
 			}
 			cfg.rdr = cfg.zr.IOReadCloser()
+		} else {
+			// the spreadsheet readers open the file by its name
+			cfg.tmpName, removeTmp = fh.Name(), false
 		}
 	}
 	cfg.fileName = fileName
@@ -267,8 +283,11 @@ func (cfg *Config) Open(fileName string) error {
 }
 
 func (cfg *Config) Close() error {
-	zr, rdr, fh := cfg.zr, cfg.rdr, cfg.file
-	cfg.zr, cfg.rdr, cfg.file, cfg.fileName, cfg.typ = nil, nil, nil, "", FileType{Type: Unknown}
+	zr, rdr, fh, tmpName := cfg.zr, cfg.rdr, cfg.file, cfg.tmpName
+	cfg.zr, cfg.rdr, cfg.file, cfg.fileName, cfg.tmpName, cfg.typ = nil, nil, nil, "", "", FileType{Type: Unknown}
+	if tmpName != "" {
+		defer os.Remove(tmpName)
+	}
 	var err error
 	if zr != nil {
 		zr.Close()
@@ -298,6 +317,8 @@ func (cfg *Config) ReadRows(ctx context.Context, fn func(string, Row) error) (er
 		return ReadXLSFile(ctx, fn, cfg.fileName, cfg.Charset, cfg.Sheet, cfg.columns, cfg.Skip)
 	case XlsX:
 		return ReadXLSXFile(ctx, fn, cfg.fileName, cfg.Sheet, cfg.columns, cfg.Skip)
+	case Ods:
+		return ReadODSFile(ctx, fn, cfg.fileName, cfg.Sheet, cfg.columns, cfg.Skip)
 	}
 	enc, err := cfg.Encoding()
 	if err != nil {
@@ -347,6 +368,8 @@ func (cfg *Config) ReadSheets(ctx context.Context) (map[int]string, error) {
 			return nil, err
 		}
 		return xlFile.GetSheetMap(), nil
+	case Ods:
+		return ReadODSSheets(ctx, cfg.fileName)
 	}
 	// CSV
 	return map[int]string{1: cfg.fileName}, nil
@@ -5,6 +5,7 @@
 package dbcsv_test
 
 import (
+	"compress/gzip"
 	"context"
 	"fmt"
 	"os"
@@ -13,6 +14,8 @@ import (
 	"time"
 
 	"github.com/UNO-SOFT/dbcsv"
+	"github.com/UNO-SOFT/spreadsheet"
+	"github.com/UNO-SOFT/spreadsheet/xlsx"
 )
 
 func TestRead(t *testing.T) {
@@ -95,3 +98,60 @@ func TestCompressedTempCSV(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestGzipXLSXRoundTrip(t *testing.T) {
+	fn := filepath.Join(t.TempDir(), "out.xlsx.gz")
+	fh, err := os.Create(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fh.Close()
+	zw := gzip.NewWriter(fh)
+	w := xlsx.NewWriter(zw)
+	columns := []dbcsv.Column{{Name: "ID", Type: typeOfInt64}, {Name: "NAME", Type: typeOfString}}
+	sheet, err := w.NewSheet("data", []spreadsheet.Column{{Name: "ID"}, {Name: "NAME"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := &sliceRows{values: [][]interface{}{{int64(1), "árvíztűrő"}, {int64(2), "tükörfúrógép"}}}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if err = dbcsv.DumpSheet(ctx, sheet, rows, columns, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err = sheet.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = fh.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var cfg dbcsv.Config
+	if err = cfg.Open(fn); err != nil {
+		t.Fatal(err)
+	}
+	defer cfg.Close()
+	typ, err := cfg.Type()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if typ.Type != dbcsv.XlsX || typ.Compression != dbcsv.Gzip {
+		t.Fatalf("got type %v, wanted gzipped XLSX", typ)
+	}
+	var got []string
+	if err = cfg.ReadRows(ctx, func(_ string, row dbcsv.Row) error {
+		got = append(got, fmt.Sprintf("%v", row.Values))
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"[ID NAME]", "[1 árvíztűrő]", "[2 tükörfúrógép]"}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+}
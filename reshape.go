@@ -0,0 +1,114 @@
+// Copyright 2021 Tamás Gulácsi.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package dbcsv
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// SplitRows splits the value of the Index-th column on Sep into the new (string) columns,
+// inserted right after the source column.
+//
+// The value is split into at most as many parts as new columns; missing parts are empty strings,
+// and all the parts of a NULL are NULLs.
+type SplitRows struct {
+	Rows
+	Index int
+	Sep   string
+	N     int
+	dest  []interface{}
+}
+
+// NewSplitRows returns the SplitRows splitting the index-th column into the named columns.
+func NewSplitRows(rows Rows, columns []Column, index int, sep string, names []string) (*SplitRows, []Column) {
+	cols := make([]Column, 0, len(columns)+len(names))
+	cols = append(cols, columns[:index+1]...)
+	for _, nm := range names {
+		cols = append(cols, Column{Name: nm, Type: typeOfString})
+	}
+	cols = append(cols, columns[index+1:]...)
+	return &SplitRows{Rows: rows, Index: index, Sep: sep, N: len(names)}, cols
+}
+
+func (sr *SplitRows) Scan(dest ...interface{}) error {
+	if len(dest) < sr.Index+1+sr.N {
+		return fmt.Errorf("split: got %d dest, wanted at least %d", len(dest), sr.Index+1+sr.N)
+	}
+	sr.dest = append(append(sr.dest[:0], dest[:sr.Index+1]...), dest[sr.Index+1+sr.N:]...)
+	if err := sr.Rows.Scan(sr.dest...); err != nil {
+		return err
+	}
+	s, ok := ScannedString(dest[sr.Index])
+	var parts []string
+	if ok {
+		parts = strings.SplitN(s, sr.Sep, sr.N)
+	}
+	for k, d := range dest[sr.Index+1 : sr.Index+1+sr.N] {
+		var v interface{}
+		if ok {
+			v = ""
+			if k < len(parts) {
+				v = parts[k]
+			}
+		}
+		scanner, isScanner := d.(sql.Scanner)
+		if !isScanner {
+			return fmt.Errorf("%d. column: cannot scan into %T", sr.Index+1+k, d)
+		}
+		if err := scanner.Scan(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DropColumns returns the rows (and columns) without the columns of the given indexes.
+func DropColumns(rows Rows, columns []Column, drop ...int) (Rows, []Column) {
+	if len(drop) == 0 {
+		return rows, columns
+	}
+	dr := dropRows{Rows: rows, dropped: make([]interface{}, len(columns))}
+	cols := make([]Column, 0, len(columns))
+	for i, col := range columns {
+		var isDropped bool
+		for _, j := range drop {
+			if i == j {
+				isDropped = true
+				break
+			}
+		}
+		if isDropped {
+			dr.dropped[i] = col.Converter(DumperOptions{}).Pointer()
+		} else {
+			cols = append(cols, col)
+		}
+	}
+	dr.dest = make([]interface{}, len(columns))
+	return &dr, cols
+}
+
+type dropRows struct {
+	Rows
+	// dropped holds the scan destinations of the dropped columns, nil for the others.
+	dropped, dest []interface{}
+}
+
+func (dr *dropRows) Scan(dest ...interface{}) error {
+	j := 0
+	for i, d := range dr.dropped {
+		if d != nil {
+			dr.dest[i] = d
+			continue
+		}
+		if j >= len(dest) {
+			return fmt.Errorf("drop: got %d dest, wanted more", len(dest))
+		}
+		dr.dest[i] = dest[j]
+		j++
+	}
+	return dr.Rows.Scan(dr.dest...)
+}
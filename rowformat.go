@@ -0,0 +1,208 @@
+// Copyright 2021 Tamás Gulácsi.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package dbcsv
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	lua "github.com/yuin/gopher-lua"
+	"go.starlark.net/starlark"
+)
+
+// RowFormatter formats a whole row.
+//
+// The values are the JSONValue of the columns: nil, int64, float64 or string.
+type RowFormatter interface {
+	FormatRow(columns []Column, values []interface{}) (string, error)
+}
+
+// DumpFormatted writes each row formatted by f, as a separate line, to w.
+func DumpFormatted(ctx context.Context, w io.Writer, rows Rows, columns []Column, f RowFormatter, opts DumperOptions, Log func(...interface{}) error) (int, error) {
+	dest := make([]interface{}, len(columns))
+	values := make([]Stringer, len(columns))
+	for i, col := range columns {
+		c := col.Converter(opts)
+		values[i] = c
+		dest[i] = c.Pointer()
+	}
+	bw := bufio.NewWriterSize(w, 65536)
+	defer bw.Flush()
+	start := time.Now()
+	vals := make([]interface{}, len(values))
+	n := 0
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return n, fmt.Errorf("scan into %#v: %w", dest, err)
+		}
+		n++
+		for i, v := range values {
+			vals[i] = JSONValue(v)
+		}
+		s, err := f.FormatRow(columns, vals)
+		if err != nil {
+			return n, fmt.Errorf("%d. row: %w", n, err)
+		}
+		bw.WriteString(s)
+		if err = bw.WriteByte('\n'); err != nil {
+			return n, err
+		}
+		if err := ctx.Err(); err != nil {
+			return n, err
+		}
+	}
+	err := rows.Err()
+	dur := time.Since(start)
+	if Log != nil {
+		_ = Log("msg", "dump finished", "rows", n, "dur", dur, "speed", float64(n)/float64(dur)*float64(time.Second), "error", err)
+	}
+	if err != nil {
+		return n, err
+	}
+	return n, bw.Flush()
+}
+
+// SparseFormatter writes only the columns whose value is neither NULL nor Default,
+// as "name:value" pairs (CSV quoted), separated by Sep.
+type SparseFormatter struct {
+	Sep, Default string
+}
+
+func (f SparseFormatter) FormatRow(columns []Column, values []interface{}) (string, error) {
+	var buf strings.Builder
+	for i, v := range values {
+		var s string
+		switch x := v.(type) {
+		case nil:
+			continue
+		case int64:
+			s = strconv.FormatInt(x, 10)
+		case float64:
+			s = strconv.FormatFloat(x, 'f', -1, 64)
+		case string:
+			s = x
+		default:
+			s = fmt.Sprint(x)
+		}
+		if s == f.Default {
+			continue
+		}
+		if buf.Len() != 0 {
+			buf.WriteString(f.Sep)
+		}
+		buf.WriteString(csvQuoteString(f.Sep, columns[i].Name+":"+s))
+	}
+	return buf.String(), nil
+}
+
+// LuaFormatter calls the format_row(cols) function of a Lua script for each row,
+// with a table of the column name → value pairs, and uses the returned string.
+//
+// The Lua state is reused for all the rows, so it must not be used concurrently.
+type LuaFormatter struct {
+	state *lua.LState
+	fn    lua.LValue
+	cols  *lua.LTable
+}
+
+// NewLuaFormatter loads the Lua script from the file.
+func NewLuaFormatter(fileName string) (*LuaFormatter, error) {
+	L := lua.NewState()
+	if err := L.DoFile(fileName); err != nil {
+		L.Close()
+		return nil, fmt.Errorf("%s: %w", fileName, err)
+	}
+	fn := L.GetGlobal("format_row")
+	if fn.Type() != lua.LTFunction {
+		L.Close()
+		return nil, fmt.Errorf("%s: no format_row function", fileName)
+	}
+	return &LuaFormatter{state: L, fn: fn, cols: L.NewTable()}, nil
+}
+
+// Close the Lua state.
+func (f *LuaFormatter) Close() error { f.state.Close(); return nil }
+
+func (f *LuaFormatter) FormatRow(columns []Column, values []interface{}) (string, error) {
+	for i, v := range values {
+		var lv lua.LValue = lua.LNil
+		switch x := v.(type) {
+		case int64:
+			lv = lua.LNumber(x)
+		case float64:
+			lv = lua.LNumber(x)
+		case string:
+			lv = lua.LString(x)
+		}
+		f.cols.RawSetString(columns[i].Name, lv)
+	}
+	if err := f.state.CallByParam(lua.P{Fn: f.fn, NRet: 1, Protect: true}, f.cols); err != nil {
+		return "", err
+	}
+	ret := f.state.Get(-1)
+	f.state.Pop(1)
+	if s, ok := ret.(lua.LString); ok {
+		return string(s), nil
+	}
+	if ret.Type() == lua.LTNumber {
+		return ret.String(), nil
+	}
+	return "", fmt.Errorf("format_row returned %s, not string", ret.Type())
+}
+
+// StarlarkFormatter calls the format_row(cols) function of a Starlark script for each row,
+// with a dict of the column name → value pairs (in column order), and uses the returned string.
+//
+// Starlark has no access to the OS, and the script's globals are frozen after loading.
+type StarlarkFormatter struct {
+	thread *starlark.Thread
+	fn     starlark.Callable
+}
+
+// NewStarlarkFormatter loads the Starlark script from the file.
+func NewStarlarkFormatter(fileName string) (*StarlarkFormatter, error) {
+	thread := &starlark.Thread{Name: fileName}
+	globals, err := starlark.ExecFile(thread, fileName, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fileName, err)
+	}
+	fn, ok := globals["format_row"].(starlark.Callable)
+	if !ok {
+		return nil, fmt.Errorf("%s: no format_row function", fileName)
+	}
+	return &StarlarkFormatter{thread: thread, fn: fn}, nil
+}
+
+func (f *StarlarkFormatter) FormatRow(columns []Column, values []interface{}) (string, error) {
+	cols := starlark.NewDict(len(values))
+	for i, v := range values {
+		var sv starlark.Value = starlark.None
+		switch x := v.(type) {
+		case int64:
+			sv = starlark.MakeInt64(x)
+		case float64:
+			sv = starlark.Float(x)
+		case string:
+			sv = starlark.String(x)
+		}
+		if err := cols.SetKey(starlark.String(columns[i].Name), sv); err != nil {
+			return "", err
+		}
+	}
+	ret, err := starlark.Call(f.thread, f.fn, starlark.Tuple{cols}, nil)
+	if err != nil {
+		return "", err
+	}
+	s, ok := starlark.AsString(ret)
+	if !ok {
+		return "", fmt.Errorf("format_row returned %s, not string", ret.Type())
+	}
+	return s, nil
+}
@@ -0,0 +1,247 @@
+// Copyright 2021 Tamás Gulácsi.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package dbcsv
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+)
+
+// SplitByOptions are the options of DumpCSVSplitBy.
+type SplitByOptions struct {
+	// Create returns the writer of the partition of the value (the raw string of the column).
+	Create func(value string) (io.WriteCloser, error)
+	// CreateNull returns the writer of the NULLs' partition, kept apart from every value (even from "NULL").
+	// The NULLs are an error if it is nil.
+	CreateNull func() (io.WriteCloser, error)
+	// Column to partition by.
+	Column string
+	// MaxFiles is the number of partitions above which a warning is logged (0 means no warning).
+	MaxFiles int
+	// DumperOptions are the options of the CSV of each partition (TSV is not supported).
+	DumperOptions
+}
+
+// DumpCSVSplitBy writes the rows as CSV (see DumpCSV) into separate writers by the distinct values of the column.
+// Each writer gets its own header. All the writers are closed at the end.
+func DumpCSVSplitBy(ctx context.Context, rows Rows, columns []Column, opts SplitByOptions, Log func(...interface{}) error) (n int, err error) {
+	by := -1
+	for i, c := range columns {
+		if strings.EqualFold(c.Name, opts.Column) {
+			by = i
+			break
+		}
+	}
+	if by < 0 {
+		return n, fmt.Errorf("%s: unknown column", opts.Column)
+	}
+	sepB, null := []byte(opts.Sep), csvNull(opts.NullValue, opts.Sep, opts.Raw)
+	dest := make([]interface{}, len(columns))
+	values := make([]Stringer, len(columns))
+	for i, col := range columns {
+		c := col.Converter(opts.DumperOptions)
+		values[i] = c
+		dest[i] = c.Pointer()
+	}
+
+	type partition struct {
+		io.WriteCloser
+		bw *bufio.Writer
+	}
+	type key struct {
+		value string
+		null  bool
+	}
+	parts := make(map[key]partition)
+	defer func() {
+		for k, p := range parts {
+			flushErr := p.bw.Flush()
+			if closeErr := p.Close(); closeErr != nil && flushErr == nil {
+				flushErr = closeErr
+			}
+			if flushErr != nil && err == nil {
+				if k.null {
+					k.value = "NULL"
+				}
+				err = fmt.Errorf("%s: %w", k.value, flushErr)
+			}
+		}
+	}()
+
+	start := time.Now()
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return n, fmt.Errorf("scan into %#v: %w", dest, err)
+		}
+		k := key{null: IsNull(values[by])}
+		if !k.null {
+			k.value = StringRaw(values[by])
+		}
+		p, ok := parts[k]
+		if !ok {
+			var w io.WriteCloser
+			var err error
+			if !k.null {
+				w, err = opts.Create(k.value)
+			} else if opts.CreateNull != nil {
+				w, err = opts.CreateNull()
+			} else {
+				err = fmt.Errorf("%s: NULL value, but no CreateNull", columns[by].Name)
+			}
+			if err != nil {
+				return n, err
+			}
+			p = partition{WriteCloser: w, bw: bufio.NewWriter(w)}
+			parts[k] = p
+			if len(parts) == opts.MaxFiles+1 && opts.MaxFiles > 0 {
+				log.Printf("[WARN] %s has more than %d distinct values", columns[by].Name, opts.MaxFiles)
+			}
+			if opts.Header && !opts.Raw {
+				if err := writeCSVHeader(p.bw, columns, opts.Sep); err != nil {
+					return n, err
+				}
+			}
+		}
+		if err := writeCSVRow(p.bw, dest, values, sepB, opts.Raw, null); err != nil {
+			return n, err
+		}
+		n++
+		if err := ctx.Err(); err != nil {
+			return n, err
+		}
+	}
+	err = rows.Err()
+	dur := time.Since(start)
+	if Log != nil {
+		_ = Log("msg", "dump finished", "rows", n, "files", len(parts), "dur", dur, "speed", float64(n)/float64(dur)*float64(time.Second), "error", err)
+	}
+	return n, err
+}
+
+// SplitRowsOptions are the options of DumpCSVSplitRows.
+type SplitRowsOptions struct {
+	// Create returns the writer of the part (numbered from 1).
+	Create func(part int) (io.WriteCloser, error)
+	// Rows is the maximum number of rows of a part.
+	Rows int
+	// DumperOptions are the options of the CSV of each part (TSV is not supported).
+	DumperOptions
+}
+
+// DumpCSVSplitRows writes the rows as CSV (see DumpCSV) into a new writer after every opts.Rows rows.
+// Each writer gets its own header, and is closed when full. Without rows, only the first part is written.
+func DumpCSVSplitRows(ctx context.Context, rows Rows, columns []Column, opts SplitRowsOptions, Log func(...interface{}) error) (n int, err error) {
+	if opts.Rows <= 0 {
+		return n, fmt.Errorf("rows per part must be positive, got %d", opts.Rows)
+	}
+	sepB, null := []byte(opts.Sep), csvNull(opts.NullValue, opts.Sep, opts.Raw)
+	dest := make([]interface{}, len(columns))
+	values := make([]Stringer, len(columns))
+	for i, col := range columns {
+		c := col.Converter(opts.DumperOptions)
+		values[i] = c
+		dest[i] = c.Pointer()
+	}
+
+	var part int
+	var w io.WriteCloser
+	var bw *bufio.Writer
+	closePart := func() error {
+		if w == nil {
+			return nil
+		}
+		err := bw.Flush()
+		if closeErr := w.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+		w = nil
+		if err != nil {
+			return fmt.Errorf("part %d: %w", part, err)
+		}
+		return nil
+	}
+	defer func() {
+		if closeErr := closePart(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+	nextPart := func() error {
+		if err := closePart(); err != nil {
+			return err
+		}
+		part++
+		var err error
+		if w, err = opts.Create(part); err != nil {
+			return err
+		}
+		bw = bufio.NewWriter(w)
+		if opts.Header && !opts.Raw {
+			return writeCSVHeader(bw, columns, opts.Sep)
+		}
+		return nil
+	}
+	if err := nextPart(); err != nil {
+		return n, err
+	}
+
+	start := time.Now()
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return n, fmt.Errorf("scan into %#v: %w", dest, err)
+		}
+		if n != 0 && n%opts.Rows == 0 {
+			if err := nextPart(); err != nil {
+				return n, err
+			}
+		}
+		if err := writeCSVRow(bw, dest, values, sepB, opts.Raw, null); err != nil {
+			return n, err
+		}
+		n++
+		if err := ctx.Err(); err != nil {
+			return n, err
+		}
+	}
+	err = rows.Err()
+	dur := time.Since(start)
+	if Log != nil {
+		_ = Log("msg", "dump finished", "rows", n, "files", part, "dur", dur, "speed", float64(n)/float64(dur)*float64(time.Second), "error", err)
+	}
+	return n, err
+}
+
+// SplitRowsFileName returns the file name of the part: base_001.ext, base_002.ext...
+func SplitRowsFileName(base, ext string, part int) string {
+	return fmt.Sprintf("%s_%03d%s", base, part, ext)
+}
+
+// SplitNullFileName returns the file name of the NULLs' partition: base_NULL.ext,
+// which differs from all the names SplitFileName returns.
+func SplitNullFileName(base, ext string) string {
+	return base + "_NULL" + ext
+}
+
+// SplitFileName returns the file name of the value's partition: base-value.ext,
+// with % and the characters not safe in file names percent-encoded (a/b is base-a%2Fb.ext),
+// so the distinct values get distinct names.
+func SplitFileName(base, ext, value string) string {
+	var buf strings.Builder
+	buf.WriteString(base)
+	buf.WriteByte('-')
+	for i := 0; i < len(value); i++ {
+		if c := value[i]; c < ' ' || c == 0x7f || strings.IndexByte(`%/\:*?"<>|`, c) >= 0 {
+			fmt.Fprintf(&buf, "%%%02X", c)
+		} else {
+			buf.WriteByte(c)
+		}
+	}
+	buf.WriteString(ext)
+	return buf.String()
+}
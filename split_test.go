@@ -0,0 +1,67 @@
+// Copyright 2021 Tamás Gulácsi.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package dbcsv_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/UNO-SOFT/dbcsv"
+)
+
+type nopCloser struct{ io.Writer }
+
+func (nopCloser) Close() error { return nil }
+
+func TestDumpCSVSplitByNull(t *testing.T) {
+	columns := []dbcsv.Column{{Name: "ID", Type: typeOfInt64}, {Name: "NAME", Type: typeOfString}}
+	rows := &sliceRows{values: [][]interface{}{{int64(1), "NULL"}, {int64(2), nil}, {int64(3), "NULL"}}}
+	parts := make(map[string]*bytes.Buffer)
+	create := func(name string) (io.WriteCloser, error) {
+		var buf bytes.Buffer
+		parts[name] = &buf
+		return nopCloser{&buf}, nil
+	}
+	opts := dbcsv.SplitByOptions{
+		Column:     "name",
+		Create:     func(value string) (io.WriteCloser, error) { return create("value " + value) },
+		CreateNull: func() (io.WriteCloser, error) { return create("null") },
+	}
+	opts.Sep = ";"
+	n, err := dbcsv.DumpCSVSplitBy(context.Background(), rows, columns, opts, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Errorf("got %d rows, wanted 3", n)
+	}
+	for name, want := range map[string]string{"value NULL": "1;NULL\n3;NULL\n", "null": "2;\n"} {
+		if got := parts[name].String(); got != want {
+			t.Errorf("%s: got %q, wanted %q", name, got, want)
+		}
+	}
+	if len(parts) != 2 {
+		t.Errorf("got %d parts, wanted 2", len(parts))
+	}
+}
+
+func TestSplitFileName(t *testing.T) {
+	for _, tc := range []struct {
+		value, want string
+	}{
+		{"a_b", "out-a_b.csv"},
+		{"a/b", "out-a%2Fb.csv"},
+		{"a:b", "out-a%3Ab.csv"},
+		{"100%", "out-100%25.csv"},
+		{"a\tb", "out-a%09b.csv"},
+		{"árvíz", "out-árvíz.csv"},
+	} {
+		if got := dbcsv.SplitFileName("out", ".csv", tc.value); got != tc.want {
+			t.Errorf("%q: got %q, wanted %q", tc.value, got, tc.want)
+		}
+	}
+}
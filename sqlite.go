@@ -0,0 +1,137 @@
+// Copyright 2021 Tamás Gulácsi.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package dbcsv
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SQLiteOptions are the options of DumpSQLite.
+type SQLiteOptions struct {
+	// Table is the name of the table to create.
+	Table string
+	// BatchSize is the number of rows inserted in one transaction (1000 if not positive).
+	BatchSize int
+	// DumperOptions format the values (see Column.Converter).
+	DumperOptions
+}
+
+// sqliteDateFormat is the format of the dates in SQLite, understood by its date functions.
+const sqliteDateFormat = "2006-01-02 15:04:05.999999999"
+
+// DumpSQLite writes the rows into a new table of the SQLite database file.
+//
+// The integers and booleans are INTEGER, the floats REAL, the binary values BLOB,
+// everything else (including the dates, as "YYYY-MM-DD HH:MM:SS") TEXT.
+func DumpSQLite(ctx context.Context, fileName string, rows Rows, columns []Column, opts SQLiteOptions, Log func(...interface{}) error) (int, error) {
+	if opts.Table == "" {
+		return 0, fmt.Errorf("table name is required")
+	}
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = 1000
+	}
+	db, err := sql.Open("sqlite", fileName)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", fileName, err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	names := make([]string, len(columns))
+	defs := make([]string, len(columns))
+	dest := make([]interface{}, len(columns))
+	values := make([]Stringer, len(columns))
+	for i, col := range columns {
+		names[i] = `"` + strings.ReplaceAll(col.Name, `"`, `""`) + `"`
+		c := col.Converter(opts.DumperOptions)
+		values[i] = c
+		dest[i] = c.Pointer()
+		typ := "TEXT"
+		switch c.(type) {
+		case *ValInt, *LocalizedValInt, *ValUint, *ValBool:
+			typ = "INTEGER"
+		case *ValFloat, *LocalizedValFloat:
+			typ = "REAL"
+		case *ValBlob:
+			typ = "BLOB"
+		}
+		defs[i] = names[i] + " " + typ
+		if col.NotNull {
+			defs[i] += " NOT NULL"
+		}
+	}
+	table := `"` + strings.ReplaceAll(opts.Table, `"`, `""`) + `"`
+	qry := "CREATE TABLE " + table + " (" + strings.Join(defs, ", ") + ")"
+	if _, err = db.ExecContext(ctx, qry); err != nil {
+		return 0, fmt.Errorf("%s: %w", qry, err)
+	}
+	qry = "INSERT INTO " + table + " (" + strings.Join(names, ", ") + ") VALUES (" + strings.Repeat(",?", len(names))[1:] + ")" //nolint:gas
+
+	var tx *sql.Tx
+	var stmt *sql.Stmt
+	defer func() {
+		if tx != nil {
+			tx.Rollback()
+		}
+	}()
+	args := make([]interface{}, len(dest))
+	start := time.Now()
+	n := 0
+	for rows.Next() {
+		if err = rows.Scan(dest...); err != nil {
+			return n, fmt.Errorf("scan into %#v: %w", dest, err)
+		}
+		if tx == nil {
+			if tx, err = db.BeginTx(ctx, nil); err != nil {
+				return n, err
+			}
+			if stmt, err = tx.PrepareContext(ctx, qry); err != nil {
+				return n, fmt.Errorf("%s: %w", qry, err)
+			}
+		}
+		for i, v := range values {
+			switch x := v.(type) {
+			case *ValBlob:
+				if args[i] = nil; x.Valid {
+					args[i] = x.Value
+				}
+			default:
+				args[i] = TypedValue(v)
+				if t, ok := args[i].(time.Time); ok {
+					args[i] = t.Format(sqliteDateFormat)
+				}
+			}
+		}
+		if _, err = stmt.ExecContext(ctx, args...); err != nil {
+			return n, fmt.Errorf("%s %v: %w", qry, args, err)
+		}
+		if n++; n%batchSize == 0 {
+			stmt.Close()
+			err = tx.Commit()
+			tx, stmt = nil, nil
+			if err != nil {
+				return n, err
+			}
+		}
+	}
+	if err = rows.Err(); err == nil && tx != nil {
+		stmt.Close()
+		err = tx.Commit()
+		tx = nil
+	}
+	if closeErr := db.Close(); closeErr != nil && err == nil {
+		err = closeErr
+	}
+	dur := time.Since(start)
+	if Log != nil {
+		_ = Log("msg", "dump finished", "rows", n, "dur", dur, "speed", float64(n)/float64(dur)*float64(time.Second), "error", err)
+	}
+	return n, err
+}
@@ -0,0 +1,193 @@
+// Copyright 2021 Tamás Gulácsi.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package dbcsv
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Standardization is the mean and (population) standard deviation of a numeric column.
+type Standardization struct {
+	Mean   float64 `json:"mean"`
+	StdDev float64 `json:"stddev"`
+}
+
+// Apply returns the Z-score of x: (x - Mean) / StdDev. A zero StdDev is treated as 1.
+func (s Standardization) Apply(x float64) float64 {
+	if s.StdDev == 0 {
+		return x - s.Mean
+	}
+	return (x - s.Mean) / s.StdDev
+}
+
+// Standardizations of the columns, by column name.
+type Standardizations map[string]Standardization
+
+// ReadStandardizations reads the JSON written by WriteTo.
+func ReadStandardizations(r io.Reader) (Standardizations, error) {
+	var ss Standardizations
+	if err := json.NewDecoder(r).Decode(&ss); err != nil {
+		return nil, err
+	}
+	return ss, nil
+}
+
+// WriteTo writes the standardizations as JSON.
+func (ss Standardizations) WriteTo(w io.Writer) (int64, error) {
+	b, err := json.MarshalIndent(ss, "", "  ")
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(append(b, '\n'))
+	return int64(n), err
+}
+
+// ComputeStandardizations reads all the rows into memory, and returns the standardizations
+// of the named columns, computed from their non-NULL values, and the rows to replay them.
+func ComputeStandardizations(rows Rows, columns []Column, names ...string) (Rows, Standardizations, error) {
+	indexes, err := columnIndexes(columns, names)
+	if err != nil {
+		return nil, nil, err
+	}
+	buffered, err := bufferRows(rows, columns)
+	if err != nil {
+		return nil, nil, err
+	}
+	ss := make(Standardizations, len(names))
+	for _, i := range indexes {
+		numeric := columns[i].isNumericString()
+		// Welford's online algorithm
+		var n int
+		var mean, m2 float64
+		for _, vals := range buffered {
+			if vals[i] == nil {
+				continue
+			}
+			x, err := toFloat(vals[i], numeric)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s: %w", columns[i].Name, err)
+			}
+			n++
+			d := x - mean
+			mean += d / float64(n)
+			m2 += d * (x - mean)
+		}
+		var s Standardization
+		if n != 0 {
+			s = Standardization{Mean: mean, StdDev: math.Sqrt(m2 / float64(n))}
+		}
+		ss[columns[i].Name] = s
+	}
+	return &replayRows{Rows: rows, buffered: buffered}, ss, nil
+}
+
+// Standardize returns the rows with the values of the columns in ss replaced with their Z-score
+// (see Standardization.Apply). These columns become float columns.
+func Standardize(rows Rows, columns []Column, ss Standardizations) (Rows, []Column, error) {
+	scale := make(map[string]func(float64) float64, len(ss))
+	for name, s := range ss {
+		scale[name] = s.Apply
+	}
+	return scaleColumns(rows, columns, scale)
+}
+
+// scaleColumns returns the rows with the non-NULL values of the columns of scale (by name) replaced
+// with the result of the function. These columns become float columns.
+func scaleColumns(rows Rows, columns []Column, scale map[string]func(float64) float64) (Rows, []Column, error) {
+	names := make([]string, 0, len(scale))
+	for name := range scale {
+		names = append(names, name)
+	}
+	indexes, err := columnIndexes(columns, names)
+	if err != nil {
+		return nil, nil, err
+	}
+	sr := scaledRows{Rows: rows, dest: make([]interface{}, len(columns)), scale: make(map[int]func(float64) float64, len(scale)),
+		numeric: make([]bool, len(columns))}
+	for i, col := range columns {
+		sr.dest[i] = col.scanConverter().Pointer()
+		sr.numeric[i] = col.isNumericString()
+	}
+	cols := make([]Column, len(columns))
+	copy(cols, columns)
+	for k, i := range indexes {
+		sr.scale[i] = scale[names[k]]
+		cols[i].Type = typeOfFloat64
+	}
+	return &sr, cols, nil
+}
+
+type scaledRows struct {
+	Rows
+	scale   map[int]func(float64) float64
+	dest    []interface{}
+	numeric []bool
+}
+
+func (sr *scaledRows) Scan(dest ...interface{}) error {
+	if err := sr.Rows.Scan(sr.dest...); err != nil {
+		return err
+	}
+	for j, d := range dest {
+		v := replayValue(sr.dest[j])
+		if f, ok := sr.scale[j]; ok && v != nil {
+			x, err := toFloat(v, sr.numeric[j])
+			if err != nil {
+				return fmt.Errorf("%d. column: %w", j, err)
+			}
+			v = f(x)
+		}
+		scanner, ok := d.(sql.Scanner)
+		if !ok {
+			return fmt.Errorf("%d. column: cannot scan into %T", j, d)
+		}
+		if err := scanner.Scan(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// toFloat returns the int64 or float64 value as float64 - with numeric, the numeric strings
+// (such as the NUMBER columns' or the big ValUint values), too.
+func toFloat(v interface{}, numeric bool) (float64, error) {
+	switch x := v.(type) {
+	case int64:
+		return float64(x), nil
+	case float64:
+		return x, nil
+	case string:
+		if numeric {
+			if f, err := strconv.ParseFloat(x, 64); err == nil {
+				return f, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("%v (%T) is not a number", v, v)
+}
+
+// columnIndexes returns the indexes of the named columns.
+func columnIndexes(columns []Column, names []string) ([]int, error) {
+	indexes := make([]int, len(names))
+	for k, name := range names {
+		indexes[k] = -1
+		for i, col := range columns {
+			if strings.EqualFold(col.Name, name) {
+				indexes[k] = i
+				break
+			}
+		}
+		if indexes[k] < 0 {
+			return nil, fmt.Errorf("%s: unknown column", name)
+		}
+	}
+	return indexes, nil
+}
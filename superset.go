@@ -0,0 +1,88 @@
+// Copyright 2021 Tamás Gulácsi.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package dbcsv
+
+import (
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// supersetTypes are the Superset column types of int, float, time, bool, []byte and string columns.
+var supersetTypes = [6]string{"BIGINT", "FLOAT", "DATETIME", "BOOLEAN", "BLOB", "VARCHAR"}
+
+// SupersetOptions are the options of WriteSupersetDataset.
+type SupersetOptions struct {
+	// Datasource is the table name of the dataset.
+	Datasource string
+	// Schema of the table, may be empty.
+	Schema string
+	// DatabaseUUID is the uuid of the Superset database the dataset belongs to, may be empty.
+	DatabaseUUID string
+}
+
+// WriteSupersetDataset writes the Apache Superset dataset import/export YAML of the columns.
+// Date columns are marked with is_dttm, and the first is the main_dttm_col.
+//
+// The uuid of the dataset is derived from the schema and the datasource name,
+// so the re-exports of the same dataset overwrite the previous import.
+func WriteSupersetDataset(w io.Writer, columns []Column, opts SupersetOptions) error {
+	if opts.Datasource == "" {
+		return fmt.Errorf("empty Superset datasource name")
+	}
+	var mainDttm string
+	for _, col := range columns {
+		if ddlTypeIndex(col.Type) == 2 {
+			mainDttm = col.Name
+			break
+		}
+	}
+	var buf strings.Builder
+	buf.WriteString("table_name: " + yamlString(opts.Datasource) + "\n")
+	if mainDttm == "" {
+		buf.WriteString("main_dttm_col: null\n")
+	} else {
+		buf.WriteString("main_dttm_col: " + yamlString(mainDttm) + "\n")
+	}
+	buf.WriteString("description: null\ndefault_endpoint: null\noffset: 0\ncache_timeout: null\n")
+	if opts.Schema == "" {
+		buf.WriteString("schema: null\n")
+	} else {
+		buf.WriteString("schema: " + yamlString(opts.Schema) + "\n")
+	}
+	buf.WriteString("sql: null\nparams: null\ntemplate_params: null\nfilter_select_enabled: true\nfetch_values_predicate: null\nextra: null\n")
+	buf.WriteString("uuid: " + nameUUID(opts.Schema+"."+opts.Datasource) + "\n")
+	buf.WriteString("metrics:\n- metric_name: count\n  verbose_name: COUNT(*)\n  metric_type: count\n  expression: COUNT(*)\n" +
+		"  description: null\n  d3format: null\n  extra: null\n  warning_text: null\n")
+	buf.WriteString("columns:\n")
+	for _, col := range columns {
+		i := ddlTypeIndex(col.Type)
+		fmt.Fprintf(&buf, "- column_name: %s\n  verbose_name: null\n  is_dttm: %t\n  is_active: true\n  type: %s\n"+
+			"  groupby: true\n  filterable: true\n  expression: null\n  description: null\n  python_date_format: null\n  extra: null\n",
+			yamlString(col.Name), i == 2, supersetTypes[i])
+	}
+	buf.WriteString("version: 1.0.0\n")
+	if opts.DatabaseUUID != "" {
+		buf.WriteString("database_uuid: " + opts.DatabaseUUID + "\n")
+	}
+	_, err := io.WriteString(w, buf.String())
+	return err
+}
+
+// yamlString returns s as a double quoted YAML (JSON) string.
+func yamlString(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
+
+// nameUUID returns the version 5 style (SHA-1 based) UUID of the name.
+func nameUUID(name string) string {
+	h := sha1.Sum([]byte(name))
+	h[6] = h[6]&0x0f | 0x50
+	h[8] = h[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", h[0:4], h[4:6], h[6:8], h[8:10], h[10:16])
+}
@@ -0,0 +1,100 @@
+// Copyright 2021 Tamás Gulácsi.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package dbcsv
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SyslogOptions are the header fields of the RFC 5424 messages written by DumpSyslog.
+type SyslogOptions struct {
+	AppName, Hostname  string
+	Facility, Severity int
+	// DumperOptions format the values (see Column.Converter).
+	DumperOptions
+}
+
+// DumpSyslog writes each row as an RFC 5424 syslog message to w, one Write per message
+// (so w can be a datagram connection).
+//
+// The MSG is the JSON object of the row, the STRUCTURED-DATA contains
+// the row's sequence number and the dump's start time.
+func DumpSyslog(ctx context.Context, w io.Writer, rows Rows, columns []Column, opts SyslogOptions, Log func(...interface{}) error) (int, error) {
+	if opts.Facility < 0 || opts.Facility > 23 {
+		return 0, fmt.Errorf("facility %d out of range [0, 23]", opts.Facility)
+	}
+	if opts.Severity < 0 || opts.Severity > 7 {
+		return 0, fmt.Errorf("severity %d out of range [0, 7]", opts.Severity)
+	}
+	if opts.Hostname == "" {
+		opts.Hostname, _ = os.Hostname()
+	}
+	dest := make([]interface{}, len(columns))
+	values := make([]Stringer, len(columns))
+	for i, col := range columns {
+		c := col.Converter(opts.DumperOptions)
+		values[i] = c
+		dest[i] = c.Pointer()
+	}
+	start := time.Now()
+	header := "<" + strconv.Itoa(opts.Facility*8+opts.Severity) + ">1 "
+	trailer := " " + syslogHeaderField(opts.Hostname) + " " + syslogHeaderField(opts.AppName) +
+		" " + strconv.Itoa(os.Getpid()) + " row "
+	sdStart := syslogSDEscape(start.Format(time.RFC3339))
+	var buf bytes.Buffer
+	n := 0
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return n, fmt.Errorf("scan into %#v: %w", dest, err)
+		}
+		n++
+		buf.Reset()
+		buf.WriteString(header)
+		buf.WriteString(time.Now().Format("2006-01-02T15:04:05.000000Z07:00"))
+		buf.WriteString(trailer)
+		fmt.Fprintf(&buf, `[meta sequenceId="%d"][dump@32473 start="%s"] `, n, sdStart)
+		if err := appendJSONObject(&buf, columns, values, nil); err != nil {
+			return n, err
+		}
+		buf.WriteByte('\n')
+		if _, err := w.Write(buf.Bytes()); err != nil {
+			return n, err
+		}
+		if err := ctx.Err(); err != nil {
+			return n, err
+		}
+	}
+	err := rows.Err()
+	dur := time.Since(start)
+	if Log != nil {
+		_ = Log("msg", "dump finished", "rows", n, "dur", dur, "speed", float64(n)/float64(dur)*float64(time.Second), "error", err)
+	}
+	return n, err
+}
+
+// syslogHeaderField returns s as a valid header field: printable ASCII without spaces, "-" if empty.
+func syslogHeaderField(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if r <= ' ' || r > '~' {
+			return '_'
+		}
+		return r
+	}, s)
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+var syslogSDEscaper = strings.NewReplacer(`"`, `\"`, `\`, `\\`, `]`, `\]`)
+
+func syslogSDEscape(s string) string { return syslogSDEscaper.Replace(s) }
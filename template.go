@@ -0,0 +1,75 @@
+// Copyright 2021 Tamás Gulácsi.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package dbcsv
+
+import (
+	"log"
+	"strings"
+	"text/template"
+)
+
+// RowMapRows records the values (ScannedValue) of the last scanned row in Row, by column name.
+type RowMapRows struct {
+	Rows
+	Columns []Column
+	Row     map[string]interface{}
+}
+
+// NewRowMapRows returns a RowMapRows for the rows and columns.
+func NewRowMapRows(rows Rows, columns []Column) *RowMapRows {
+	return &RowMapRows{Rows: rows, Columns: columns, Row: make(map[string]interface{}, len(columns))}
+}
+
+func (r *RowMapRows) Scan(dest ...interface{}) error {
+	if err := r.Rows.Scan(dest...); err != nil {
+		return err
+	}
+	for i, d := range dest {
+		if i < len(r.Columns) {
+			r.Row[r.Columns[i].Name] = ScannedValue(d)
+		}
+	}
+	return nil
+}
+
+// TemplateStringer is a Stringer whose String is the result of the Template executed with
+// .Value (the raw string of the wrapped Stringer) and .Row (the values of the row, see RowMapRows).
+//
+// The Template is not executed for NULLs. Execution errors (and panics) are logged, and result in an empty string.
+type TemplateStringer struct {
+	Stringer
+	Template *template.Template
+	Row      map[string]interface{}
+	Sep      string
+}
+
+// NewTemplateWrapper returns a StringerWrapper that wraps with a TemplateStringer using tmpl and row.
+func NewTemplateWrapper(tmpl *template.Template, row map[string]interface{}) StringerWrapper {
+	return func(s Stringer, sep string) Stringer {
+		return &TemplateStringer{Stringer: s, Template: tmpl, Row: row, Sep: sep}
+	}
+}
+
+func (t TemplateStringer) String() string { return csvQuoteString(t.Sep, t.StringRaw()) }
+func (t TemplateStringer) StringRaw() (s string) {
+	if IsNull(t.Stringer) {
+		return ""
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("[WARN] template %s: %+v", t.Template.Name(), r)
+			s = ""
+		}
+	}()
+	var buf strings.Builder
+	if err := t.Template.Execute(&buf, struct {
+		Value string
+		Row   map[string]interface{}
+	}{Value: StringRaw(t.Stringer), Row: t.Row}); err != nil {
+		log.Printf("[WARN] template %s: %+v", t.Template.Name(), err)
+		return ""
+	}
+	return buf.String()
+}
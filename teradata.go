@@ -0,0 +1,168 @@
+// Copyright 2021 Tamás Gulácsi.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package dbcsv
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+)
+
+// FastLoadOptions are the parameters of the Teradata FastLoad data and control files written by DumpFastLoad.
+type FastLoadOptions struct {
+	// Table is the table to load into.
+	Table string
+	// DataFile is the name of the data file, as referred from the control file.
+	DataFile string
+	// Fixed selects fixed width records (padded to the longest value of each column),
+	// instead of the variable ones (2-byte length prefixed, pipe separated).
+	Fixed bool
+	// Sessions is the number of FastLoad sessions (defaults to 4).
+	Sessions int
+	// DumperOptions format the values (see Column.Converter).
+	DumperOptions
+}
+
+// DumpFastLoad writes the rows as Teradata FastLoad input to w,
+// and the FastLoad control script (with a DEFINE for each column) to ctl.
+//
+// Variable records are the pipe separated values, preceded by their length as a
+// 2-byte little-endian integer, NULLs are empty fields.
+// Fixed records are newline terminated, with each value padded with spaces to the
+// width of the column, NULLs are all spaces. This needs all the rows to be kept in memory.
+func DumpFastLoad(ctx context.Context, w, ctl io.Writer, rows Rows, columns []Column, opts FastLoadOptions, Log func(...interface{}) error) (int, error) {
+	if opts.Sessions <= 0 {
+		opts.Sessions = 4
+	}
+	dest := make([]interface{}, len(columns))
+	values := make([]Stringer, len(columns))
+	for i, col := range columns {
+		c := col.Converter(opts.DumperOptions)
+		values[i] = c
+		dest[i] = c.Pointer()
+	}
+	widths := make([]int, len(columns))
+	for i := range widths {
+		widths[i] = 1
+	}
+
+	bw := bufio.NewWriterSize(w, 65536)
+	defer bw.Flush()
+	start := time.Now()
+	var records [][]string
+	var buf strings.Builder
+	var prefix [2]byte
+	n := 0
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return n, fmt.Errorf("scan into %#v: %w", dest, err)
+		}
+		rec := make([]string, len(values))
+		for i, v := range values {
+			if IsNull(v) {
+				continue
+			}
+			rec[i] = StringRaw(v)
+			if len(rec[i]) > widths[i] {
+				widths[i] = len(rec[i])
+			}
+		}
+		n++
+		if opts.Fixed {
+			records = append(records, rec)
+		} else {
+			buf.Reset()
+			for i, s := range rec {
+				if i != 0 {
+					buf.WriteByte('|')
+				}
+				if strings.IndexByte(s, '|') >= 0 {
+					return n, fmt.Errorf("%d. row %q: value %q contains the delimiter", n, columns[i].Name, s)
+				}
+				buf.WriteString(s)
+			}
+			if buf.Len() > math.MaxUint16 {
+				return n, fmt.Errorf("%d. row is too long (%d bytes)", n, buf.Len())
+			}
+			binary.LittleEndian.PutUint16(prefix[:], uint16(buf.Len()))
+			bw.Write(prefix[:])
+			if _, err := bw.WriteString(buf.String()); err != nil {
+				return n, err
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			return n, err
+		}
+	}
+	err := rows.Err()
+	if err == nil && opts.Fixed {
+		for _, rec := range records {
+			for i, s := range rec {
+				bw.WriteString(s)
+				bw.WriteString(strings.Repeat(" ", widths[i]-len(s)))
+			}
+			if err = bw.WriteByte('\n'); err != nil {
+				break
+			}
+		}
+	}
+	dur := time.Since(start)
+	if Log != nil {
+		_ = Log("msg", "dump finished", "rows", n, "dur", dur, "speed", float64(n)/float64(dur)*float64(time.Second), "error", err)
+	}
+	if err != nil {
+		return n, err
+	}
+	if err = bw.Flush(); err != nil {
+		return n, err
+	}
+	return n, writeFastLoadControl(ctl, columns, widths, opts)
+}
+
+// writeFastLoadControl writes the FastLoad control script, with the LOGON left for the user to fill.
+func writeFastLoadControl(w io.Writer, columns []Column, widths []int, opts FastLoadOptions) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "SESSIONS %d;\nERRLIMIT 25;\n/* LOGON tdpid/username,password; */\n\n", opts.Sessions)
+	if opts.Fixed {
+		bw.WriteString("SET RECORD TEXT;\n")
+	} else {
+		bw.WriteString("SET RECORD VARTEXT \"|\";\n")
+	}
+	bw.WriteString("DEFINE\n")
+	for i, col := range columns {
+		if opts.Fixed {
+			fmt.Fprintf(bw, "  %s (CHAR(%d), NULLIF = '')", col.Name, widths[i])
+		} else {
+			fmt.Fprintf(bw, "  %s (VARCHAR(%d))", col.Name, widths[i])
+		}
+		if i != len(columns)-1 {
+			bw.WriteByte(',')
+		}
+		bw.WriteByte('\n')
+	}
+	fmt.Fprintf(bw, "FILE = %s;\n\n", opts.DataFile)
+	fmt.Fprintf(bw, "BEGIN LOADING %s ERRORFILES %s_err1, %s_err2;\n", opts.Table, opts.Table, opts.Table)
+	fmt.Fprintf(bw, "INSERT INTO %s (", opts.Table)
+	for i, col := range columns {
+		if i != 0 {
+			bw.WriteString(", ")
+		}
+		bw.WriteString(col.Name)
+	}
+	bw.WriteString(") VALUES (")
+	for i, col := range columns {
+		if i != 0 {
+			bw.WriteString(", ")
+		}
+		bw.WriteString(":" + col.Name)
+	}
+	bw.WriteString(");\nEND LOADING;\nLOGOFF;\n")
+	return bw.Flush()
+}
@@ -0,0 +1,381 @@
+// Copyright 2021 Tamás Gulácsi.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package dbcsv
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/vm"
+)
+
+// ErrValidation is returned (wrapped) by ValidatingRows.Scan when a row fails validation.
+var ErrValidation = errors.New("validation failed")
+
+// ErrTooManyErrors is returned (wrapped) by ValidatingRows.Scan when the ErrorBudget is exhausted.
+var ErrTooManyErrors = errors.New("too many errors")
+
+// ErrorBudget counts the violations of (possibly several, concurrently scanned) WarnOnly ValidatingRows.
+type ErrorBudget struct {
+	mu sync.Mutex // of count
+	// Max is the number of violations allowed, 0 means unlimited.
+	Max   int
+	count int
+}
+
+// Count returns the number of violations added so far.
+func (eb *ErrorBudget) Count() int {
+	eb.mu.Lock()
+	defer eb.mu.Unlock()
+	return eb.count
+}
+
+// Add a violation, returning ErrTooManyErrors when Max is reached.
+func (eb *ErrorBudget) Add() error {
+	eb.mu.Lock()
+	eb.count++
+	count := eb.count
+	eb.mu.Unlock()
+	if eb.Max > 0 && count >= eb.Max {
+		return ErrTooManyErrors
+	}
+	return nil
+}
+
+// Validator checks the row-th (1-based) scanned row - dest is what has been passed to Rows.Scan.
+// The returned error describes the violation.
+type Validator interface {
+	Validate(row int, dest []interface{}) error
+}
+
+// ValidatingRows wraps Rows and checks each scanned row with the Validators.
+//
+// A violation is written to ErrorLog (or the standard logger if nil),
+// and aborts the scan with ErrValidation, unless WarnOnly is set.
+// With WarnOnly, the violations are also counted in Budget (if not nil), and the scan is
+// aborted when it is exhausted.
+type ValidatingRows struct {
+	Rows
+	ErrorLog   io.Writer
+	Budget     *ErrorBudget
+	Validators []Validator
+	Violations int
+	WarnOnly   bool
+	n          int
+}
+
+func (vr *ValidatingRows) Scan(dest ...interface{}) error {
+	if err := vr.Rows.Scan(dest...); err != nil {
+		return err
+	}
+	vr.n++
+	for _, v := range vr.Validators {
+		err := v.Validate(vr.n, dest)
+		if err == nil {
+			continue
+		}
+		vr.Violations++
+		if vr.ErrorLog != nil {
+			fmt.Fprintf(vr.ErrorLog, "row %d: %v\n", vr.n, err)
+		} else {
+			log.Printf("row %d: %v", vr.n, err)
+		}
+		if !vr.WarnOnly {
+			return fmt.Errorf("row %d: %v: %w", vr.n, err, ErrValidation)
+		}
+		if vr.Budget != nil {
+			if err := vr.Budget.Add(); err != nil {
+				return fmt.Errorf("row %d: %w", vr.n, err)
+			}
+		}
+	}
+	return nil
+}
+
+// RangeValidator checks that the non-null values of the Index-th column
+// are between Min and Max (inclusive). A nil bound is not checked.
+type RangeValidator struct {
+	Min, Max interface{}
+	Name     string
+	Index    int
+}
+
+// NewRangeValidator returns a RangeValidator for the column, parsing min and max
+// as numbers for ValInt, ValUint, ValDecimal and ValFloat columns (*big.Rat for ValUint, ValDecimal
+// and the other numeric columns scanned as strings, such as Oracle's NUMBER),
+// and for ValTime columns as dates in the column's date format of opts (in opts.Location, if not nil),
+// or with ParseInputDate if that does not match.
+func NewRangeValidator(col Column, index int, min, max string, opts DumperOptions) (*RangeValidator, error) {
+	rv := RangeValidator{Name: col.Name, Index: index}
+	parseRat := func(s string) (interface{}, error) {
+		r, ok := new(big.Rat).SetString(s)
+		if !ok {
+			return nil, fmt.Errorf("%q is not a number", s)
+		}
+		return r, nil
+	}
+	var parse func(string) (interface{}, error)
+	switch col.scanConverter().(type) {
+	case *ValInt:
+		parse = func(s string) (interface{}, error) { return strconv.ParseInt(s, 10, 64) }
+	case *ValUint, *ValDecimal:
+		// their big values are scanned as strings
+		parse = parseRat
+	case *ValFloat:
+		parse = func(s string) (interface{}, error) { return strconv.ParseFloat(s, 64) }
+	case *ValTime:
+		layout, loc := opts.withDefaults().dateFormat(col.Name), opts.Location
+		if loc == nil {
+			loc = time.UTC
+		}
+		parse = func(s string) (interface{}, error) {
+			if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+				return t, nil
+			}
+			return ParseInputDate(s)
+		}
+	default:
+		if !col.isNumericString() {
+			return nil, fmt.Errorf("%s: range validation needs a numeric or date column", col.Name)
+		}
+		// such as the godror.Number strings of NUMBER
+		parse = parseRat
+	}
+	var err error
+	if min != "" {
+		if rv.Min, err = parse(min); err != nil {
+			return nil, fmt.Errorf("%s: min %q: %w", col.Name, min, err)
+		}
+	}
+	if max != "" {
+		if rv.Max, err = parse(max); err != nil {
+			return nil, fmt.Errorf("%s: max %q: %w", col.Name, max, err)
+		}
+	}
+	return &rv, nil
+}
+
+func (rv RangeValidator) Validate(_ int, dest []interface{}) error {
+	v := ScannedValue(dest[rv.Index])
+	if v == nil {
+		return nil
+	}
+	if rv.Min != nil {
+		if c, err := compareValues(v, rv.Min); err != nil {
+			return fmt.Errorf("%s: %w", rv.Name, err)
+		} else if c < 0 {
+			return fmt.Errorf("%s=%v is less than %v", rv.Name, v, ratString(rv.Min))
+		}
+	}
+	if rv.Max != nil {
+		if c, err := compareValues(v, rv.Max); err != nil {
+			return fmt.Errorf("%s: %w", rv.Name, err)
+		} else if c > 0 {
+			return fmt.Errorf("%s=%v is greater than %v", rv.Name, v, ratString(rv.Max))
+		}
+	}
+	return nil
+}
+
+// ratString returns the *big.Rat v as a decimal, anything else as is.
+func ratString(v interface{}) interface{} {
+	if r, ok := v.(*big.Rat); ok {
+		return ValDecimal{Value: *r, Valid: true}.StringRaw()
+	}
+	return v
+}
+
+// UniqueValidator checks that the non-null values of the Index-th column are unique.
+//
+// At most MaxValues (if positive) distinct values are remembered,
+// after that new values are not checked.
+type UniqueValidator struct {
+	seen      map[string]int
+	Name      string
+	Index     int
+	MaxValues int
+	full      bool
+}
+
+func (uv *UniqueValidator) Validate(row int, dest []interface{}) error {
+	v := ScannedValue(dest[uv.Index])
+	if v == nil {
+		return nil
+	}
+	k := fmt.Sprint(v)
+	if first, ok := uv.seen[k]; ok {
+		return fmt.Errorf("%s=%q is duplicate of row %d", uv.Name, k, first)
+	}
+	if uv.MaxValues > 0 && len(uv.seen) >= uv.MaxValues {
+		if !uv.full {
+			uv.full = true
+			log.Printf("[WARN] %s: more than %d distinct values, not checking the new ones", uv.Name, uv.MaxValues)
+		}
+		return nil
+	}
+	if uv.seen == nil {
+		uv.seen = make(map[string]int)
+	}
+	uv.seen[k] = row
+	return nil
+}
+
+// RegexValidator checks that the non-null values of the Index-th column match Regexp.
+type RegexValidator struct {
+	*regexp.Regexp
+	Name  string
+	Index int
+}
+
+// NewRegexValidator returns a RegexValidator for the column, with the pattern anchored (^...$).
+func NewRegexValidator(col Column, index int, pattern string) (*RegexValidator, error) {
+	rx, err := regexp.Compile("^(?:" + pattern + ")$")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %q: %w", col.Name, pattern, err)
+	}
+	return &RegexValidator{Regexp: rx, Name: col.Name, Index: index}, nil
+}
+
+func (rv RegexValidator) Validate(_ int, dest []interface{}) error {
+	s, ok := ScannedString(dest[rv.Index])
+	if !ok || rv.MatchString(s) {
+		return nil
+	}
+	return fmt.Errorf("%s=%q does not match %s", rv.Name, s, rv.Regexp)
+}
+
+// ExprValidator checks that the boolean expression (github.com/expr-lang/expr)
+// is true for the row. The column names are the variables,
+// NULLs are the zero value of the column's type (0, "" or the zero time).
+type ExprValidator struct {
+	program *vm.Program
+	env     map[string]interface{}
+	zero    []interface{}
+	names   []string
+	Source  string
+}
+
+// NewExprValidator compiles the expression for the given columns.
+func NewExprValidator(columns []Column, source string) (*ExprValidator, error) {
+	ev := ExprValidator{
+		Source: source,
+		names:  make([]string, len(columns)),
+		zero:   make([]interface{}, len(columns)),
+		env:    make(map[string]interface{}, len(columns)),
+	}
+	for i, c := range columns {
+		ev.names[i] = c.Name
+		switch c.scanConverter().(type) {
+		case *ValInt, *ValUint:
+			ev.zero[i] = int64(0)
+		case *ValFloat:
+			ev.zero[i] = float64(0)
+		case *ValTime:
+			ev.zero[i] = time.Time{}
+		case *ValBool:
+			ev.zero[i] = false
+		default:
+			ev.zero[i] = ""
+		}
+		ev.env[c.Name] = ev.zero[i]
+	}
+	var err error
+	if ev.program, err = expr.Compile(source, expr.Env(ev.env), expr.AsBool()); err != nil {
+		return nil, fmt.Errorf("%q: %w", source, err)
+	}
+	return &ev, nil
+}
+
+func (ev *ExprValidator) Validate(_ int, dest []interface{}) error {
+	for i, nm := range ev.names {
+		if v := ScannedValue(dest[i]); v != nil {
+			ev.env[nm] = v
+		} else {
+			ev.env[nm] = ev.zero[i]
+		}
+	}
+	res, err := expr.Run(ev.program, ev.env)
+	if err != nil {
+		return fmt.Errorf("%q: %w", ev.Source, err)
+	}
+	if ok, _ := res.(bool); !ok {
+		return fmt.Errorf("%q is false", ev.Source)
+	}
+	return nil
+}
+
+// compareValues compares the ScannedValues a and b: the numbers (int64, float64 and *big.Rat) as numbers,
+// where a string compared to a *big.Rat is parsed as a number (as ValDecimal and the big ValUint values are scanned);
+// the times and the strings as such. Values of other types cannot be compared.
+func compareValues(a, b interface{}) (int, error) {
+	switch x := a.(type) {
+	case int64:
+		if y, ok := b.(int64); ok {
+			if x < y {
+				return -1, nil
+			} else if x > y {
+				return 1, nil
+			}
+			return 0, nil
+		}
+	case float64:
+		if y, ok := b.(float64); ok {
+			if x < y {
+				return -1, nil
+			} else if x > y {
+				return 1, nil
+			}
+			return 0, nil
+		}
+	case time.Time:
+		if y, ok := b.(time.Time); ok {
+			if x.Before(y) {
+				return -1, nil
+			} else if x.After(y) {
+				return 1, nil
+			}
+			return 0, nil
+		}
+	case string:
+		if y, ok := b.(string); ok {
+			return strings.Compare(x, y), nil
+		}
+	}
+	x, okA := ratValue(a)
+	y, okB := ratValue(b)
+	_, ratA := a.(*big.Rat)
+	_, ratB := b.(*big.Rat)
+	if okA && okB && (ratA || ratB || !(isString(a) || isString(b))) {
+		return x.Cmp(y), nil
+	}
+	return 0, fmt.Errorf("cannot compare %v (%T) with %v (%T)", a, a, b, b)
+}
+
+func isString(v interface{}) bool { _, ok := v.(string); return ok }
+
+// ratValue returns the number (int64, float64, *big.Rat or numeric string) v as *big.Rat.
+func ratValue(v interface{}) (*big.Rat, bool) {
+	switch x := v.(type) {
+	case int64:
+		return new(big.Rat).SetInt64(x), true
+	case float64:
+		r := new(big.Rat)
+		return r, r.SetFloat64(x) != nil
+	case *big.Rat:
+		return x, true
+	case string:
+		return new(big.Rat).SetString(x)
+	}
+	return nil, false
+}
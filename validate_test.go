@@ -0,0 +1,169 @@
+// Copyright 2021 Tamás Gulácsi.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package dbcsv_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/UNO-SOFT/dbcsv"
+)
+
+// sliceRows is a dbcsv.Rows returning the given values.
+type sliceRows struct {
+	values [][]interface{}
+	i      int
+}
+
+func (r *sliceRows) Next() bool   { r.i++; return r.i <= len(r.values) }
+func (r *sliceRows) Err() error   { return nil }
+func (r *sliceRows) Close() error { return nil }
+func (r *sliceRows) Scan(dest ...interface{}) error {
+	for i, v := range r.values[r.i-1] {
+		if err := dest[i].(sql.Scanner).Scan(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var (
+	typeOfInt64  = reflect.TypeOf(int64(0))
+	typeOfString = reflect.TypeOf("")
+)
+
+func TestRangeValidator(t *testing.T) {
+	columns := []dbcsv.Column{{Name: "ID", Type: typeOfInt64}, {Name: "NAME", Type: typeOfString}}
+	rv, err := dbcsv.NewRangeValidator(columns[0], 0, "1", "10", dbcsv.DumperOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = dbcsv.NewRangeValidator(columns[1], 1, "a", "b", dbcsv.DumperOptions{}); err == nil {
+		t.Error("wanted error for string column")
+	}
+
+	newRows := func() *sliceRows {
+		return &sliceRows{values: [][]interface{}{{int64(1), "a"}, {nil, "b"}, {int64(11), "c"}, {int64(10), "d"}}}
+	}
+	var errLog bytes.Buffer
+	vr := &dbcsv.ValidatingRows{Rows: newRows(), ErrorLog: &errLog, Validators: []dbcsv.Validator{rv}}
+	var buf bytes.Buffer
+	err = dbcsv.DumpCSV(context.Background(), &buf, vr, columns, true, ";", false, nil)
+	if !errors.Is(err, dbcsv.ErrValidation) {
+		t.Errorf("wanted validation error, got %+v", err)
+	}
+	if vr.Violations != 1 {
+		t.Errorf("got %d violations, wanted 1", vr.Violations)
+	}
+
+	errLog.Reset()
+	buf.Reset()
+	vr = &dbcsv.ValidatingRows{Rows: newRows(), ErrorLog: &errLog, Validators: []dbcsv.Validator{rv}, WarnOnly: true}
+	if err = dbcsv.DumpCSV(context.Background(), &buf, vr, columns, true, ";", false, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), "ID;NAME\n1;a\n;b\n11;c\n10;d\n"; got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+	if !strings.HasPrefix(errLog.String(), "row 3: ID=11 is greater than 10") {
+		t.Errorf("got error log %q", errLog.String())
+	}
+}
+
+func TestRangeValidatorDecimal(t *testing.T) {
+	columns := []dbcsv.Column{
+		{Name: "AMOUNT", Type: typeOfString, DatabaseTypeName: "NUMBER", Scale: 20},
+		{Name: "BIG", Type: reflect.TypeOf(uint64(0))},
+		// godror scans the bare NUMBER as godror.Number, a string
+		{Name: "QTY", Type: typeOfString, DatabaseTypeName: "NUMBER"},
+	}
+	amount, err := dbcsv.NewRangeValidator(columns[0], 0, "0.5", "100", dbcsv.DumperOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	huge, err := dbcsv.NewRangeValidator(columns[1], 1, "", "18446744073709551614", dbcsv.DumperOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	qty, err := dbcsv.NewRangeValidator(columns[2], 2, "9", "", dbcsv.DumperOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := &sliceRows{values: [][]interface{}{
+		{"0.49999999999999999999", uint64(1), "10"},
+		{"100", uint64(18446744073709551615), "9"},
+		{"12.25", uint64(9223372036854775808), "8.5"},
+		{nil, nil, nil},
+	}}
+	var errLog bytes.Buffer
+	vr := &dbcsv.ValidatingRows{Rows: rows, ErrorLog: &errLog, Validators: []dbcsv.Validator{amount, huge, qty}, WarnOnly: true}
+	var buf bytes.Buffer
+	if err = dbcsv.DumpCSV(context.Background(), &buf, vr, columns, false, ";", false, nil); err != nil {
+		t.Fatal(err)
+	}
+	if vr.Violations != 3 {
+		t.Errorf("got %d violations, wanted 3", vr.Violations)
+	}
+	if got, want := errLog.String(), "row 1: AMOUNT=0.49999999999999999999 is less than 0.5\n"+
+		"row 2: BIG=18446744073709551615 is greater than 18446744073709551614\n"+
+		"row 3: QTY=8.5 is less than 9\n"; got != want {
+		t.Errorf("got error log %q, wanted %q", got, want)
+	}
+}
+
+func TestRangeValidatorDate(t *testing.T) {
+	col := dbcsv.Column{Name: "CREATED", Type: reflect.TypeOf(time.Time{})}
+	opts := dbcsv.DumperOptions{DateFormat: "2006-01-02 15:04:05", DateFormats: map[string]string{"created": "2006.01.02 15:04"}}
+	rv, err := dbcsv.NewRangeValidator(col, 0, "2024.01.01 10:00", "2024-12-31", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		value time.Time
+		ok    bool
+	}{
+		{time.Date(2024, 1, 1, 9, 59, 0, 0, time.UTC), false},
+		{time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC), true},
+		{time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC), true},
+		{time.Date(2024, 12, 31, 0, 0, 1, 0, time.UTC), false},
+	} {
+		v := dbcsv.ValTime{Value: sql.NullTime{Time: tc.value, Valid: true}}
+		if err := rv.Validate(1, []interface{}{&v}); (err == nil) != tc.ok {
+			t.Errorf("%s: got %v", tc.value, err)
+		}
+	}
+	if _, err = dbcsv.NewRangeValidator(col, 0, "01/02/2024", "", opts); err == nil {
+		t.Error("wanted error for a date in neither format")
+	}
+}
+
+func TestErrorBudgetConcurrent(t *testing.T) {
+	budget := &dbcsv.ErrorBudget{Max: 1000}
+	var wg sync.WaitGroup
+	var tooMany int32
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 250; j++ {
+				if err := budget.Add(); errors.Is(err, dbcsv.ErrTooManyErrors) {
+					atomic.AddInt32(&tooMany, 1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if budget.Count() != 1000 || tooMany != 1 {
+		t.Errorf("got count=%d tooMany=%d, wanted 1000 and 1", budget.Count(), tooMany)
+	}
+}
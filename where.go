@@ -0,0 +1,158 @@
+// Copyright 2021 Tamás Gulácsi.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package dbcsv
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var rFilter = regexp.MustCompile(`(?is)^\s*([\w$#]+)\s*(IS\s+NOT\s+NULL|IS\s+NULL|NOT\s+LIKE\b|LIKE\b|NOT\s+IN\b|IN\b|<=|>=|<>|!=|=|<|>)\s*(.*?)\s*$`)
+
+// FilterCondition returns the SQL condition of the simplified filter expression, checking the column
+// and converting the value(s) to SQL literals by the type of the column. The supported forms are
+//
+//	COL op VALUE (op is one of = <> != < <= > >=)
+//	COL [NOT] LIKE 'PATTERN'
+//	COL [NOT] IN (V1, V2, ...)
+//	COL IS [NOT] NULL
+//
+// Values may be single quoted.
+func FilterCondition(columns []Column, expr string) (string, error) {
+	m := rFilter.FindStringSubmatch(expr)
+	if m == nil {
+		return "", fmt.Errorf("%q: wanted COL op VALUE, COL LIKE 'PATTERN', COL IN (V1, V2) or COL IS NULL", expr)
+	}
+	var col *Column
+	for i := range columns {
+		if strings.EqualFold(columns[i].Name, m[1]) {
+			col = &columns[i]
+			break
+		}
+	}
+	if col == nil {
+		return "", fmt.Errorf("%s: unknown column", m[1])
+	}
+	op := strings.Join(strings.Fields(strings.ToUpper(m[2])), " ")
+	rest := m[3]
+	switch op {
+	case "IS NULL", "IS NOT NULL":
+		if rest != "" {
+			return "", fmt.Errorf("%q: unexpected %q after %s", expr, rest, op)
+		}
+		return col.Name + " " + op, nil
+	case "LIKE", "NOT LIKE":
+		v, err := unquoteFilterValue(rest)
+		if err != nil {
+			return "", fmt.Errorf("%q: %w", expr, err)
+		}
+		return col.Name + " " + op + " " + sqlQuote(v), nil
+	case "IN", "NOT IN":
+		if !(strings.HasPrefix(rest, "(") && strings.HasSuffix(rest, ")")) {
+			return "", fmt.Errorf("%q: wanted (V1, V2, ...) after %s", expr, op)
+		}
+		values, err := splitFilterValues(rest[1 : len(rest)-1])
+		if err != nil {
+			return "", fmt.Errorf("%q: %w", expr, err)
+		}
+		literals := make([]string, len(values))
+		for i, v := range values {
+			if literals[i], err = sqlLiteral(*col, v); err != nil {
+				return "", fmt.Errorf("%q: %w", expr, err)
+			}
+		}
+		return col.Name + " " + op + " (" + strings.Join(literals, ", ") + ")", nil
+	}
+	if op == "!=" {
+		op = "<>"
+	}
+	v, err := unquoteFilterValue(rest)
+	if err != nil {
+		return "", fmt.Errorf("%q: %w", expr, err)
+	}
+	lit, err := sqlLiteral(*col, v)
+	if err != nil {
+		return "", fmt.Errorf("%q: %w", expr, err)
+	}
+	return col.Name + " " + op + " " + lit, nil
+}
+
+// sqlLiteral returns the SQL literal of the value for the column: numbers as is,
+// dates as DATE or TIMESTAMP literals, everything else as quoted strings.
+func sqlLiteral(col Column, v string) (string, error) {
+	switch col.scanConverter().(type) {
+	case *ValInt, *ValUint:
+		if _, err := strconv.ParseInt(v, 10, 64); err != nil {
+			return "", fmt.Errorf("%s: %q is not an integer", col.Name, v)
+		}
+		return v, nil
+	case *ValFloat, *ValDecimal:
+		if _, err := strconv.ParseFloat(v, 64); err != nil {
+			return "", fmt.Errorf("%s: %q is not a number", col.Name, v)
+		}
+		return v, nil
+	case *ValTime:
+		t, err := ParseInputDate(v)
+		if err != nil {
+			return "", fmt.Errorf("%s: %q is not a date", col.Name, v)
+		}
+		if t.Hour() == 0 && t.Minute() == 0 && t.Second() == 0 && t.Nanosecond() == 0 {
+			return "DATE '" + t.Format("2006-01-02") + "'", nil
+		}
+		return "TIMESTAMP '" + t.Format("2006-01-02 15:04:05") + "'", nil
+	}
+	return sqlQuote(v), nil
+}
+
+func sqlQuote(s string) string { return "'" + strings.ReplaceAll(s, "'", "''") + "'" }
+
+// unquoteFilterValue returns the value, without the single quotes (if quoted).
+func unquoteFilterValue(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return "", fmt.Errorf("missing value")
+	}
+	if !strings.HasPrefix(s, "'") {
+		return s, nil
+	}
+	if len(s) < 2 || !strings.HasSuffix(s, "'") {
+		return "", fmt.Errorf("unterminated quote in %s", s)
+	}
+	return strings.ReplaceAll(s[1:len(s)-1], "''", "'"), nil
+}
+
+// splitFilterValues splits the comma separated list of (maybe single quoted) values.
+func splitFilterValues(s string) ([]string, error) {
+	var values []string
+	var buf strings.Builder
+	var inQuote bool
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\'':
+			inQuote = !inQuote
+			buf.WriteByte(c)
+		case c == ',' && !inQuote:
+			v, err := unquoteFilterValue(buf.String())
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, v)
+			buf.Reset()
+		default:
+			buf.WriteByte(c)
+		}
+	}
+	if inQuote {
+		return nil, fmt.Errorf("unterminated quote in %s", s)
+	}
+	v, err := unquoteFilterValue(buf.String())
+	if err != nil {
+		return nil, err
+	}
+	return append(values, v), nil
+}
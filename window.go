@@ -0,0 +1,196 @@
+// Copyright 2021 Tamás Gulácsi.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package dbcsv
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/vm"
+)
+
+// windowRows appends the result of an expression evaluated over a sliding window of the last rows.
+type windowRows struct {
+	Rows
+	program *vm.Program
+	env     map[string]interface{}
+	source  string
+	names   []string
+	zero    []interface{}
+	dest    []interface{}
+	// buf is the circular buffer of the ScannedValues of the last len(buf) rows,
+	// the oldest at head (when full).
+	buf     [][]interface{}
+	window  []map[string]interface{}
+	head    int
+	count   int
+	partial bool
+	result  interface{}
+	err     error
+}
+
+// NewWindowRows returns the rows with the name column appended: the result of the expression
+// (github.com/expr-lang/expr) evaluated over the sliding window of the last size rows (the current row included).
+//
+// The column names are the variables of the current row, and window is the list of the rows of the window
+// (maps of the column names to the values), the oldest first, the current row last, so
+// window[len(window)-1-k] is the lag k row. NULLs are the zero value of the column's type, as with NewExprValidator.
+// Examples: sum(map(window, .AMOUNT)) / len(window) is the rolling average,
+// AMOUNT - window[0].AMOUNT the difference from the first row of the window.
+//
+// Only the rows with a complete window are returned, unless partial is true,
+// when the first size-1 rows are returned, too, with the shorter windows.
+func NewWindowRows(rows Rows, columns []Column, size int, name, source string, partial bool) (Rows, []Column, error) {
+	if size < 1 {
+		return nil, nil, fmt.Errorf("window size must be positive, got %d", size)
+	}
+	wr := windowRows{
+		Rows: rows, source: source, partial: partial,
+		names: make([]string, len(columns)),
+		zero:  make([]interface{}, len(columns)),
+		dest:  make([]interface{}, len(columns)),
+		buf:   make([][]interface{}, size),
+		env:   make(map[string]interface{}, len(columns)+1),
+	}
+	for i, c := range columns {
+		wr.names[i] = c.Name
+		conv := c.scanConverter()
+		wr.dest[i] = conv.Pointer()
+		switch conv.(type) {
+		case *ValInt, *ValUint:
+			wr.zero[i] = int64(0)
+		case *ValFloat:
+			wr.zero[i] = float64(0)
+		case *ValTime:
+			wr.zero[i] = time.Time{}
+		case *ValBool:
+			wr.zero[i] = false
+		default:
+			wr.zero[i] = ""
+		}
+		wr.env[c.Name] = wr.zero[i]
+	}
+	for i := range wr.buf {
+		wr.buf[i] = make([]interface{}, len(columns))
+	}
+	wr.env["window"] = []map[string]interface{}{}
+	var err error
+	if wr.program, err = expr.Compile(source, expr.Env(wr.env)); err != nil {
+		return nil, nil, fmt.Errorf("%q: %w", source, err)
+	}
+
+	typ := typeOfString
+	if t := wr.program.Node().Type(); t != nil {
+		switch t.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			typ = typeOfInt64
+		case reflect.Float32, reflect.Float64:
+			typ = typeOfFloat64
+		case reflect.Bool:
+			typ = typeOfNullBool
+		default:
+			if t == typeOfTime {
+				typ = typeOfTime
+			}
+		}
+	}
+	cols := make([]Column, len(columns), len(columns)+1)
+	copy(cols, columns)
+	return &wr, append(cols, Column{Name: name, Type: typ}), nil
+}
+
+func (wr *windowRows) Next() bool {
+	size := len(wr.buf)
+	for wr.Rows.Next() {
+		if wr.err = wr.Rows.Scan(wr.dest...); wr.err != nil {
+			return false
+		}
+		// overwrite the oldest
+		vals := wr.buf[(wr.head+wr.count)%size]
+		for i, d := range wr.dest {
+			vals[i] = replayValue(d)
+		}
+		if wr.count < size {
+			wr.count++
+		} else {
+			wr.head = (wr.head + 1) % size
+		}
+		if wr.count < size && !wr.partial {
+			continue
+		}
+		wr.window = wr.window[:0]
+		for k := 0; k < wr.count; k++ {
+			vals := wr.buf[(wr.head+k)%size]
+			m := make(map[string]interface{}, len(vals))
+			for i, v := range vals {
+				if v == nil {
+					v = wr.zero[i]
+				}
+				m[wr.names[i]] = v
+			}
+			wr.window = append(wr.window, m)
+		}
+		for k, v := range wr.window[len(wr.window)-1] {
+			wr.env[k] = v
+		}
+		wr.env["window"] = wr.window
+		res, err := expr.Run(wr.program, wr.env)
+		if err != nil {
+			wr.err = fmt.Errorf("%q: %w", wr.source, err)
+			return false
+		}
+		wr.result = windowValue(res)
+		return true
+	}
+	return false
+}
+
+// windowValue converts the result of the expression to a value a Stringer can Scan.
+func windowValue(v interface{}) interface{} {
+	switch x := v.(type) {
+	case nil, int64, float64, bool, string, time.Time:
+		return x
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(rv.Uint())
+	case reflect.Float32, reflect.Float64:
+		return rv.Float()
+	}
+	return fmt.Sprint(v)
+}
+
+func (wr *windowRows) Err() error {
+	if wr.err != nil {
+		return wr.err
+	}
+	return wr.Rows.Err()
+}
+
+func (wr *windowRows) Scan(dest ...interface{}) error {
+	n := len(wr.names)
+	vals := wr.buf[(wr.head+wr.count-1)%len(wr.buf)]
+	for j, d := range dest {
+		v := wr.result
+		if j < n {
+			v = vals[j]
+		}
+		scanner, ok := d.(sql.Scanner)
+		if !ok {
+			return fmt.Errorf("%d. column: cannot scan into %T", j, d)
+		}
+		if err := scanner.Scan(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
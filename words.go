@@ -0,0 +1,199 @@
+// Copyright 2021 Tamás Gulácsi.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package dbcsv
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+)
+
+var (
+	enOnes = [...]string{"zero", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine",
+		"ten", "eleven", "twelve", "thirteen", "fourteen", "fifteen", "sixteen", "seventeen", "eighteen", "nineteen"}
+	enTens   = [...]string{"", "", "twenty", "thirty", "forty", "fifty", "sixty", "seventy", "eighty", "ninety"}
+	enScales = [...]string{"", "thousand", "million", "billion", "trillion", "quadrillion", "quintillion"}
+
+	huOnes       = [...]string{"nulla", "egy", "kettő", "három", "négy", "öt", "hat", "hét", "nyolc", "kilenc"}
+	huTens       = [...]string{"", "tíz", "húsz", "harminc", "negyven", "ötven", "hatvan", "hetven", "nyolcvan", "kilencven"}
+	huTensPrefix = [...]string{"", "tizen", "huszon", "harminc", "negyven", "ötven", "hatvan", "hetven", "nyolcvan", "kilencven"}
+	huScales     = [...]string{"", "ezer", "millió", "milliárd", "billió", "billiárd", "trillió"}
+)
+
+// NumberWords returns the integer written in words, in the language of the locale (en or hu).
+func NumberWords(n int64, locale string) (string, error) {
+	var minus string
+	switch locale {
+	case "", "en":
+		minus = "minus "
+	case "hu":
+		minus = "mínusz "
+	default:
+		return "", fmt.Errorf("unsupported number words locale %q (only en and hu)", locale)
+	}
+	if n == 0 {
+		if locale == "hu" {
+			return huOnes[0], nil
+		}
+		return enOnes[0], nil
+	}
+	u := uint64(n)
+	var prefix string
+	if n < 0 {
+		u, prefix = uint64(-n), minus
+	}
+	var groups []int
+	for ; u != 0; u /= 1000 {
+		groups = append(groups, int(u%1000))
+	}
+	if locale == "hu" {
+		return prefix + huWords(groups), nil
+	}
+	return prefix + enWords(groups), nil
+}
+
+// enWords returns the English words of the number of the (little-endian) thousand groups.
+func enWords(groups []int) string {
+	parts := make([]string, 0, 2*len(groups))
+	for i := len(groups) - 1; i >= 0; i-- {
+		g := groups[i]
+		if g == 0 {
+			continue
+		}
+		if h := g / 100; h != 0 {
+			parts = append(parts, enOnes[h], "hundred")
+		}
+		if r := g % 100; r >= 20 {
+			if r%10 == 0 {
+				parts = append(parts, enTens[r/10])
+			} else {
+				parts = append(parts, enTens[r/10]+"-"+enOnes[r%10])
+			}
+		} else if r != 0 {
+			parts = append(parts, enOnes[r])
+		}
+		if i != 0 {
+			parts = append(parts, enScales[i])
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// huWords returns the Hungarian words of the number of the (little-endian) thousand groups:
+// one word, with hyphens between the thousand groups above 2000.
+func huWords(groups []int) string {
+	hyphen := len(groups) > 2 || len(groups) == 2 && groups[1]*1000+groups[0] > 2000
+	var buf strings.Builder
+	for i := len(groups) - 1; i >= 0; i-- {
+		g := groups[i]
+		if g == 0 {
+			continue
+		}
+		if hyphen && buf.Len() != 0 {
+			buf.WriteByte('-')
+		}
+		// "egy" is omitted before száz and ezer, "kettő" is "két" before another word
+		final := i == 0
+		if h := g / 100; h == 1 {
+			buf.WriteString("száz")
+		} else if h != 0 {
+			buf.WriteString(huUnit(h, false))
+			buf.WriteString("száz")
+		}
+		if t, u := g%100/10, g%10; u == 0 {
+			buf.WriteString(huTens[t])
+		} else {
+			buf.WriteString(huTensPrefix[t])
+			if !(i == 1 && g == 1) {
+				buf.WriteString(huUnit(u, final))
+			}
+		}
+		buf.WriteString(huScales[i])
+	}
+	return buf.String()
+}
+
+func huUnit(u int, final bool) string {
+	if u == 2 && !final {
+		return "két"
+	}
+	return huOnes[u]
+}
+
+// NumberWordsString writes the integer part of the number s in words (see NumberWords),
+// and keeps the fractional part as digits.
+func NumberWordsString(s, locale string) (string, error) {
+	intPart, frac := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		intPart, frac = s[:i], s[i+1:]
+	}
+	neg := strings.HasPrefix(intPart, "-")
+	n, err := strconv.ParseInt(intPart, 10, 64)
+	if err != nil {
+		return s, fmt.Errorf("%q: %w", s, err)
+	}
+	words, err := NumberWords(n, locale)
+	if err != nil || frac == "" {
+		return words, err
+	}
+	if neg && n == 0 {
+		minus := "minus "
+		if locale == "hu" {
+			minus = "mínusz "
+		}
+		words = minus + words
+	}
+	if locale == "hu" {
+		return words + " egész " + frac, nil
+	}
+	return words + " point " + frac, nil
+}
+
+// NewNumberWordsWrapper returns a StringerWrapper writing the numbers in words, with NumberWordsString.
+func NewNumberWordsWrapper(locale, name string) StringerWrapper {
+	return NewMapWrapper(func(s string) string {
+		t, err := NumberWordsString(s, locale)
+		if err != nil {
+			log.Printf("[WARN] %s: %+v", name, err)
+		}
+		return t
+	})
+}
+
+// toOrdinal returns n with its English ordinal suffix (1st, 2nd, 3rd, 11th...).
+// The suffix of negative numbers is that of their absolute value.
+func toOrdinal(n int64) string {
+	abs := uint64(n)
+	if n < 0 {
+		abs = uint64(-n)
+	}
+	suffix := "th"
+	switch abs % 100 {
+	case 11, 12, 13:
+	default:
+		switch abs % 10 {
+		case 1:
+			suffix = "st"
+		case 2:
+			suffix = "nd"
+		case 3:
+			suffix = "rd"
+		}
+	}
+	return strconv.FormatInt(n, 10) + suffix
+}
+
+// NewOrdinalWrapper returns a StringerWrapper writing the integers as English ordinals (1st, 2nd...).
+func NewOrdinalWrapper(name string) StringerWrapper {
+	return NewMapWrapper(func(s string) string {
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			log.Printf("[WARN] %s: %q is not an integer: %+v", name, s, err)
+			return s
+		}
+		return toOrdinal(n)
+	})
+}
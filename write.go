@@ -10,6 +10,7 @@ import (
 	"context"
 	"database/sql"
 	"database/sql/driver"
+	"encoding/json"
 	"fmt"
 	"io"
 	"reflect"
@@ -21,110 +22,372 @@ import (
 	"github.com/UNO-SOFT/spreadsheet"
 )
 
-func DumpCSV(ctx context.Context, w io.Writer, rows *sql.Rows, columns []Column, header bool, sep string, raw bool, Log func(...interface{}) error) error {
+// Rows is the subset of *sql.Rows the dumpers need.
+type Rows interface {
+	Next() bool
+	Scan(dest ...interface{}) error
+	Err() error
+	Close() error
+}
+
+// DumpCSV writes the rows as CSV to w, separated by sep, with the column names as the first line if header is true.
+// Raw writes the raw values, without separators and quoting.
+func DumpCSV(ctx context.Context, w io.Writer, rows Rows, columns []Column, header bool, sep string, raw bool, Log func(...interface{}) error) error {
+	_, err := Dumper{Log: Log, DumperOptions: DumperOptions{Sep: sep, Header: header, Raw: raw}}.DumpCSV(ctx, w, rows, columns)
+	return err
+}
+
+// DumpCSV writes the rows as CSV to w, with the DumperOptions, and returns the number of rows written.
+func (d Dumper) DumpCSV(ctx context.Context, w io.Writer, rows Rows, columns []Column) (int, error) {
+	sep, raw, Log := d.Sep, d.Raw, d.Log
+	opts := d.DumperOptions
+	if d.TSV {
+		// no quoting
+		sep, raw, opts.Sep = "\t", false, ""
+	}
 	sepB := []byte(sep)
 	dest := make([]interface{}, len(columns))
 	bw := bufio.NewWriterSize(w, 65536)
 	defer bw.Flush()
 	values := make([]Stringer, len(columns))
 	for i, col := range columns {
-		c := col.Converter(sep)
+		c := col.Converter(opts)
 		values[i] = c
 		dest[i] = c.Pointer()
 	}
-	if header && !raw {
-		for i, col := range columns {
-			if i > 0 {
-				_, _ = bw.Write(sepB)
-			}
-			if _, err := csvQuote(bw, sep, col.Name); err != nil {
-				return err
-			}
+	null := csvNull(d.NullValue, sep, raw)
+	writeRow := func() error { return writeCSVRow(bw, dest, values, sepB, raw, null) }
+	if d.TSV {
+		null = d.NullValue
+		writeRow = func() error { return writeTSVRow(bw, dest, values, null) }
+	}
+	if d.Header && !raw {
+		var err error
+		if d.TSV {
+			err = writeTSVHeader(bw, columns)
+		} else {
+			err = writeCSVHeader(bw, columns, sep)
 		}
-		if _, err := bw.Write([]byte{'\n'}); err != nil {
-			return err
+		if err != nil {
+			return 0, err
 		}
 	}
 
 	start := time.Now()
+	prg := startProgress(ctx, start, d.DumperOptions)
+	defer prg.Stop()
 	n := 0
 	for rows.Next() {
 		if err := rows.Scan(dest...); err != nil {
-			return fmt.Errorf("scan into %#v: %w", dest, err)
-		}
-		if raw {
-			for i, data := range dest {
-				if data == nil {
-					continue
-				}
-				if sr, ok := values[i].(interface{ StringRaw() string }); ok {
-					_, _ = bw.WriteString(sr.StringRaw())
-				} else {
-					_, _ = bw.WriteString(values[i].String())
-				}
-			}
-		} else {
-			for i, data := range dest {
-				if i > 0 {
-					_, _ = bw.Write(sepB)
-				}
-				if data == nil {
-					continue
-				}
-				_, _ = bw.WriteString(values[i].String())
-			}
+			return n, fmt.Errorf("scan into %#v: %w", dest, err)
 		}
-		if _, err := bw.Write([]byte{'\n'}); err != nil {
-			return err
+		if err := writeRow(); err != nil {
+			return n, err
 		}
 		n++
+		prg.Set(n)
 	}
 	err := rows.Err()
 	dur := time.Since(start)
 	if Log != nil {
 		_ = Log("msg", "dump finished", "rows", n, "dur", dur, "speed", float64(n)/float64(dur)*float64(time.Second), "error", err)
 	}
+	return n, err
+}
+
+func writeCSVHeader(bw *bufio.Writer, columns []Column, sep string) error {
+	for i, col := range columns {
+		if i > 0 {
+			_, _ = bw.WriteString(sep)
+		}
+		if _, err := csvQuote(bw, sep, col.Name); err != nil {
+			return err
+		}
+	}
+	return bw.WriteByte('\n')
+}
+
+// tsvEscaper escapes the TSV values.
+var tsvEscaper = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`)
+
+func writeTSVHeader(bw *bufio.Writer, columns []Column) error {
+	for i, col := range columns {
+		if i > 0 {
+			_ = bw.WriteByte('\t')
+		}
+		if _, err := tsvEscaper.WriteString(bw, col.Name); err != nil {
+			return err
+		}
+	}
+	return bw.WriteByte('\n')
+}
+
+// writeTSVRow writes the escaped raw values separated by tabs, and null for the NULLs if it is not empty.
+func writeTSVRow(bw *bufio.Writer, dest []interface{}, values []Stringer, null string) error {
+	for i, data := range dest {
+		if i > 0 {
+			_ = bw.WriteByte('\t')
+		}
+		if null != "" && ScannedValue(data) == nil {
+			_, _ = bw.WriteString(null)
+			continue
+		}
+		_, _ = tsvEscaper.WriteString(bw, StringRaw(values[i]))
+	}
+	return bw.WriteByte('\n')
+}
+
+// csvNull returns the string of the NULLs in the CSV: the quoted null (unless raw).
+func csvNull(null, sep string, raw bool) string {
+	if null == "" || raw {
+		return null
+	}
+	return csvQuoteString(sep, null)
+}
+
+// writeCSVRow writes the values, and null for the NULLs if it is not empty.
+func writeCSVRow(bw *bufio.Writer, dest []interface{}, values []Stringer, sepB []byte, raw bool, null string) error {
+	if raw {
+		for i, data := range dest {
+			if data == nil {
+				continue
+			}
+			if null != "" && ScannedValue(data) == nil {
+				_, _ = bw.WriteString(null)
+				continue
+			}
+			if sr, ok := values[i].(interface{ StringRaw() string }); ok {
+				_, _ = bw.WriteString(sr.StringRaw())
+			} else {
+				_, _ = bw.WriteString(values[i].String())
+			}
+		}
+	} else {
+		for i, data := range dest {
+			if i > 0 {
+				_, _ = bw.Write(sepB)
+			}
+			if data == nil {
+				continue
+			}
+			if null != "" && ScannedValue(data) == nil {
+				_, _ = bw.WriteString(null)
+				continue
+			}
+			_, _ = bw.WriteString(values[i].String())
+		}
+	}
+	return bw.WriteByte('\n')
+}
+
+// DumpSheet appends the rows to the sheet.
+func DumpSheet(ctx context.Context, sheet spreadsheet.Sheet, rows Rows, columns []Column, Log func(...interface{}) error) error {
+	_, err := Dumper{Log: Log}.DumpSheet(ctx, sheet, rows, columns)
 	return err
 }
 
-func DumpSheet(ctx context.Context, sheet spreadsheet.Sheet, rows *sql.Rows, columns []Column, Log func(...interface{}) error) error {
+// DumpSheet appends the rows to the sheet, with the DumperOptions (but Sep, Header and Raw),
+// and returns the number of rows appended.
+func (d Dumper) DumpSheet(ctx context.Context, sheet spreadsheet.Sheet, rows Rows, columns []Column) (int, error) {
+	Log := d.Log
+	opts := d.DumperOptions
+	opts.Sep = ""
 	dest := make([]interface{}, len(columns))
 	vals := make([]interface{}, len(columns))
 	values := make([]Stringer, len(columns))
 	for i, col := range columns {
-		c := col.Converter("")
+		c := col.Converter(opts)
 		values[i] = c
 		vals[i] = c
 		dest[i] = c.Pointer()
 	}
 	start := time.Now()
+	prg := startProgress(ctx, start, d.DumperOptions)
+	defer prg.Stop()
 	n := 0
 	for rows.Next() {
 		if err := rows.Scan(dest...); err != nil {
-			return fmt.Errorf("scan into %#v: %w", dest, err)
+			return n, fmt.Errorf("scan into %#v: %w", dest, err)
 		}
 		if err := sheet.AppendRow(vals...); err != nil {
-			return err
+			return n, err
 		}
 		n++
+		prg.Set(n)
 	}
 	err := rows.Err()
 	dur := time.Since(start)
 	if Log != nil {
 		_ = Log("msg", "dump finished", "rows", n, "dur", dur, "speed", float64(n)/float64(dur)*float64(time.Second), "error", err)
 	}
-	return err
+	return n, err
 }
 
+// Column describes a column of the Rows: its name, (scan) type and the Wrappers of its Stringer.
 type Column struct {
 	reflect.Type
 	Name string
+	// NotNull is true if the database reported the column as not nullable.
+	NotNull bool
+	// DatabaseTypeName and Scale are as reported by the database, if known.
+	DatabaseTypeName string
+	Scale            int64
+	// Wrappers are applied in order on the Stringer returned by Converter.
+	Wrappers []StringerWrapper
+}
+
+// SetType sets the type of the column by its name: int, uint, float, bool, date (or time), string or binary,
+// overriding what the driver reported - the DatabaseTypeName is cleared, too, not to force ValDecimal or ValLob.
+// The scanned values are converted to this type (such as "12" to 12).
+func (col *Column) SetType(name string) error {
+	var typ reflect.Type
+	switch strings.ToLower(name) {
+	case "int":
+		typ = typeOfInt64
+	case "uint":
+		typ = reflect.TypeOf(uint64(0))
+	case "float":
+		typ = typeOfFloat64
+	case "bool":
+		typ = typeOfNullBool
+	case "date", "time":
+		typ = typeOfTime
+	case "string":
+		typ = typeOfString
+	case "binary":
+		typ = reflect.TypeOf([]byte(nil))
+	default:
+		return fmt.Errorf("%s: unknown type %q (only int, uint, float, bool, date, string or binary)", col.Name, name)
+	}
+	col.Type, col.DatabaseTypeName, col.Scale = typ, "", 0
+	return nil
+}
+
+// Converter returns the Stringer the column's values are scanned into, wrapped by the Wrappers,
+// formatting the values with opts.
+func (col Column) Converter(opts DumperOptions) Stringer {
+	opts = opts.withDefaults()
+	sep := opts.Sep
+	c := getColConverter(col.Type, sep)
+	if col.isDecimal() {
+		c = &ValDecimal{Sep: sep}
+	} else if col.isLob() {
+		c = &ValLob{Sep: sep}
+	} else if col.isBlob() {
+		c = &ValBlob{Sep: sep}
+	}
+	switch x := c.(type) {
+	case *ValTime:
+		x.Format, x.End, x.Location, x.Locale = opts.dateFormat(col.Name), opts.DateEnd, opts.Location, opts.DateLocale
+		x.Quote = sep != "" && strings.Contains(x.Format, sep)
+	case *ValBool:
+		x.True, x.False = opts.BoolTrue, opts.BoolFalse
+	case *ValBlob:
+		x.Encoding, x.Max = opts.BlobEncoding, opts.LobMax
+	case *ValString:
+		x.MaxWidth, x.Suffix = opts.MaxColWidth, opts.MaxColWidthSuffix
+	case *ValLob:
+		x.MaxWidth, x.Suffix, x.Max = opts.MaxColWidth, opts.MaxColWidthSuffix, opts.LobMax
+	}
+	c = localize(c, opts)
+	for _, w := range col.Wrappers {
+		c = w(c, sep)
+	}
+	return c
+}
+
+// scanConverter returns the Stringer the column's values are scanned into by the row wrappers
+// (such as ValLob, ValBlob or ValDecimal): the Converter without options and Wrappers.
+func (col Column) scanConverter() Stringer {
+	col.Wrappers = nil
+	return col.Converter(DumperOptions{})
+}
+
+// replayValue returns the value scanned into dest (the Pointer of a scanConverter) to be kept
+// and scanned into another Stringer later: the ScannedValue, but a copy of the bytes for ValBlob.
+func replayValue(dest interface{}) interface{} {
+	if x, ok := dest.(*ValBlob); ok {
+		if !x.Valid {
+			return nil
+		}
+		return append([]byte(nil), x.Value...)
+	}
+	return ScannedValue(dest)
+}
+
+// ConverterSep returns the Converter of the column with the values quoted with sep.
+//
+// Deprecated: use Converter with DumperOptions{Sep: sep}.
+func (col Column) ConverterSep(sep string) Stringer { return col.Converter(DumperOptions{Sep: sep}) }
+
+// localize returns the localized Stringer of c, by the NumberPrinter, the DateLocale and the date format of opts.
+func localize(c Stringer, opts DumperOptions) Stringer {
+	switch x := c.(type) {
+	case *ValInt:
+		if opts.NumberPrinter != nil {
+			return &LocalizedValInt{ValInt: *x, Sep: opts.Sep, Printer: opts.NumberPrinter}
+		}
+	case *ValFloat:
+		if opts.NumberPrinter != nil {
+			return &LocalizedValFloat{ValFloat: *x, Sep: opts.Sep, Printer: opts.NumberPrinter}
+		}
+	case *ValTime:
+		if x.Locale != nil || strings.Contains(x.layout(), "{") {
+			return &LocalizedValTime{ValTime: *x}
+		}
+	}
+	return c
+}
+
+// StringerWrapper wraps a Stringer, to change its String - quoted with sep, if not empty.
+type StringerWrapper func(s Stringer, sep string) Stringer
+
+// MapStringer is a Stringer whose String is Map applied to the raw string of the wrapped Stringer.
+// Map is not called for NULLs.
+type MapStringer struct {
+	Stringer
+	Map func(string) string
+	Sep string
+}
+
+// NewMapWrapper returns a StringerWrapper that wraps with a MapStringer using f.
+func NewMapWrapper(f func(string) string) StringerWrapper {
+	return func(s Stringer, sep string) Stringer { return &MapStringer{Stringer: s, Map: f, Sep: sep} }
+}
+
+func (m MapStringer) String() string { return csvQuoteString(m.Sep, m.StringRaw()) }
+func (m MapStringer) StringRaw() string {
+	if IsNull(m.Stringer) {
+		return ""
+	}
+	return m.Map(StringRaw(m.Stringer))
+}
+
+// StringRaw returns the unquoted string of s.
+func StringRaw(s Stringer) string {
+	if sr, ok := s.(interface{ StringRaw() string }); ok {
+		return sr.StringRaw()
+	}
+	return s.String()
 }
 
-func (col Column) Converter(sep string) Stringer {
-	return getColConverter(col.Type, sep)
+// TypedValue returns the value of s: nil for NULL, int64, float64, time.Time or bool
+// for ValInt, ValFloat, ValTime and ValBool, and the raw string for everything else (including wrapped Stringers).
+func TypedValue(s Stringer) interface{} {
+	switch x := s.(type) {
+	case *ValInt, *ValFloat, *ValTime, *ValString, *ValBool, *ValDecimal, *ValLob, *ValBlob, *ValUint,
+		*LocalizedValInt, *LocalizedValFloat, *LocalizedValTime:
+		return ScannedValue(x.Pointer())
+	}
+	if IsNull(s) {
+		return nil
+	}
+	return StringRaw(s)
 }
 
+// IsNull reports whether the last scanned value of s is NULL.
+func IsNull(s Stringer) bool { return ScannedValue(s.Pointer()) == nil }
+
 type Stringer interface {
 	String() string
 	Pointer() interface{}
@@ -134,10 +397,13 @@ type Stringer interface {
 type ValString struct {
 	Sep   string
 	Value sql.NullString
+	// MaxWidth (if positive) is the maximum number of characters written, followed by Suffix if truncated.
+	MaxWidth int
+	Suffix   string
 }
 
-func (v ValString) String() string            { return csvQuoteString(v.Sep, v.Value.String) }
-func (v ValString) StringRaw() string         { return v.Value.String }
+func (v ValString) String() string            { return csvQuoteString(v.Sep, v.StringRaw()) }
+func (v ValString) StringRaw() string         { return truncateString(v.Value.String, v.MaxWidth, v.Suffix) }
 func (v *ValString) Pointer() interface{}     { return &v.Value }
 func (v *ValString) Scan(x interface{}) error { return v.Value.Scan(x) }
 
@@ -154,6 +420,38 @@ func (v ValInt) String() string {
 func (v *ValInt) Pointer() interface{}     { return &v.Value }
 func (v *ValInt) Scan(x interface{}) error { return v.Value.Scan(x) }
 
+// ValUint is an unsigned integer, stored in the bits of an sql.NullInt64.
+type ValUint struct {
+	Value sql.NullInt64
+}
+
+func (v ValUint) String() string {
+	if v.Value.Valid {
+		return strconv.FormatUint(uint64(v.Value.Int64), 10)
+	}
+	return ""
+}
+func (v *ValUint) Pointer() interface{} { return v }
+func (v *ValUint) Scan(x interface{}) error {
+	switch u := x.(type) {
+	case uint64:
+		// sql.NullInt64 refuses the values above math.MaxInt64
+		v.Value.Int64, v.Value.Valid = int64(u), true
+		return nil
+	case []byte:
+		x = string(u)
+	}
+	if s, ok := x.(string); ok {
+		u, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return err
+		}
+		v.Value.Int64, v.Value.Valid = int64(u), true
+		return nil
+	}
+	return v.Value.Scan(x)
+}
+
 type ValFloat struct {
 	Value sql.NullFloat64
 }
@@ -167,36 +465,77 @@ func (v ValFloat) String() string {
 func (v *ValFloat) Pointer() interface{}     { return &v.Value }
 func (v *ValFloat) Scan(x interface{}) error { return v.Value.Scan(x) }
 
+type ValBool struct {
+	Value sql.NullBool
+	// True and False are the strings of the true and false values; "true" and "false" if empty.
+	True, False string
+}
+
+func (v ValBool) String() string {
+	if !v.Value.Valid {
+		return ""
+	}
+	if v.Value.Bool {
+		if v.True == "" {
+			return "true"
+		}
+		return v.True
+	}
+	if v.False == "" {
+		return "false"
+	}
+	return v.False
+}
+func (v *ValBool) Pointer() interface{}     { return &v.Value }
+func (v *ValBool) Scan(x interface{}) error { return v.Value.Scan(x) }
+
 type ValTime struct {
 	Value sql.NullTime
 	Quote bool
+	// Format and End are the date format (DefaultDateFormat if empty) and the string of the negative years.
+	Format, End string
+	// Location (if not nil) is the time zone the time is converted into before formatting.
+	Location *time.Location
+	// Locale is the locale of the {MON} and {WEEKDAY} placeholders of Format (see LocalizedValTime).
+	Locale *DateNames
 }
 
-var (
-	DateEnd    string
-	DateFormat = "2006-01-02"
-)
+func (v ValTime) layout() string {
+	if v.Format != "" {
+		return v.Format
+	}
+	return DefaultDateFormat
+}
+
+// inLocation returns the time in Location.
+func (v ValTime) inLocation() time.Time {
+	if v.Location != nil {
+		return v.Value.Time.In(v.Location)
+	}
+	return v.Value.Time
+}
+func (v ValTime) end() string { return v.End }
 
 func (v ValTime) String() string {
 	if !v.Value.Valid || v.Value.Time.IsZero() {
 		return ""
 	}
 	if v.Value.Time.Year() < 0 {
-		return DateEnd
+		return v.end()
 	}
 	if v.Quote {
-		return `"` + v.Value.Time.Format(DateFormat) + `"`
+		return `"` + v.inLocation().Format(v.layout()) + `"`
 	}
-	return v.Value.Time.Format(DateFormat)
+	return v.inLocation().Format(v.layout())
 }
 func (v ValTime) StringRaw() string {
 	if !v.Value.Valid || v.Value.Time.IsZero() {
 		return ""
 	}
 	if v.Value.Time.Year() < 0 {
-		return DateEnd
+		return v.end()
 	}
-	return v.Value.Time.Format(DateFormat)
+	return v.inLocation().Format(v.layout())
 }
 
 func (vt ValTime) ConvertValue(v interface{}) (driver.Value, error) {
@@ -221,6 +560,19 @@ func (vt *ValTime) Scan(v interface{}) error {
 		vt.Value = v
 	case time.Time:
 		vt.Value = sql.NullTime{Valid: !v.IsZero(), Time: v}
+	case []byte:
+		return vt.Scan(string(v))
+	case string:
+		// MySQL's zero date (without parseTime)
+		if v == "" || strings.HasPrefix(v, "0000-00-00") {
+			vt.Value = sql.NullTime{}
+			return nil
+		}
+		t, err := ParseInputDate(v)
+		if err != nil {
+			return err
+		}
+		vt.Value = sql.NullTime{Valid: true, Time: t}
 	default:
 		return fmt.Errorf("unknown scan source %T", v)
 	}
@@ -228,26 +580,177 @@ func (vt *ValTime) Scan(v interface{}) error {
 }
 func (v *ValTime) Pointer() interface{} { return v }
 
-var typeOfTime, typeOfNullTime = reflect.TypeOf(time.Time{}), reflect.TypeOf(sql.NullTime{})
+// ScannedValue returns the value scanned into dest (a Stringer's Pointer()),
+// as nil, string, int64, float64, time.Time or bool.
+func ScannedValue(dest interface{}) interface{} {
+	switch x := dest.(type) {
+	case *sql.NullString:
+		if x.Valid {
+			return x.String
+		}
+	case *sql.NullInt64:
+		if x.Valid {
+			return x.Int64
+		}
+	case *sql.NullFloat64:
+		if x.Valid {
+			return x.Float64
+		}
+	case *sql.NullBool:
+		if x.Valid {
+			return x.Bool
+		}
+	case *ValTime:
+		if x.Value.Valid && !x.Value.Time.IsZero() {
+			return x.Value.Time
+		}
+	case *SerializeStructStringer:
+		if x.Value != nil {
+			return x.StringRaw()
+		}
+	case *ValDecimal:
+		if x.Valid {
+			return x.StringRaw()
+		}
+	case *ValUint:
+		if x.Value.Valid {
+			// int64 if it fits, as the other integers
+			if x.Value.Int64 >= 0 {
+				return x.Value.Int64
+			}
+			return x.String()
+		}
+	case *ValLob:
+		if x.Valid {
+			return x.Value
+		}
+	case *ValBlob:
+		if x.Valid {
+			return x.StringRaw()
+		}
+	}
+	return nil
+}
+
+// ScannedString returns the raw string representation of the value scanned into dest,
+// and whether it is not null.
+func ScannedString(dest interface{}) (string, bool) { return formatValue(ScannedValue(dest)) }
+
+// formatValue returns the raw string representation of the ScannedValue v, and whether it is not null.
+func formatValue(v interface{}) (string, bool) {
+	switch v := v.(type) {
+	case nil:
+		return "", false
+	case string:
+		return v, true
+	case int64:
+		return strconv.FormatInt(v, 10), true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case time.Time:
+		return FormatDate(v, DefaultDateFormat, nil), true
+	case bool:
+		return strconv.FormatBool(v), true
+	default:
+		return fmt.Sprint(v), true
+	}
+}
+
+var (
+	typeOfTime, typeOfNullTime = reflect.TypeOf(time.Time{}), reflect.TypeOf(sql.NullTime{})
+	typeOfNullBool             = reflect.TypeOf(sql.NullBool{})
+	typeOfRawBytes             = reflect.TypeOf(sql.RawBytes{})
+)
 
 func getColConverter(typ reflect.Type, sep string) Stringer {
+	if typ == nil {
+		// unknown scan type (such as modernc.org/sqlite's TEXT columns)
+		return &ValString{Sep: sep}
+	}
 	switch typ.Kind() {
 	case reflect.String:
 		return &ValString{Sep: sep}
+	case reflect.Slice:
+		if typ.Elem().Kind() == reflect.Uint8 {
+			if typ == typeOfRawBytes {
+				// go-sql-driver/mysql scans also the text columns into sql.RawBytes,
+				// the binary ones are told apart by their DatabaseTypeName (Column.isBlob)
+				return &ValString{Sep: sep}
+			}
+			return &ValBlob{Sep: sep}
+		}
 	case reflect.Float32, reflect.Float64:
 		return &ValFloat{}
 	case reflect.Int32, reflect.Int64, reflect.Int:
 		return &ValInt{}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &ValUint{}
+	case reflect.Bool:
+		return &ValBool{}
 	}
 	switch typ {
 	case typeOfTime, typeOfNullTime:
-		return &ValTime{Quote: sep != "" && strings.Contains(DateFormat, sep)}
+		return &ValTime{Quote: sep != "" && strings.Contains(DefaultDateFormat, sep)}
+	case typeOfNullBool:
+		return &ValBool{}
+	}
+	if isStructType(typ) {
+		return &SerializeStructStringer{Sep: sep}
 	}
 	return &ValString{Sep: sep}
 }
 
+// isStructType reports whether typ is a (pointer to) struct that is not a driver.Valuer,
+// such as an Oracle object type.
+func isStructType(typ reflect.Type) bool {
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	return typ.Kind() == reflect.Struct && !typ.Implements(typeOfValuer) && !reflect.PtrTo(typ).Implements(typeOfValuer)
+}
+
+var typeOfValuer = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+
+// SerializeStructStringer holds any scanned value (such as a struct), and writes it as JSON.
+type SerializeStructStringer struct {
+	Value interface{}
+	Sep   string
+}
+
+func (v SerializeStructStringer) String() string { return csvQuoteString(v.Sep, v.StringRaw()) }
+func (v SerializeStructStringer) StringRaw() string {
+	if v.Value == nil {
+		return ""
+	}
+	b, err := json.Marshal(v.Value)
+	if err != nil {
+		return fmt.Sprintf("%+v", v.Value)
+	}
+	return string(b)
+}
+func (v *SerializeStructStringer) Pointer() interface{} { return v }
+func (v *SerializeStructStringer) Scan(x interface{}) error {
+	v.Value = x
+	return nil
+}
+
 var bufPool = sync.Pool{New: func() interface{} { return bytes.NewBuffer(make([]byte, 0, 1024)) }}
 
+// truncateString returns s truncated to max characters, followed by suffix, if it is longer (and max is positive).
+func truncateString(s string, max int, suffix string) string {
+	if max <= 0 || len(s) <= max {
+		return s
+	}
+	var n int
+	for i := range s {
+		if n == max {
+			return s[:i] + suffix
+		}
+		n++
+	}
+	return s
+}
+
 func csvQuoteString(sep, s string) string {
 	if sep == "" {
 		return s
@@ -279,6 +782,7 @@ func csvQuote(w io.Writer, sep, s string) (int, error) {
 	return n + m, err
 }
 
+// GetColumns returns the columns of rows, which is either a *sql.Rows or a driver.Rows.
 func GetColumns(rows interface{}) ([]Column, error) {
 	if r, ok := rows.(*sql.Rows); ok {
 		types, err := r.ColumnTypes()
@@ -287,7 +791,13 @@ func GetColumns(rows interface{}) ([]Column, error) {
 		}
 		cols := make([]Column, len(types))
 		for i, t := range types {
-			cols[i] = Column{Name: t.Name(), Type: t.ScanType()}
+			cols[i] = Column{Name: t.Name(), Type: t.ScanType(), DatabaseTypeName: t.DatabaseTypeName()}
+			if nullable, ok := t.Nullable(); ok {
+				cols[i].NotNull = !nullable
+			}
+			if _, scale, ok := t.DecimalSize(); ok {
+				cols[i].Scale = scale
+			}
 		}
 		return cols, nil
 	}
@@ -300,6 +810,14 @@ func GetColumns(rows interface{}) ([]Column, error) {
 			Name: name,
 			Type: r.ColumnTypeScanType(i),
 		}
+		if r, ok := rows.(driver.RowsColumnTypeDatabaseTypeName); ok {
+			cols[i].DatabaseTypeName = r.ColumnTypeDatabaseTypeName(i)
+		}
+		if r, ok := rows.(driver.RowsColumnTypePrecisionScale); ok {
+			if _, scale, ok := r.ColumnTypePrecisionScale(i); ok {
+				cols[i].Scale = scale
+			}
+		}
 	}
 	return cols, nil
 }
@@ -0,0 +1,78 @@
+// Copyright 2021 Tamás Gulácsi.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package dbcsv
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/360EntSecGroup-Skylar/excelize/v2"
+)
+
+// DumpXLSXTemplate fills the rows into the named (or the first) sheet of the XLSX template,
+// starting at startRow (1-based; after the last used row if not positive),
+// and writes the result to w. Everything else of the template is kept as is.
+func DumpXLSXTemplate(ctx context.Context, w io.Writer, template, sheetName string, startRow int, rows Rows, columns []Column, opts DumperOptions, Log func(...interface{}) error) (int, error) {
+	xlFile, err := excelize.OpenFile(template)
+	if err != nil {
+		return 0, fmt.Errorf("open %q: %w", template, err)
+	}
+	if sheetName == "" {
+		sheetName = xlFile.GetSheetName(0)
+	} else if xlFile.GetSheetIndex(sheetName) < 0 {
+		return 0, fmt.Errorf("%s (only: %v): %w", sheetName, xlFile.GetSheetList(), ErrUnknownSheet)
+	}
+	if startRow <= 0 {
+		used, err := xlFile.GetRows(sheetName)
+		if err != nil {
+			return 0, err
+		}
+		startRow = len(used) + 1
+	}
+
+	dest := make([]interface{}, len(columns))
+	values := make([]Stringer, len(columns))
+	for i, col := range columns {
+		c := col.Converter(opts)
+		values[i] = c
+		dest[i] = c.Pointer()
+	}
+	start := time.Now()
+	n := 0
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return n, fmt.Errorf("scan into %#v: %w", dest, err)
+		}
+		for j, v := range values {
+			x := TypedValue(v)
+			if x == nil {
+				continue
+			}
+			axis, err := excelize.CoordinatesToCellName(j+1, startRow+n)
+			if err != nil {
+				return n, fmt.Errorf("%d:%d: %w", j+1, startRow+n, err)
+			}
+			if err = xlFile.SetCellValue(sheetName, axis, x); err != nil {
+				return n, fmt.Errorf("%s[%s]: %w", sheetName, axis, err)
+			}
+		}
+		n++
+		if err := ctx.Err(); err != nil {
+			return n, err
+		}
+	}
+	err = rows.Err()
+	dur := time.Since(start)
+	if Log != nil {
+		_ = Log("msg", "dump finished", "rows", n, "dur", dur, "speed", float64(n)/float64(dur)*float64(time.Second), "error", err)
+	}
+	if err != nil {
+		return n, err
+	}
+	_, err = xlFile.WriteTo(w)
+	return n, err
+}